@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditMappingFile string
+	auditRepoDir     string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit the consistency of llpkgstore",
+}
+
+var auditMappingCmd = &cobra.Command{
+	Use:   "mapping",
+	Short: "Audit llpkgstore.json against git tags",
+	Long: `Audit llpkgstore.json against local git tags, and report:
+  - mapped versions without {CLibraryName}/{MappedVersion} tags
+  - tags without mapped versions
+  - tags whose llpkg.cfg version disagrees with the mapped C version
+  - violations of the mapping rules and the bumping rules`,
+	Args: cobra.NoArgs,
+	RunE: runAuditMappingCmd,
+	// problems are printed already
+	SilenceUsage: true,
+}
+
+func runAuditMappingCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(auditMappingFile)
+	if err != nil {
+		return err
+	}
+	err = actions.AuditMapping(auditRepoDir, ver)
+	if err == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: no problem found\n", auditMappingFile)
+		return nil
+	}
+	problems := printViolations(cmd.OutOrStdout(), err)
+	return fmt.Errorf("%s: %d problem(s) found", auditMappingFile, problems)
+}
+
+func init() {
+	auditMappingCmd.Flags().StringVarP(&auditMappingFile, "file", "f", "llpkgstore.json", "path to the mapping file")
+	auditMappingCmd.Flags().StringVar(&auditRepoDir, "repo", ".", "path to the git repository")
+	auditCmd.AddCommand(auditMappingCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 
@@ -9,9 +10,12 @@ import (
 )
 
 func TestCMD(t *testing.T) {
+	if _, err := exec.LookPath("llcppg"); err != nil {
+		t.Skip("llcppg is not available")
+	}
 	// ../../../_demo
 	demoDir := filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(currentDir()))), "_demo")
-	runLLCppgGenerateWithDir(demoDir)
+	runGenerateWithDir(lazyMetadataMgr(t.TempDir(), nil), demoDir)
 
 	// remove go.mod
 	file.RemovePattern(filepath.Join(demoDir, "go.*"))
@@ -2,8 +2,10 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 
+	"github.com/PengPengPeng717/llpkgstore/internal/actions"
 	"github.com/PengPengPeng717/llpkgstore/internal/demo"
 	"github.com/spf13/cobra"
 )
@@ -18,15 +20,27 @@ var runCmd = &cobra.Command{
 func runDemotestCmd(cmd *cobra.Command, args []string) error {
 	var paths []string
 	pathEnv := os.Getenv("LLPKG_PATH")
-	if pathEnv != "" {
-		json.Unmarshal([]byte(pathEnv), &paths)
-	} else {
+	if pathEnv == "" {
 		// not in github action
 		paths = append(paths, currentDir())
+		for _, path := range paths {
+			if err := demo.Run(path); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
+	json.Unmarshal([]byte(pathEnv), &paths)
 
+	// in github action, publish the results to the pull request
+	report := actions.NewReport("demotest")
+	if client, err := actions.NewDefaultClient(); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot publish the report: %v\n", err)
+	} else {
+		defer publishReport(client, report)
+	}
 	for _, path := range paths {
-		if err := demo.Run(path); err != nil {
+		if err := report.Add(path+": demo", demo.Run(path), ""); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deprecateReason      string
+	deprecateReplacement string
+	deprecateMappingFile string
+)
+
+var deprecateCmd = &cobra.Command{
+	Use:   "deprecate clib",
+	Short: "Mark a package as deprecated",
+	Long:  `Mark a package as deprecated in llpkgstore.json, optionally pointing to its replacement.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDeprecateCmd,
+}
+
+func runDeprecateCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(deprecateMappingFile)
+	if err != nil {
+		return err
+	}
+	if err := ver.Deprecate(args[0], deprecateReason, deprecateReplacement); err != nil {
+		return err
+	}
+	return resignMapping(cmd, deprecateMappingFile)
+}
+
+func init() {
+	deprecateCmd.Flags().StringVarP(&deprecateReason, "reason", "r", "", "why the package is deprecated")
+	deprecateCmd.Flags().StringVar(&deprecateReplacement, "replacement", "", "name of the package replacing it")
+	deprecateCmd.Flags().StringVarP(&deprecateMappingFile, "file", "f", "llpkgstore.json", "path to the mapping file")
+	deprecateCmd.MarkFlagRequired("reason")
+	rootCmd.AddCommand(deprecateCmd)
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadOutput     string
+	downloadPlatform   string
+	downloadReleaseURL string
+	downloadCacheDir   string
+	downloadMirrors    []string
+)
+
+var downloadCmd = &cobra.Command{
+	Use:   "download <name>@<goVersion>",
+	Short: "Download the binary of an llpkg",
+	Long: `Download the binary zip of an llpkg released for the platform,
+and extract it after verifying its sha256 recorded in llpkgstore.json.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDownloadCmd,
+}
+
+func runDownloadCmd(cmd *cobra.Command, args []string) error {
+	name, goVer, ok := strings.Cut(args[0], "@")
+	if !ok || name == "" || goVer == "" {
+		return fmt.Errorf("invalid llpkg %s, expected <name>@<goVersion>", args[0])
+	}
+	mgr, err := metadata.NewMetadataMgr(downloadCacheDir, downloadMirrors...)
+	if err != nil {
+		return err
+	}
+	if goVer == "latest" {
+		if goVer, err = mgr.LatestGoVer(cmd.Context(), name); err != nil {
+			return err
+		}
+	}
+	err = metadata.DownloadBinary(cmd.Context(), mgr, downloadReleaseURL, name, goVer, downloadPlatform, downloadOutput)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s@%s for %s is verified and extracted to %s\n", name, goVer, downloadPlatform, downloadOutput)
+	return nil
+}
+
+func init() {
+	downloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", ".", "directory to extract the binary to")
+	downloadCmd.Flags().StringVar(&downloadPlatform, "platform", runtime.GOOS+"_"+runtime.GOARCH, "GOOS_GOARCH of the binary")
+	downloadCmd.Flags().StringVar(&downloadReleaseURL, "release-url", metadata.DefaultReleaseURL, "URL where binaries are published")
+	downloadCmd.Flags().StringVar(&downloadCacheDir, "cache-dir", defaultCacheDir(), "directory for caching llpkgstore.json")
+	downloadCmd.Flags().StringSliceVar(&downloadMirrors, "mirror", nil, "mirrors of llpkgstore.json, tried in order (default from "+metadata.MirrorsEnv+")")
+	rootCmd.AddCommand(downloadCmd)
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"github.com/PengPengPeng717/llpkgstore/internal/actions"
+	"github.com/spf13/cobra"
+)
+
+// dryRunFlags are flags of commands supporting dry run,
+// the pull request is read from the local git range instead of the CI.
+type dryRunFlags struct {
+	enabled bool
+	opts    actions.LocalOptions
+}
+
+// register adds the flags to cmd, artifacts are only registered if the command uploads them.
+func (f *dryRunFlags) register(cmd *cobra.Command, artifacts bool) {
+	cmd.Flags().BoolVar(&f.enabled, "dry-run", false, "rehearse locally, print the changes instead of making them")
+	cmd.Flags().StringVar(&f.opts.Base, "base", "main", "target branch of the pull request in dry run")
+	cmd.Flags().StringVar(&f.opts.Head, "head", "HEAD", "head revision of the pull request in dry run")
+	cmd.Flags().IntVar(&f.opts.Number, "pr", 0, "number of the pull request in dry run")
+	if artifacts {
+		cmd.Flags().StringSliceVar(&f.opts.Artifacts, "artifact", nil, "local files uploaded as artifacts in dry run")
+	}
+	f.opts.RepoDir = "."
+}
+
+// newClient creates the client of the command, and a function printing the plan in dry run,
+// which should be called after the command finishes.
+func (f *dryRunFlags) newClient(cmd *cobra.Command) (client *actions.DefaultClient, done func(), err error) {
+	if !f.enabled {
+		client, err = actions.NewDefaultClient()
+		return client, func() {}, err
+	}
+	client, plan := actions.NewDryRunClient(f.opts, cmd.OutOrStdout())
+	return client, func() { plan.WriteTo(cmd.OutOrStdout()) }, nil
+}
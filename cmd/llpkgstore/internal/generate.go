@@ -1,18 +1,23 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 
 	"github.com/PengPengPeng717/llpkgstore/config"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator/llcppg"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator/llpyg"
 	"github.com/PengPengPeng717/llpkgstore/internal/file"
 	"github.com/PengPengPeng717/llpkgstore/internal/pc"
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
 	"github.com/spf13/cobra"
 )
 
@@ -31,7 +36,47 @@ func currentDir() string {
 	return dir
 }
 
-func runGenerateWithDir(dir string) error {
+var (
+	generateCacheDir string
+	generateMirrors  []string
+)
+
+// metadataMgrFunc returns the metadata manager of a command
+type metadataMgrFunc func() (metadata.Manager, error)
+
+// lazyMetadataMgr returns a metadataMgrFunc creating the manager on first use and sharing it afterwards,
+// so that packages without dependencies don't fetch llpkgstore.json.
+func lazyMetadataMgr(cacheDir string, mirrors []string) metadataMgrFunc {
+	return sync.OnceValues(func() (metadata.Manager, error) {
+		return metadata.NewMetadataMgr(cacheDir, mirrors...)
+	})
+}
+
+// requireDependencies writes requirements of llpkgs for the C dependencies of the package into go.mod in dir,
+// the Go versions are resolved by the metadata manager except the unreleased llpkgs keyed by module path.
+func requireDependencies(ctx context.Context, metadataMgr metadataMgrFunc, uc *upstream.Upstream, dir string, unreleased map[string]string) error {
+	deps, err := uc.Installer.Dependency(uc.Pkg)
+	if err != nil {
+		return fmt.Errorf("cannot resolve dependencies of %s: %w", uc.Pkg.Name, err)
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+	mgr, err := metadataMgr()
+	if err != nil {
+		return err
+	}
+	reqs, err := actions.ResolveRequirements(ctx, mgr, uc.Pkg, deps, unreleased)
+	if err != nil {
+		return err
+	}
+	for _, req := range reqs {
+		log.Printf("Require %s %s for %s %s", req.Path, req.Version, req.Dependency.Name, req.Dependency.Version)
+	}
+	return actions.WriteRequirements(dir, reqs)
+}
+
+func runGenerateWithDir(metadataMgr metadataMgrFunc, dir string) error {
 	cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, LLGOModuleIdentifyFile))
 	if err != nil {
 		return fmt.Errorf("parse config error: %v", err)
@@ -95,23 +140,31 @@ func runGenerateWithDir(dir string) error {
 		gen = llcppg.New(dir, cfg.Upstream.Package.Name, tempDir)
 	}
 
-	return gen.Generate(dir)
+	if err := gen.Generate(dir); err != nil {
+		return err
+	}
+	// Python packages don't depend on llpkgs of C libraries
+	if cfg.Type == "python" {
+		return nil
+	}
+	return requireDependencies(context.TODO(), metadataMgr, uc, dir, nil)
 }
 
 func runGenerate(_ *cobra.Command, args []string) error {
 	exec.Command("conan", "profile", "detect").Run()
 
+	metadataMgr := lazyMetadataMgr(generateCacheDir, generateMirrors)
 	path := currentDir()
 	// by default, use current dir
 	if len(args) == 0 {
-		return runGenerateWithDir(path)
+		return runGenerateWithDir(metadataMgr, path)
 	}
 	for _, argPath := range args {
 		absPath, err := filepath.Abs(argPath)
 		if err != nil {
 			continue
 		}
-		err = runGenerateWithDir(absPath)
+		err = runGenerateWithDir(metadataMgr, absPath)
 		if err != nil {
 			return err
 		}
@@ -120,5 +173,7 @@ func runGenerate(_ *cobra.Command, args []string) error {
 }
 
 func init() {
+	generateCmd.Flags().StringVar(&generateCacheDir, "cache-dir", defaultCacheDir(), "directory for caching llpkgstore.json")
+	generateCmd.Flags().StringSliceVar(&generateMirrors, "mirror", nil, "mirrors of llpkgstore.json, tried in order (default from "+metadata.MirrorsEnv+")")
 	rootCmd.AddCommand(generateCmd)
 }
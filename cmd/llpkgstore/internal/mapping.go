@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mappingFile        string
+	mappingMergeOutput string
+	mappingMergeSign   bool
+)
+
+var mappingCmd = &cobra.Command{
+	Use:   "mapping",
+	Short: "Inspect and edit llpkgstore.json locally",
+	Long: `Inspect and edit the version mapping file locally.
+Edits enforce the same mapping rules as the Github Action, and the file is signed again
+if LLPKGSTORE_SIGNING_KEY is set.`,
+}
+
+var mappingListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all clibs with their latest versions",
+	Args:  cobra.NoArgs,
+	RunE:  runMappingListCmd,
+}
+
+var mappingShowCmd = &cobra.Command{
+	Use:   "show clib",
+	Short: "Show the version mapping of a clib",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMappingShowCmd,
+}
+
+var mappingAddCmd = &cobra.Command{
+	Use:   "add clib cversion vX.Y.Z",
+	Short: "Map a Go version to a C version",
+	Long: `Map a Go version to a C version.
+The Go version must not be mapped yet, and must increase with C versions.`,
+	Args: cobra.ExactArgs(3),
+	RunE: runMappingAddCmd,
+}
+
+var mappingRemoveCmd = &cobra.Command{
+	Use:   "remove clib cversion [vX.Y.Z]",
+	Short: "Remove a mapped Go version, or a C version with all its Go versions",
+	Args:  cobra.RangeArgs(2, 3),
+	RunE:  runMappingRemoveCmd,
+}
+
+var mappingValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Print all violations of the mapping rules",
+	Args:  cobra.NoArgs,
+	RunE:  runMappingValidateCmd,
+	// violations are printed already
+	SilenceUsage: true,
+}
+
+var mappingDiffCmd = &cobra.Command{
+	Use:   "diff old new",
+	Short: "Print mappings removed from old and added to new",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runMappingDiffCmd,
+}
+
+var mappingMergeCmd = &cobra.Command{
+	Use:   "merge base ours theirs",
+	Short: "Three-way merge mapping files",
+	Long: `Three-way merge mapping files changed from base by ours and theirs, the result is written to ours by default.
+Mappings added by both sides are kept, and it conflicts only if both sides change the same thing differently,
+for example, the same Go version is mapped to different C versions.
+
+The result is signed if LLPKGSTORE_SIGNING_KEY is set.
+
+It can be installed as a git merge driver, git merges into a temporary file,
+so the signature is kept as ours and regenerated by "llpkgstore mapping sign" after merging:
+
+  git config merge.llpkgstore.name "llpkgstore.json merge driver"
+  git config merge.llpkgstore.driver "llpkgstore mapping merge --sign=false %O %A %B"
+  git config merge.llpkgstore-sig.name "llpkgstore.json.sig merge driver"
+  git config merge.llpkgstore-sig.driver true
+  echo "llpkgstore.json merge=llpkgstore" >> .gitattributes
+  echo "llpkgstore.json.sig merge=llpkgstore-sig" >> .gitattributes`,
+	Args: cobra.ExactArgs(3),
+	RunE: runMappingMergeCmd,
+	// conflicts are printed already
+	SilenceUsage: true,
+}
+
+var mappingSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign the mapping file with LLPKGSTORE_SIGNING_KEY",
+	Args:  cobra.NoArgs,
+	RunE:  runMappingSignCmd,
+}
+
+// sortedCVersions returns C versions of the clib in descending order,
+// versions don't follow the version scheme are put at the end.
+func sortedCVersions(ver *versions.Versions, clib string) ([]string, error) {
+	comparator, err := ver.Comparator(clib)
+	if err != nil {
+		return nil, err
+	}
+	sorted := ver.SortedCVersions(clib, comparator)
+
+	var rest []string
+	for cversion := range ver.MetadataMap[clib].Versions {
+		if !slices.Contains(sorted, cversion) {
+			rest = append(rest, cversion)
+		}
+	}
+	sort.Strings(rest)
+	return append(sorted, rest...), nil
+}
+
+func runMappingListCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(mappingFile)
+	if err != nil {
+		return err
+	}
+	clibs := make([]string, 0, len(ver.MetadataMap))
+	for clib := range ver.MetadataMap {
+		clibs = append(clibs, clib)
+	}
+	sort.Strings(clibs)
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLATEST C VERSION\tLATEST GO VERSION")
+	for _, clib := range clibs {
+		cversions, err := sortedCVersions(ver, clib)
+		if err != nil {
+			return err
+		}
+		var latestCVersion string
+		if len(cversions) > 0 {
+			latestCVersion = cversions[0]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", clib, latestCVersion, ver.LatestGoVersion(clib))
+	}
+	return w.Flush()
+}
+
+func runMappingShowCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(mappingFile)
+	if err != nil {
+		return err
+	}
+	clib := args[0]
+	clibVersions := ver.MetadataMap[clib]
+	if clibVersions == nil {
+		return fmt.Errorf("clib %s not found", clib)
+	}
+	cversions, err := sortedCVersions(ver, clib)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	scheme := clibVersions.VersionScheme
+	if scheme == "" {
+		scheme = versions.SchemeSemver
+	}
+	fmt.Fprintf(out, "name: %s\nversion scheme: %s\n", clib, scheme)
+	if deprecated := clibVersions.Deprecated; deprecated != nil {
+		fmt.Fprintf(out, "deprecated: %s", deprecated.Reason)
+		if deprecated.Replacement != "" {
+			fmt.Fprintf(out, ", use %s instead", deprecated.Replacement)
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintln(out)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "C VERSION\tGO VERSIONS")
+	for _, cversion := range cversions {
+		goVersions := slices.Clone(clibVersions.Versions[cversion])
+		sort.Sort(versions.ByVersionDescending(goVersions))
+		for i, goVersion := range goVersions {
+			if reason, ok := clibVersions.Yanked[goVersion]; ok {
+				goVersions[i] = fmt.Sprintf("%s (yanked: %s)", goVersion, reason)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\n", cversion, strings.Join(goVersions, ", "))
+	}
+	return w.Flush()
+}
+
+func runMappingAddCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(mappingFile)
+	if err != nil {
+		return err
+	}
+	if err := ver.Add(args[0], args[1], args[2]); err != nil {
+		return err
+	}
+	return resignMapping(cmd, mappingFile)
+}
+
+func runMappingRemoveCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(mappingFile)
+	if err != nil {
+		return err
+	}
+	var mappedVersion string
+	if len(args) == 3 {
+		mappedVersion = args[2]
+	}
+	if err := ver.Remove(args[0], args[1], mappedVersion); err != nil {
+		return err
+	}
+	return resignMapping(cmd, mappingFile)
+}
+
+func runMappingValidateCmd(cmd *cobra.Command, args []string) error {
+	ver, err := versions.Read(mappingFile)
+	if err == nil {
+		err = ver.Check()
+	}
+	if err == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: no violation found\n", mappingFile)
+		return nil
+	}
+	if !errors.Is(err, versions.ErrInvalidMapping) && !errors.Is(err, versions.ErrUnorderedMapping) {
+		return err
+	}
+	violations := printViolations(cmd.OutOrStdout(), err)
+	return fmt.Errorf("%s: %d violation(s) found", mappingFile, violations)
+}
+
+// printViolations prints joined errors one per line, and returns the number of them.
+func printViolations(w io.Writer, err error) (n int) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			n += printViolations(w, err)
+		}
+		return
+	}
+	fmt.Fprintln(w, err)
+	return 1
+}
+
+func runMappingDiffCmd(cmd *cobra.Command, args []string) error {
+	oldVer, err := versions.Read(args[0])
+	if err != nil {
+		return err
+	}
+	newVer, err := versions.Read(args[1])
+	if err != nil {
+		return err
+	}
+	for _, change := range versions.Diff(oldVer.MetadataMap, newVer.MetadataMap) {
+		fmt.Fprintln(cmd.OutOrStdout(), change)
+	}
+	return nil
+}
+
+func runMappingMergeCmd(cmd *cobra.Command, args []string) error {
+	output := mappingMergeOutput
+	if output == "" {
+		output = args[1]
+	}
+	err := versions.MergeFiles(args[0], args[1], args[2], output)
+	if err != nil && !errors.Is(err, versions.ErrMergeConflict) {
+		return err
+	}
+	// the result is written even on conflict, keep it matching its signature
+	if mappingMergeSign {
+		if err := resignMapping(cmd, output); err != nil {
+			return err
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	conflicts := printViolations(cmd.ErrOrStderr(), err)
+	return fmt.Errorf("%s: %d conflict(s) found, ours is kept", output, conflicts)
+}
+
+func runMappingSignCmd(cmd *cobra.Command, args []string) error {
+	signingKey, err := env.SigningKey()
+	if err != nil {
+		return err
+	}
+	privateKey, err := metadata.ParsePrivateKey(signingKey)
+	if err != nil {
+		return err
+	}
+	if _, err := versions.Read(mappingFile); err != nil {
+		return err
+	}
+	return metadata.SignFile(privateKey, mappingFile)
+}
+
+func init() {
+	mappingCmd.PersistentFlags().StringVarP(&mappingFile, "file", "f", "llpkgstore.json", "path to the mapping file")
+	mappingMergeCmd.Flags().StringVarP(&mappingMergeOutput, "output", "o", "", "path to write the result (default ours)")
+	mappingMergeCmd.Flags().BoolVar(&mappingMergeSign, "sign", true, "sign the result if LLPKGSTORE_SIGNING_KEY is set")
+	mappingCmd.AddCommand(mappingListCmd, mappingShowCmd, mappingAddCmd, mappingRemoveCmd, mappingValidateCmd, mappingDiffCmd, mappingMergeCmd, mappingSignCmd)
+	rootCmd.AddCommand(mappingCmd)
+}
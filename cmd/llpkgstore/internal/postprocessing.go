@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"github.com/PengPengPeng717/llpkgstore/internal/actions"
 	"github.com/spf13/cobra"
 )
 
@@ -12,14 +11,18 @@ var postProcessingCmd = &cobra.Command{
 	RunE:  runPostProcessingCmd,
 }
 
-func runPostProcessingCmd(_ *cobra.Command, _ []string) error {
-	client, err := actions.NewDefaultClient()
+var postProcessingDryRun dryRunFlags
+
+func runPostProcessingCmd(cmd *cobra.Command, _ []string) error {
+	client, done, err := postProcessingDryRun.newClient(cmd)
 	if err != nil {
 		return err
 	}
+	defer done()
 	return client.Postprocessing()
 }
 
 func init() {
+	postProcessingDryRun.register(postProcessingCmd, true)
 	rootCmd.AddCommand(postProcessingCmd)
 }
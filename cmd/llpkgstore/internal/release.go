@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"github.com/PengPengPeng717/llpkgstore/internal/actions"
 	"github.com/spf13/cobra"
 )
 
@@ -12,14 +11,18 @@ var releaseCmd = &cobra.Command{
 	RunE:  runReleaseCmd,
 }
 
-func runReleaseCmd(_ *cobra.Command, _ []string) error {
-	client, err := actions.NewDefaultClient()
+var releaseDryRun dryRunFlags
+
+func runReleaseCmd(cmd *cobra.Command, _ []string) error {
+	client, done, err := releaseDryRun.newClient(cmd)
 	if err != nil {
 		return err
 	}
+	defer done()
 	return client.Release()
 }
 
 func init() {
+	releaseDryRun.register(releaseCmd, false)
 	rootCmd.AddCommand(releaseCmd)
 }
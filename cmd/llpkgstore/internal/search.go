@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchJSON     bool
+	searchCacheDir string
+	searchMirrors  []string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Search llpkgs in llpkgstore",
+	Long: `Search llpkgs by name, description and keywords.
+Exact, prefix, substring and misspelled matches are listed in order of relevance.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearchCmd,
+}
+
+// defaultCacheDir returns the default directory for caching llpkgstore.json
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "llpkgstore")
+	}
+	return filepath.Join(dir, "llpkgstore")
+}
+
+func runSearchCmd(cmd *cobra.Command, args []string) error {
+	mgr, err := metadata.NewMetadataMgr(searchCacheDir, searchMirrors...)
+	if err != nil {
+		return err
+	}
+	results, err := mgr.Search(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if searchJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []metadata.SearchResult{}
+		}
+		return encoder.Encode(results)
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(out, "no llpkg found for %s\n", args[0])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tC VERSION\tGO VERSION")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Name, result.LatestCVer, result.LatestGoVer)
+	}
+	return w.Flush()
+}
+
+func init() {
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "print results in JSON")
+	searchCmd.Flags().StringVar(&searchCacheDir, "cache-dir", defaultCacheDir(), "directory for caching llpkgstore.json")
+	searchCmd.Flags().StringSliceVar(&searchMirrors, "mirror", nil, "mirrors of llpkgstore.json, tried in order (default from "+metadata.MirrorsEnv+")")
+	rootCmd.AddCommand(searchCmd)
+}
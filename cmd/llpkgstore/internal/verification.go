@@ -1,11 +1,17 @@
 package internal
 
 import (
+	"archive/zip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/PengPengPeng717/llpkgstore/config"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions"
@@ -13,7 +19,10 @@ import (
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator/llcppg"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator/llpyg"
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
 	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
 )
 
 const LLGOModuleIdentifyFile = "llpkg.cfg"
@@ -21,77 +30,358 @@ const LLGOModuleIdentifyFile = "llpkg.cfg"
 var verificationCmd = &cobra.Command{
 	Use:   "verification",
 	Short: "PR Verification",
-	Long:  ``,
-	RunE:  runLLCppgVerification,
+	Long: `PR Verification
+The result is published as a comment on the pull request and a check run with annotations.`,
+	RunE: runLLCppgVerification,
 }
 
-func runLLCppgVerificationWithDir(dir string) error {
+// annotate returns an annotation of the failure at the line of the file
+func annotate(path string, line int, title string, err error) actions.Annotation {
+	return actions.Annotation{
+		Path:    filepath.ToSlash(path),
+		Line:    line,
+		Level:   actions.AnnotationFailure,
+		Title:   title,
+		Message: err.Error(),
+	}
+}
+
+// zipContents lists the files with sizes in the zip file
+func zipContents(fileName string) (string, error) {
+	r, err := zip.OpenReader(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var b strings.Builder
+	for _, f := range r.File {
+		fmt.Fprintf(&b, "%10d  %s\n", f.UncompressedSize64, f.Name)
+	}
+	return b.String(), nil
+}
+
+// uploadDebugArtifact packages the generated result of the package in the directory path for debugging,
+// and returns where to find it. The package is written into debugDir if it's specified,
+// otherwise it's uploaded as an artifact of the CI run.
+func uploadDebugArtifact(client *actions.DefaultClient, path, dir, generated string, gen generator.Generator) string {
+	var stderr []byte
+	if recorder, ok := gen.(generator.Recorder); ok {
+		stderr = recorder.Stderr()
+	}
+	name := "llpkg-debug-" + strings.ReplaceAll(filepath.ToSlash(filepath.Clean(path)), "/", "-")
+	zipFilePath := filepath.Join(os.TempDir(), name+".zip")
+	if debugDir != "" {
+		zipFilePath = filepath.Join(debugDir, name+".zip")
+		if err := os.MkdirAll(debugDir, 0777); err != nil {
+			return fmt.Sprintf("unavailable (%v)", err)
+		}
+	}
+	if err := actions.BuildDebugZip(zipFilePath, dir, generated, stderr); err != nil {
+		return fmt.Sprintf("unavailable (%v)", err)
+	}
+	if debugDir != "" {
+		return zipFilePath
+	}
+	link, err := client.UploadArtifact(name, zipFilePath)
+	if err != nil {
+		// leave it to the workflow, e.g. an upload-artifact step running on failure
+		client.Setenv(env.Env{"LLPKG_DEBUG_ARTIFACT": zipFilePath})
+		return fmt.Sprintf("%s (upload failed: %v)", zipFilePath, err)
+	}
+	return link
+}
+
+// runLLCppgVerificationWithDir verifies the package in the directory path relative to the repository,
+// the result of each check is added to the report.
+// A debug artifact is uploaded by the client if the generation or the check fails,
+// and dependencies updated by the same PR are resolved from the proxy.
+func runLLCppgVerificationWithDir(client *actions.DefaultClient, metadataMgr metadataMgrFunc, path string, report *actions.Report, proxy *generator.LocalProxy) error {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	cfgFile := filepath.Join(path, LLGOModuleIdentifyFile)
 	cfg, err := config.ParseLLPkgConfig(filepath.Join(dir, LLGOModuleIdentifyFile))
+	if err == nil {
+		err = config.ValidateLLPkgConfig(cfg)
+	}
 	if err != nil {
-		return fmt.Errorf("parse config error: %v", err)
+		err = fmt.Errorf("parse config error: %v", err)
+		return report.Add(path+": config", err, "", annotate(cfgFile, 1, "Invalid llpkg.cfg", err))
 	}
 	uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
 	if err != nil {
-		return err
+		return report.Add(path+": config", err, "", annotate(cfgFile, 1, "Invalid llpkg.cfg", err))
 	}
-	_, err = uc.Installer.Install(uc.Pkg, dir)
+	report.Add(path+": config", nil, "")
+
+	// packages are verified in parallel, but installers share a cache, e.g. CONAN_HOME of conan,
+	// which isn't safe for concurrent installs
+	installMu.Lock()
+	deps, err := uc.Installer.Install(uc.Pkg, dir)
+	installMu.Unlock()
 	if err != nil {
-		return err
+		return report.Add(path+": install", err, "", annotate(cfgFile, 1, "Install failed", err))
 	}
+	report.Add(path+": install", nil, fmt.Sprintf("installed by %s: %s", uc.Installer.Name(), strings.Join(deps, " ")))
 
 	// Choose generator based on package type
 	var gen generator.Generator
+	genCfgFile := filepath.Join(path, "llcppg.cfg")
 	if cfg.Type == "python" {
 		gen = llpyg.New(dir, cfg.Upstream.Package.Name, dir)
+		genCfgFile = filepath.Join(path, "llpyg.cfg")
 	} else {
 		gen = llcppg.New(dir, cfg.Upstream.Package.Name, dir)
 	}
+	if user, ok := gen.(generator.ProxyUser); ok && proxy != nil {
+		user.UseProxy(proxy)
+	}
 
 	generated := filepath.Join(dir, ".generated")
 	os.Mkdir(generated, 0777)
 
 	if err := gen.Generate(generated); err != nil {
-		return err
+		err = fmt.Errorf("%w\ndebug artifact: %s", err, uploadDebugArtifact(client, path, dir, generated, gen))
+		return report.Add(path+": generate", err, "", annotate(genCfgFile, 1, "Generation failed", err))
 	}
+	report.Add(path+": generate", nil, "")
+
+	if cfg.Type != "python" {
+		var unreleased map[string]string
+		if proxy != nil {
+			unreleased = proxy.Modules()
+		}
+		if err := requireDependencies(context.TODO(), metadataMgr, uc, generated, unreleased); err != nil {
+			return report.Add(path+": requirements", err, "", annotate(cfgFile, 1, "Dependencies without llpkg", err))
+		}
+		report.Add(path+": requirements", nil, "")
+	}
+
 	if err := gen.Check(generated); err != nil {
-		return err
+		var annotations []actions.Annotation
+		for _, fileErr := range generator.FileErrors(err) {
+			annotations = append(annotations, actions.Annotation{
+				Path:    filepath.ToSlash(filepath.Join(path, fileErr.Name)),
+				Line:    fileErr.Line(),
+				Level:   actions.AnnotationFailure,
+				Title:   "Generated file differs",
+				Message: fmt.Sprintf("%s: %s differs from the generated result, please regenerate it", fileErr.Reason, fileErr.Name),
+			})
+		}
+		err = fmt.Errorf("%w\ndebug artifact: %s", err, uploadDebugArtifact(client, path, dir, generated, gen))
+		return report.Add(path+": check generated files", err, "", annotations...)
 	}
-	// TODO(ghl): upload generated result to artifact for debugging.
+	report.Add(path+": check generated files", nil, "")
 	os.RemoveAll(generated)
+
 	// start prebuilt check
-	_, _, err = actions.BuildBinaryZip(uc)
-	return err
+	_, zipFilePath, err := actions.BuildBinaryZip(uc)
+	if err != nil {
+		return report.Add(path+": binary zip", err, "")
+	}
+	contents, err := zipContents(zipFilePath)
+	return report.Add(path+": binary zip", err, contents)
 }
 
-func runLLCppgVerification(_ *cobra.Command, _ []string) error {
-	exec.Command("conan", "profile", "detect").Run()
+// dependencyLevels groups the packages into levels ordered by dependencies among them,
+// a package only depends on packages of previous levels.
+func dependencyLevels(paths []string) (levels [][]string, deps map[string][]string, err error) {
+	packages := make(map[string]*upstream.Upstream, len(paths))
+	for _, path := range paths {
+		cfg, err := config.ParseLLPkgConfig(filepath.Join(path, LLGOModuleIdentifyFile))
+		if err != nil {
+			// reported by the verification of the package
+			continue
+		}
+		uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+		if err != nil {
+			continue
+		}
+		packages[path] = uc
+	}
+	deps, err = actions.PackageDependencies(packages)
+	if err != nil {
+		return
+	}
+	levels, err = actions.SortByDependency(paths, deps)
+	return
+}
 
-	client, err := actions.NewDefaultClient()
+// serveLocalModule serves the package verified by the proxy at the mapped version of current PR
+func serveLocalModule(client *actions.DefaultClient, proxy *generator.LocalProxy, path string) error {
+	version, err := client.MappedVersion(path)
 	if err != nil {
 		return err
 	}
-	paths, err := client.CheckPR()
+	dir, err := filepath.Abs(path)
 	if err != nil {
 		return err
 	}
+	return proxy.Add(llcppg.ModulePath(path), version, dir)
+}
+
+// verifyPackages verifies packages level by level in order of dependencies,
+// packages of a level are verified in parallel, and each of them reports to its own report,
+// which are merged in order of paths.
+// A package verified is served by a local proxy to its dependents, since it's not released yet,
+// and the verification of a package is skipped if any of its dependencies fails.
+func verifyPackages(client *actions.DefaultClient, paths []string, report *actions.Report) error {
+	levels, deps, err := dependencyLevels(paths)
+	if err != nil {
+		return report.Add("dependencies", err, "")
+	}
+	var order []string
+	for _, level := range levels {
+		order = append(order, strings.Join(level, " "))
+	}
+	report.Add("dependencies", nil, "verification order: "+strings.Join(order, " => "))
 
+	proxyDir, err := os.MkdirTemp("", "llpkg-proxy")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(proxyDir)
+	proxy := generator.NewLocalProxy(proxyDir)
+	depended := map[string]bool{}
+	for _, pkgDeps := range deps {
+		for _, dep := range pkgDeps {
+			depended[dep] = true
+		}
+	}
+
+	// shared by the packages verified in parallel
+	metadataMgr := lazyMetadataMgr(verificationCacheDir, verificationMirrors)
+	verify := func(path string, pkgReport *actions.Report) error {
+		return runLLCppgVerificationWithDir(client, metadataMgr, path, pkgReport, proxy)
+	}
+	serve := func(path string) error {
+		if !depended[path] {
+			return nil
+		}
+		return serveLocalModule(client, proxy, path)
+	}
+	reports, errs := verifyLevels(report.Step, levels, deps, verify, serve)
+
+	var allErrs []error
 	for _, path := range paths {
-		absPath, _ := filepath.Abs(path)
-		err := runLLCppgVerificationWithDir(absPath)
-		if err != nil {
-			return err
+		report.Sections = append(report.Sections, reports[path].Sections...)
+		allErrs = append(allErrs, errs[path])
+	}
+	return errors.Join(allErrs...)
+}
+
+// verifyLevels verifies the packages level by level, the packages of a level are verified concurrently,
+// and a package is skipped if any of its dependencies failed.
+// serve is called with each verified package before verifying the following levels.
+func verifyLevels(step string, levels [][]string, deps map[string][]string,
+	verify func(path string, report *actions.Report) error, serve func(path string) error) (map[string]*actions.Report, map[string]error) {
+	reports := make(map[string]*actions.Report)
+	errs := make(map[string]error)
+	// errs is written by the workers of a level, and by skipped packages at the same time
+	var mu sync.Mutex
+	for _, level := range levels {
+		var group errgroup.Group
+		group.SetLimit(max(verificationJobs, 1))
+		for _, path := range level {
+			pkgReport := actions.NewReport(step)
+			reports[path] = pkgReport
+
+			var failed []string
+			mu.Lock()
+			for _, dep := range deps[path] {
+				if errs[dep] != nil {
+					failed = append(failed, dep)
+				}
+			}
+			mu.Unlock()
+			if len(failed) > 0 {
+				err := fmt.Errorf("%s: skipped, dependencies failed: %s", path, strings.Join(failed, " "))
+				err = pkgReport.Add(path+": dependencies", err, "")
+				mu.Lock()
+				errs[path] = err
+				mu.Unlock()
+				continue
+			}
+			group.Go(func() error {
+				err := verify(path, pkgReport)
+				mu.Lock()
+				errs[path] = err
+				mu.Unlock()
+				return nil
+			})
 		}
+		group.Wait()
+
+		// serve the packages verified to their dependents in the following levels
+		for _, path := range level {
+			if errs[path] == nil {
+				if err := serve(path); err != nil {
+					errs[path] = reports[path].Add(path+": local module", err, "")
+				}
+			}
+		}
+	}
+	return reports, errs
+}
+
+// publishReport publishes the report, failures are only printed,
+// since the result of the step shouldn't be affected, e.g. the token is read-only for forks.
+func publishReport(client *actions.DefaultClient, report *actions.Report) {
+	if err := client.PublishReport(report); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot publish the report: %v\n", err)
+	}
+}
+
+func runLLCppgVerification(cmd *cobra.Command, _ []string) error {
+	exec.Command("conan", "profile", "detect").Run()
+
+	client, done, err := verificationDryRun.newClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	report := actions.NewReport("verification")
+	defer publishReport(client, report)
+
+	paths, err := client.CheckPR()
+	if err != nil {
+		return report.Add("pull request", err, "")
 	}
-	// output parsed path to Github Env for demotest
+	report.Add("pull request", nil, "packages: "+strings.Join(paths, " "))
+
+	if err := verifyPackages(client, paths, report); err != nil {
+		return err
+	}
+	// output parsed path to CI Env for demotest
 	b, err := json.Marshal(&paths)
 	if err != nil {
 		return err
 	}
-	return env.Setenv(env.Env{
+	return client.Setenv(env.Env{
 		"LLPKG_PATH": string(b),
 	})
 }
 
+var (
+	verificationDryRun dryRunFlags
+	// debugDir is the directory to write debug artifacts into instead of uploading them
+	debugDir string
+	// verificationJobs is the max number of packages verified in parallel
+	verificationJobs     int
+	verificationCacheDir string
+	verificationMirrors  []string
+	// installMu serializes installing packages verified in parallel
+	installMu sync.Mutex
+)
+
 func init() {
+	verificationDryRun.register(verificationCmd, false)
+	verificationCmd.Flags().IntVarP(&verificationJobs, "jobs", "j", runtime.NumCPU(), "max number of packages verified in parallel")
+	verificationCmd.Flags().StringVar(&verificationCacheDir, "cache-dir", defaultCacheDir(), "directory for caching llpkgstore.json")
+	verificationCmd.Flags().StringSliceVar(&verificationMirrors, "mirror", nil, "mirrors of llpkgstore.json, tried in order (default from "+metadata.MirrorsEnv+")")
+	verificationCmd.Flags().StringVar(&debugDir, "debug-dir", "", "write the debug artifact of a failed generation into the directory instead of uploading it")
 	rootCmd.AddCommand(verificationCmd)
 }
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions"
+)
+
+func TestVerifyLevels(t *testing.T) {
+	old := verificationJobs
+	verificationJobs = 4
+	defer func() { verificationJobs = old }()
+
+	// zlib fails, so libxml2 is skipped while its siblings are still verifying
+	levels := [][]string{
+		{"zlib", "cjson"},
+		{"sqlite", "libpng", "libxml2", "libjpeg", "libwebp"},
+	}
+	deps := map[string][]string{"libxml2": {"zlib", "cjson"}, "libpng": {"cjson"}}
+	var verified, served atomic.Int32
+	verify := func(path string, report *actions.Report) error {
+		verified.Add(1)
+		// still verifying when libxml2 is skipped
+		time.Sleep(time.Millisecond)
+		if path == "zlib" {
+			return report.Add(path+": build", errors.New("build failed"), "")
+		}
+		return report.Add(path+": build", nil, "")
+	}
+	serve := func(path string) error {
+		served.Add(1)
+		return nil
+	}
+	for i := 0; i < 20; i++ {
+		verified.Store(0)
+		served.Store(0)
+		reports, errs := verifyLevels("verification", levels, deps, verify, serve)
+		if verified.Load() != 6 || served.Load() != 5 {
+			t.Fatalf("Expected 6 verified and 5 served, got %d and %d", verified.Load(), served.Load())
+		}
+		for _, path := range []string{"zlib", "libxml2"} {
+			if errs[path] == nil {
+				t.Errorf("Expected %s to fail", path)
+			}
+		}
+		for _, path := range []string{"cjson", "sqlite", "libpng", "libjpeg", "libwebp"} {
+			if errs[path] != nil {
+				t.Errorf("Expected %s to pass, got %v", path, errs[path])
+			}
+		}
+		expected := "libxml2: skipped, dependencies failed: zlib"
+		if got := reports["libxml2"].Sections[0].Err; got == nil || fmt.Sprint(got) != expected {
+			t.Errorf("Expected %q, got %v", expected, got)
+		}
+	}
+}
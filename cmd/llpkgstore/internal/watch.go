@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchRepoDir string
+	watchRemote  string
+	watchBase    string
+	watchDryRun  bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Open PRs for new upstream versions",
+	Long: `Search newer upstream versions of every package in the repository,
+and for each of them:
+  - update the version in llpkg.cfg and regenerate the bindings in branch llpkgstore/update/{CLibraryName}/{CLibraryVersion}
+  - commit with "Release-as: {CLibraryName}/{MappedVersion}" of the next mapped version by the bumping rules
+  - push the branch and open a PR to the base branch
+
+Updates whose branch exists are skipped, so it can run as a scheduled job.`,
+	Args: cobra.NoArgs,
+	RunE: runWatchCmd,
+}
+
+func runWatchCmd(cmd *cobra.Command, _ []string) error {
+	var client *actions.DefaultClient
+	if watchDryRun {
+		var plan *actions.Plan
+		client, plan = actions.NewDryRunClient(actions.LocalOptions{RepoDir: watchRepoDir, Base: watchBase, Head: "HEAD"}, cmd.OutOrStdout())
+		defer plan.WriteTo(cmd.OutOrStdout())
+	} else {
+		var err error
+		if client, err = actions.NewDefaultClient(); err != nil {
+			return err
+		}
+	}
+
+	metadataMgr := lazyMetadataMgr(defaultCacheDir(), nil)
+	updates, err := client.Watch(actions.WatchOptions{
+		RepoDir: watchRepoDir,
+		Remote:  watchRemote,
+		Base:    watchBase,
+		Generate: func(dir string) error {
+			return runGenerateWithDir(metadataMgr, dir)
+		},
+	})
+	for _, update := range updates {
+		if update.Skipped {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s => %s: branch %s exists, skipped\n", update.Package, update.Version, update.NewVersion, update.Branch)
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s => %s (%s): PR #%d opened from %s\n", update.Package, update.Version, update.NewVersion, update.MappedVersion, update.PullRequest, update.Branch)
+	}
+	return err
+}
+
+func init() {
+	watchCmd.Flags().StringVar(&watchRepoDir, "repo", ".", "path to the local clone of the repository")
+	watchCmd.Flags().StringVar(&watchRemote, "remote", "origin", "git remote to push update branches to")
+	watchCmd.Flags().StringVar(&watchBase, "base", "main", "branch updates are based on")
+	watchCmd.Flags().BoolVar(&watchDryRun, "dry-run", false, "rehearse locally, print the changes instead of making them")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	yankReason      string
+	yankMappingFile string
+)
+
+var yankCmd = &cobra.Command{
+	Use:   "yank clib/vX.Y.Z",
+	Short: "Mark a published mapped version as yanked",
+	Long: `Mark a published mapped version as yanked in llpkgstore.json.
+A yanked version is excluded from latest and constraint resolution,
+but still resolvable by exact version.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runYankCmd,
+}
+
+func runYankCmd(cmd *cobra.Command, args []string) error {
+	clib, mappedVersion, ok := strings.Cut(args[0], "/")
+	if !ok {
+		return fmt.Errorf("invalid mapped version format: %s", args[0])
+	}
+	if yankReason == "" {
+		return fmt.Errorf("no reason")
+	}
+	ver, err := versions.Read(yankMappingFile)
+	if err != nil {
+		return err
+	}
+	if err := ver.Yank(clib, mappedVersion, yankReason); err != nil {
+		return err
+	}
+	return resignMapping(cmd, yankMappingFile)
+}
+
+// resignMapping signs the mapping file again after editing it if the signing key is available,
+// otherwise, the signature must be regenerated before publishing.
+func resignMapping(cmd *cobra.Command, fileName string) error {
+	signingKey, err := env.SigningKey()
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v, %s%s must be regenerated before publishing\n",
+			err, fileName, metadata.SignatureSuffix)
+		return nil
+	}
+	privateKey, err := metadata.ParsePrivateKey(signingKey)
+	if err != nil {
+		return err
+	}
+	return metadata.SignFile(privateKey, fileName)
+}
+
+func init() {
+	yankCmd.Flags().StringVarP(&yankReason, "reason", "r", "", "why the version is yanked")
+	yankCmd.Flags().StringVarP(&yankMappingFile, "file", "f", "llpkgstore.json", "path to the mapping file")
+	yankCmd.MarkFlagRequired("reason")
+	rootCmd.AddCommand(yankCmd)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
 	"github.com/PengPengPeng717/llpkgstore/upstream"
 	"github.com/PengPengPeng717/llpkgstore/upstream/installer/conan"
 	"github.com/PengPengPeng717/llpkgstore/upstream/installer/pip"
@@ -10,6 +11,9 @@ import (
 
 var ValidInstallers = []string{"conan", "pip"}
 
+// ValidVersionSchemes lists how C versions can be ordered, empty means semver.
+var ValidVersionSchemes = versions.Schemes
+
 // LLPkgConfig represents the configuration structure parsed from llpkg.cfg files.
 type LLPkgConfig struct {
 	Type     string         `json:"type,omitempty"` // "python" for Python packages, empty for C/C++ packages
@@ -34,6 +38,9 @@ type InstallerConfig struct {
 type PackageConfig struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// VersionScheme selects how versions are ordered for branch maintenance,
+	// it's required when the version doesn't follow semver (optional).
+	VersionScheme string `json:"versionScheme,omitempty"`
 }
 
 // NewUpstreamFromConfig creates an Upstream instance from configuration data.
@@ -28,6 +28,9 @@ func validateUpstreamConfig(config UpstreamConfig) error {
 	if config.Package.Version == "" {
 		return fmt.Errorf("missing required version specification: upstream.package.version cannot be empty")
 	}
+	if config.Package.VersionScheme != "" && !slices.Contains(ValidVersionSchemes, config.Package.VersionScheme) {
+		return fmt.Errorf("unsupported version scheme: %s (valid options: %v)", config.Package.VersionScheme, ValidVersionSchemes)
+	}
 
 	return nil
 }
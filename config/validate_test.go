@@ -12,3 +12,19 @@ func TestValidateLLPkgConfig(t *testing.T) {
 		t.Errorf("Error validating config: %v", err)
 	}
 }
+
+func TestValidateVersionScheme(t *testing.T) {
+	config := LLPkgConfig{
+		Upstream: UpstreamConfig{
+			Installer: InstallerConfig{Name: "conan"},
+			Package:   PackageConfig{Name: "sqlite3", Version: "3450100", VersionScheme: "sqlite"},
+		},
+	}
+	if err := ValidateLLPkgConfig(config); err != nil {
+		t.Errorf("Expected valid version scheme, got %v", err)
+	}
+	config.Upstream.Package.VersionScheme = "unknown"
+	if err := ValidateLLPkgConfig(config); err == nil {
+		t.Error("Expected error for unknown version scheme")
+	}
+}
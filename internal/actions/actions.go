@@ -1,7 +1,8 @@
 package actions
 
 import (
-	"encoding/json"
+	"bytes"
+	"cmp"
 	"fmt"
 	"log"
 	"os"
@@ -11,10 +12,8 @@ import (
 	"slices"
 	"sort"
 	"strings"
-	"sync"
 
 	"github.com/PengPengPeng717/llpkgstore/config"
-	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
 	"github.com/PengPengPeng717/llpkgstore/internal/file"
 	"github.com/PengPengPeng717/llpkgstore/internal/pc"
@@ -22,54 +21,10 @@ import (
 	"golang.org/x/mod/semver"
 )
 
-// GitHubEvent caches parsed GitHub event data from GITHUB_EVENT_PATH
-var GitHubEvent = sync.OnceValues(parseGitHubEvent)
-
 // In our previous design, each platform should generate *_{OS}_{Arch}.go file
 // Feb 12th, this design revoked, still keep the code.
 var currentSuffix = runtime.GOOS + "_" + runtime.GOARCH
 
-// parseGitHubEvent parses the GitHub event payload from GITHUB_EVENT_PATH into a map
-func parseGitHubEvent() (map[string]any, error) {
-	eventFile, err := env.EventFile()
-	if err != nil {
-		return nil, err
-	}
-	var m map[string]any
-	err = json.Unmarshal(eventFile, &m)
-
-	if err != nil {
-		return nil, err
-	}
-	return m, nil
-}
-
-// PullRequestEvent extracts pull request details from the parsed GitHub event data
-func PullRequestEvent() (map[string]any, error) {
-	event, err := GitHubEvent()
-	if err != nil {
-		return nil, err
-	}
-	pullRequest, ok := event["pull_request"].(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("env: cannot parse GITHUB_EVENT_PATH pull_request")
-	}
-	return pullRequest, nil
-}
-
-// IssueEvent retrieves issue-related information from the GitHub event payload
-func IssueEvent() (map[string]any, error) {
-	event, err := GitHubEvent()
-	if err != nil {
-		return nil, err
-	}
-	issue, ok := event["issue"].(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("env: cannot parse GITHUB_EVENT_PATH pull_request")
-	}
-	return issue, nil
-}
-
 // tagRef constructs full Git tag reference string (e.g. "refs/tags/v1.0.0")
 func tagRef(tag string) string {
 	return "refs/tags/" + strings.TrimSpace(tag)
@@ -113,6 +68,56 @@ func parseMappedVersion(version string) (clib, mappedVersion string, err error)
 	return
 }
 
+// platformChecksums converts checksums keyed by asset file name to checksums keyed by GOOS_GOARCH.
+// The asset file name follows binaryZip, e.g. cjson_linux_amd64.zip => linux_amd64,
+// and the file name is kept as it is if it doesn't follow the format. Zips of SBOMs are skipped.
+func platformChecksums(packageName string, checksums map[string]string) map[string]string {
+	ret := make(map[string]string, len(checksums))
+	for fileName, checksum := range checksums {
+		if isSBOMZip(fileName) {
+			continue
+		}
+		platform := strings.TrimSuffix(filepath.Base(fileName), ".zip")
+		platform = strings.TrimPrefix(platform, packageName+"_")
+		ret[platform] = checksum
+	}
+	return ret
+}
+
+// checksumsManifest lists the checksums keyed by file name in the format of sha256sum, sorted by file name,
+// which is published as metadata.ChecksumsFileName.
+func checksumsManifest(checksums map[string]string) []byte {
+	fileNames := make([]string, 0, len(checksums))
+	for fileName := range checksums {
+		fileNames = append(fileNames, fileName)
+	}
+	slices.Sort(fileNames)
+	var b bytes.Buffer
+	for _, fileName := range fileNames {
+		fmt.Fprintf(&b, "%s  %s\n", checksums[fileName], fileName)
+	}
+	return b.Bytes()
+}
+
+// packageFiles returns the files of the package among the packages released together.
+// Files are named after the binary zip, i.e. {PackageName}_{GOOS}_{GOARCH}.zip and its SBOM zip,
+// so a file belongs to the longest package name prefixing it, e.g. foo_bar_linux_amd64.zip belongs to foo_bar rather than foo.
+func packageFiles(packageName string, packages []string, files []string) []string {
+	var ret []string
+	for _, fileName := range files {
+		owner := ""
+		for _, name := range packages {
+			if strings.HasPrefix(filepath.Base(fileName), name+"_") && len(name) > len(owner) {
+				owner = name
+			}
+		}
+		if owner == packageName {
+			ret = append(ret, fileName)
+		}
+	}
+	return ret
+}
+
 // isValidLLPkg checks if directory contains both llpkg.cfg and llcppg.cfg
 func isValidLLPkg(files []os.DirEntry) bool {
 	fileMap := make(map[string]struct{}, len(files))
@@ -135,19 +140,37 @@ func checkLegacyVersion(ver *versions.Versions, cfg config.LLPkgConfig, mappedVe
 	if slices.Contains(ver.GoVersions(cfg.Upstream.Package.Name), mappedVersion) {
 		return fmt.Errorf("actions: repeat semver %s", mappedVersion)
 	}
-	vers := ver.CVersions(cfg.Upstream.Package.Name)
-	currentVersion := versions.ToSemVer(cfg.Upstream.Package.Version)
+	clib := cfg.Upstream.Package.Name
+	scheme := cmp.Or(cfg.Upstream.Package.VersionScheme, versions.SchemeSemver)
+	comparator, err := versions.ComparatorOf(scheme)
+	if err != nil {
+		return wrapActionError(err)
+	}
+	// recorded C versions are ordered by the recorded scheme, which can't be changed by a release
+	if _, ok := ver.MetadataMap[clib]; ok {
+		if recorded := ver.VersionScheme(clib); scheme != recorded {
+			return fmt.Errorf("actions: version scheme %s differs from %s recorded for %s", scheme, recorded, clib)
+		}
+		comparator, err = ver.Comparator(clib)
+		if err != nil {
+			return wrapActionError(err)
+		}
+	}
+	currentVersion := cfg.Upstream.Package.Version
 
-	// skip when we're the only latest version or C version doesn't follow semver.
-	if len(vers) == 0 || !semver.IsValid(currentVersion) {
-		return nil
+	if !comparator.IsValid(currentVersion) {
+		return fmt.Errorf("actions: C version %s doesn't follow the version scheme %s", currentVersion, scheme)
 	}
 
-	sort.Sort(versions.ByVersionDescending(vers))
+	vers := ver.SortedCVersions(clib, comparator)
+	// skip when we're the only latest version
+	if len(vers) == 0 {
+		return nil
+	}
 
 	latestVersion := vers[0]
 
-	isLatest := semver.Compare(currentVersion, latestVersion) >= 0
+	isLatest := comparator.Compare(currentVersion, latestVersion) >= 0
 	// fast-path: we're the latest version
 	if isLatest {
 		// case1: we're the latest version, but mapped version is not latest, invalid.
@@ -165,35 +188,31 @@ func checkLegacyVersion(ver *versions.Versions, cfg config.LLPkgConfig, mappedVe
 
 	// find the closest verion which is smaller than us.
 	i := sort.Search(len(vers), func(i int) bool {
-		return semver.Compare(vers[i], currentVersion) < 0
+		return comparator.Compare(vers[i], currentVersion) < 0
 	})
 
-	hasClosestSemver := i < len(vers) &&
-		semver.Compare(vers[i], currentVersion) < 0
+	hasClosestVersion := i < len(vers)
 	// case3: we're the smallest version
 	// example: latest: 1.6.1 maintain: 1.5.1, that's valid
-	if !hasClosestSemver {
+	if !hasClosestVersion {
 		return nil
 	}
 
-	// case4: the major and minor version of the previous version is same,
+	// case4: the previous version is in the same series (major and minor version for semver),
 	// which means we're not the latest patch version, invalid.
 	// example: all version: 1.6.1 1.5.3 1.5.1 current: 1.5.2, so the previous one is 1.5.3, that's invalid
 	previousVersion := vers[i-1]
 
-	if semver.MajorMinor(previousVersion) == semver.MajorMinor(currentVersion) &&
-		semver.Compare(previousVersion, currentVersion) > 0 {
+	if comparator.Series(previousVersion) == comparator.Series(currentVersion) &&
+		comparator.Compare(previousVersion, currentVersion) > 0 {
 		return fmt.Errorf(`actions: cannot submit a historical legacy version.
 	for more details: https://github.com/PengPengPeng717/llpkgstore/blob/main/docs/llpkgstore.md#branch-maintenance-strategy`)
 	}
 
-	// case5: we're the latest patch version for current major and minor, check the mapped version
+	// case5: we're the latest patch version for current series, check the mapped version
 	// our mapped version should be larger than the closest one.
 	// example: current submit: 1.5.2 => v1.1.1, closest minor: 1.4.1 => v1.1.0, valid.
-	originalVersion := ver.SearchBySemVer(cfg.Upstream.Package.Name, vers[i])
-	if originalVersion == "" {
-		return fmt.Errorf("actions: cannot find original C version from semver")
-	}
+	originalVersion := vers[i]
 	closestMappedVersion := ver.LatestGoVersionForCVersion(cfg.Upstream.Package.Name, originalVersion)
 	if closestMappedVersion == "" {
 		return fmt.Errorf("cannot find latest Go version from C version")
@@ -205,6 +224,8 @@ func checkLegacyVersion(ver *versions.Versions, cfg config.LLPkgConfig, mappedVe
 	return nil
 }
 
+// BuildBinaryZip installs the package and zips it as {PackageName}_{GOOS}_{GOARCH}.zip in the working directory,
+// alongside the zip of its SBOMs named by sbomZip.
 func BuildBinaryZip(uc *upstream.Upstream) (zipFileName, zipFilePath string, err error) {
 	tempDir, err := os.MkdirTemp("", "llpkg-tool")
 	if err != nil {
@@ -264,7 +285,46 @@ func BuildBinaryZip(uc *upstream.Upstream) (zipFileName, zipFilePath string, err
 	err = file.Zip(tempDir, zipFilePath)
 	if err != nil {
 		err = wrapActionError(err)
+		return
 	}
 
+	// the SBOMs are written alongside the binary zip, see sbomZip
+	sbom, err := NewSBOM(uc, strings.TrimSuffix(zipFileName, ".zip"), tempDir)
+	if err != nil {
+		return
+	}
+	err = sbom.WriteSBOMZip(filepath.Join(filepath.Dir(zipFilePath), sbomZip(zipFileName)))
 	return
 }
+
+// BuildDebugZip packages the generated result for debugging a failed verification into zipFilePath:
+// generated/ is the generated directory, pkgconfig/ contains the installed .pc files of the package directory,
+// and stderr.log is the stderr of the generation tool.
+func BuildDebugZip(zipFilePath, pkgDir, generatedDir string, stderr []byte) error {
+	tempDir, err := os.MkdirTemp("", "llpkg-debug")
+	if err != nil {
+		return wrapActionError(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := os.Stat(generatedDir); err == nil {
+		err = file.CopyFS(filepath.Join(tempDir, "generated"), os.DirFS(generatedDir), false)
+		if err != nil {
+			return wrapActionError(err)
+		}
+	}
+	pkgConfigDir := filepath.Join(tempDir, "pkgconfig")
+	if err := os.Mkdir(pkgConfigDir, 0777); err != nil {
+		return wrapActionError(err)
+	}
+	if err := file.CopyFilePattern(pkgDir, pkgConfigDir, "*.pc"); err != nil {
+		return wrapActionError(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "stderr.log"), stderr, 0666); err != nil {
+		return wrapActionError(err)
+	}
+	if err := file.Zip(tempDir, zipFilePath); err != nil {
+		return wrapActionError(err)
+	}
+	return nil
+}
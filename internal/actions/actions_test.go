@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
@@ -32,12 +33,17 @@ func TestBuildBinaryZip(t *testing.T) {
 		return
 	}
 
-	_, zipFilepath, err := BuildBinaryZip(uc)
+	zipFilename, zipFilepath, err := BuildBinaryZip(uc)
 	if err != nil {
 		t.Error(err)
 		return
 	}
 	defer os.Remove(zipFilepath)
+	sbomFilepath := filepath.Join(filepath.Dir(zipFilepath), sbomZip(zipFilename))
+	defer os.Remove(sbomFilepath)
+	if _, err := os.Stat(sbomFilepath); err != nil {
+		t.Errorf("Expected SBOM zip: %v", err)
+	}
 
 	zipr, err := zip.OpenReader(zipFilepath)
 	if err != nil {
@@ -86,3 +92,86 @@ func TestBuildBinaryZip(t *testing.T) {
 	}
 
 }
+
+func TestPlatformChecksums(t *testing.T) {
+	checksums := platformChecksums("cjson", map[string]string{
+		"cjson_linux_amd64.zip":      "aaa",
+		"cjson_darwin_arm64.zip":     "bbb",
+		"cjson_linux_amd64_sbom.zip": "ddd",
+		"other.tar.gz":               "ccc",
+	})
+	expected := map[string]string{
+		"linux_amd64":  "aaa",
+		"darwin_arm64": "bbb",
+		"other.tar.gz": "ccc",
+	}
+	if !reflect.DeepEqual(checksums, expected) {
+		t.Errorf("unexpected checksums: want %v got %v", expected, checksums)
+	}
+}
+
+func TestChecksumsManifest(t *testing.T) {
+	manifest := checksumsManifest(map[string]string{
+		"cjson_linux_amd64.zip":  "aaa",
+		"cjson_darwin_arm64.zip": "bbb",
+	})
+	expected := "bbb  cjson_darwin_arm64.zip\naaa  cjson_linux_amd64.zip\n"
+	if string(manifest) != expected {
+		t.Errorf("unexpected manifest: want %q got %q", expected, manifest)
+	}
+}
+
+func TestBuildDebugZip(t *testing.T) {
+	pkgDir := t.TempDir()
+	generated := filepath.Join(pkgDir, ".generated")
+	os.MkdirAll(filepath.Join(generated, "cjson"), 0777)
+	os.WriteFile(filepath.Join(generated, "cjson", "cJSON.go"), []byte("package cjson"), 0644)
+	os.WriteFile(filepath.Join(pkgDir, "libcjson.pc"), []byte("Name: libcjson"), 0644)
+	os.WriteFile(filepath.Join(pkgDir, "llpkg.cfg"), []byte("{}"), 0644)
+
+	zipFilePath := filepath.Join(t.TempDir(), "debug.zip")
+	if err := BuildDebugZip(zipFilePath, pkgDir, generated, []byte("llcppg: error")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var files []string
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, filepath.ToSlash(f.Name))
+		}
+	}
+	expected := []string{"generated/cjson/cJSON.go", "pkgconfig/libcjson.pc", "stderr.log"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	// the generated directory may not exist if the generation fails early
+	if err := BuildDebugZip(zipFilePath, pkgDir, filepath.Join(pkgDir, "missing"), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackageFiles(t *testing.T) {
+	files := []string{
+		"1/foo_linux_amd64.zip",
+		"1/foo_linux_amd64_sbom.zip",
+		"2/foo_bar_linux_amd64.zip",
+		"3/zlib_darwin_arm64.zip",
+		"4/unknown_linux_amd64.zip",
+	}
+	packages := []string{"foo", "foo_bar", "zlib"}
+	expected := map[string][]string{
+		"foo":     {"1/foo_linux_amd64.zip", "1/foo_linux_amd64_sbom.zip"},
+		"foo_bar": {"2/foo_bar_linux_amd64.zip"},
+		"zlib":    {"3/zlib_darwin_arm64.zip"},
+	}
+	for name, want := range expected {
+		if got := packageFiles(name, packages, files); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Expected %v, got %v", name, want, got)
+		}
+	}
+}
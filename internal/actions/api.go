@@ -2,22 +2,29 @@
 package actions
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"mime"
-	"net/http"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/go-github/v69/github"
 	"github.com/PengPengPeng717/llpkgstore/config"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/metadata"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -28,6 +35,12 @@ const (
 
 	defaultReleaseBranch = "main"
 	regexString          = `Release-as:\s%s/v(?P<major>0|[1-9]\d*)\.(?P<minor>0|[1-9]\d*)\.(?P<patch>0|[1-9]\d*)(?:-(?P<prerelease>(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+(?P<buildmetadata>[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?`
+
+	// anyPackage matches the package name of any "Release-as" directive
+	anyPackage = `[^/\s]+`
+
+	// releaseDirName is the directory zips are built into by Release, which is uploaded as an artifact
+	releaseDirName = "llpkg-release"
 )
 
 // regex compiles a regular expression pattern to detect "Release-as" directives in commit messages
@@ -48,38 +61,46 @@ func binaryZip(packageName string) string {
 	return fmt.Sprintf("%s_%s.zip", packageName, currentSuffix)
 }
 
-// DefaultClient provides GitHub API client capabilities with authentication for Actions workflows
+// DefaultClient provides source host and CI capabilities for Actions workflows
 type DefaultClient struct {
-	// repo: Target repository name
-	// owner: Repository owner organization/user
-	// client: Authenticated GitHub API client instance
-	repo   string
-	owner  string
-	client *github.Client
+	// host: Source host client of the repository, e.g. GitHub
+	// ci: Environment of the CI runner, e.g. GitHub Actions
+	// plan: Changes to llpkgstore.json are recorded into it in a dry run
+	host Host
+	ci   CIEnv
+	plan *Plan
 }
 
-// NewDefaultClient initializes a new GitHub API client with authentication and repository configuration
+// NewDefaultClient initializes a new client with authentication and repository configuration
 // Uses:
-//   - GitHub token from environment
+//   - Source host from LLPKGSTORE_SOURCE_HOST, GitHub by default
+//   - Token from environment
 //   - Repository info from GITHUB_REPOSITORY context
 //
 // Returns:
 //
 //	*DefaultClient: Configured client instance
 func NewDefaultClient() (*DefaultClient, error) {
-	token, err := env.Token()
+	host, err := NewHostFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	owner, repo, err := env.Repository()
-	if err != nil {
-		return nil, err
-	}
-	dc := &DefaultClient{
-		owner: owner, repo: repo,
-		client: github.NewClient(nil).WithAuthToken(token),
-	}
-	return dc, nil
+	return NewClient(host, NewGitHubActionsEnv()), nil
+}
+
+// NewClient creates a client operating on the source host in the CI environment
+func NewClient(host Host, ci CIEnv) *DefaultClient {
+	return &DefaultClient{host: host, ci: ci}
+}
+
+// Setenv exports environment variables to the following steps of the CI
+func (d *DefaultClient) Setenv(e env.Env) error {
+	return d.ci.Setenv(e)
+}
+
+// UploadArtifact uploads the file as an artifact of the CI run, and returns the link to it
+func (d *DefaultClient) UploadArtifact(name, fileName string) (string, error) {
+	return d.ci.UploadArtifact(name, fileName)
 }
 
 // hasBranch checks existence of a specific branch in the repository
@@ -91,15 +112,7 @@ func NewDefaultClient() (*DefaultClient, error) {
 //
 //	bool: True if branch exists
 func (d *DefaultClient) hasBranch(branchName string) bool {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	branch, resp, err := d.client.Repositories.GetBranch(
-		ctx, d.owner, d.repo, branchName, 0,
-	)
-
-	return err == nil && branch != nil &&
-		resp.StatusCode == http.StatusOK
+	return d.host.HasBranch(context.TODO(), branchName)
 }
 
 // associatedWithPullRequest finds all pull requests containing the specified commit
@@ -109,18 +122,9 @@ func (d *DefaultClient) hasBranch(branchName string) bool {
 //
 // Returns:
 //
-//	[]*github.PullRequest: List of associated pull requests
-func (d *DefaultClient) associatedWithPullRequest(sha string) ([]*github.PullRequest, error) {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	pulls, _, err := d.client.PullRequests.ListPullRequestsWithCommit(
-		ctx, d.owner, d.repo, sha, &github.ListOptions{},
-	)
-	if err != nil {
-		return nil, wrapActionError(err)
-	}
-	return pulls, nil
+//	[]PullRequest: List of associated pull requests
+func (d *DefaultClient) associatedWithPullRequest(sha string) ([]PullRequest, error) {
+	return d.host.PullRequestsWithCommit(context.TODO(), sha)
 }
 
 // isAssociatedWithPullRequest checks if commit belongs to a closed pull request
@@ -137,7 +141,7 @@ func (d *DefaultClient) isAssociatedWithPullRequest(sha string) bool {
 	// sometime, when a pull request is merged, GetMerge still returns false.
 	// so checking pull request state is more accurate.
 	return len(pulls) > 0 &&
-		pulls[0].GetState() == "closed"
+		pulls[0].State == "closed"
 }
 
 // isLegacyVersion determines if PR targets a legacy branch
@@ -146,16 +150,15 @@ func (d *DefaultClient) isAssociatedWithPullRequest(sha string) bool {
 //	branchName: Base branch name
 //	legacy: True if branch starts with "release-branch."
 func (d *DefaultClient) isLegacyVersion() (branchName string, legacy bool, err error) {
-	event, err := GitHubEvent()
+	pullRequest, err := d.ci.PullRequest()
 	if err != nil {
 		return
 	}
-	pullRequest, ok := event["pull_request"].(map[string]any)
 	var refName string
-	if !ok {
+	if pullRequest == nil {
 		var sha string
-		var pulls []*github.PullRequest
-		sha, err = env.LatestCommitSHA()
+		var pulls []PullRequest
+		sha, err = d.ci.LatestCommitSHA()
 		if err != nil {
 			return
 		}
@@ -164,11 +167,13 @@ func (d *DefaultClient) isLegacyVersion() (branchName string, legacy bool, err e
 		if err != nil {
 			return
 		}
-		refName = pulls[0].GetBase().GetRef()
+		if len(pulls) == 0 {
+			err = fmt.Errorf("actions: no pull request associated with %s", sha)
+			return
+		}
+		refName = pulls[0].BaseRef
 	} else {
-		// unnecessary to check type, because currentPRCommit has been checked.
-		base := pullRequest["base"].(map[string]any)
-		refName = base["ref"].(string)
+		refName = pullRequest.BaseRef
 	}
 
 	legacy = strings.HasPrefix(refName, BranchPrefix)
@@ -179,43 +184,25 @@ func (d *DefaultClient) isLegacyVersion() (branchName string, legacy bool, err e
 // currentPRCommit retrieves all commits in the current pull request
 // Returns:
 //
-//	[]*github.RepositoryCommit: List of PR commits
-func (d *DefaultClient) currentPRCommit() ([]*github.RepositoryCommit, error) {
-	pullRequest, err := PullRequestEvent()
+//	[]Commit: List of PR commits
+func (d *DefaultClient) currentPRCommit() ([]Commit, error) {
+	pullRequest, err := d.ci.PullRequest()
 	if err != nil {
 		return nil, err
 	}
-	prNumber := int(pullRequest["number"].(float64))
-
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-	// use authorized API to avoid Github RateLimit
-	commits, _, err := d.client.PullRequests.ListCommits(
-		ctx, d.owner, d.repo, prNumber,
-		&github.ListOptions{},
-	)
-	if err != nil {
-		return nil, wrapActionError(err)
+	if pullRequest == nil {
+		return nil, fmt.Errorf("env: cannot parse GITHUB_EVENT_PATH pull_request")
 	}
-	return commits, nil
+	return d.host.PullRequestCommits(context.TODO(), pullRequest.Number)
 }
 
-// allCommits retrieves all repository commits
+// findCommit retrieves repository commits from the latest until one matches
 // Returns:
 //
-//	[]*github.RepositoryCommit: List of all commits
-func (d *DefaultClient) allCommits() ([]*github.RepositoryCommit, error) {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-	// use authorized API to avoid Github RateLimit
-	commits, _, err := d.client.Repositories.ListCommits(
-		ctx, d.owner, d.repo,
-		&github.CommitsListOptions{},
-	)
-	if err != nil {
-		return nil, wrapActionError(err)
-	}
-	return commits, nil
+//	bool: Whether a commit matches
+func (d *DefaultClient) findCommit(match func(Commit) bool) (bool, error) {
+	_, found, err := d.host.FindCommit(context.TODO(), match)
+	return found, err
 }
 
 // removeLabel deletes a label from the repository
@@ -223,13 +210,7 @@ func (d *DefaultClient) allCommits() ([]*github.RepositoryCommit, error) {
 //
 //	labelName: Name of the label to remove
 func (d *DefaultClient) removeLabel(labelName string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-	// use authorized API to avoid Github RateLimit
-	_, err := d.client.Issues.DeleteLabel(
-		ctx, d.owner, d.repo, labelName,
-	)
-	return wrapActionError(err)
+	return d.host.RemoveLabel(context.TODO(), labelName)
 }
 
 // checkMappedVersion validates PR contains valid "Release-as" version declaration
@@ -252,8 +233,7 @@ func (d *DefaultClient) checkMappedVersion(packageName string) (mappedVersion st
 		return
 	}
 	for _, commit := range allCommits {
-		message := commit.GetCommit().GetMessage()
-		if mappedVersion = matchMappedVersion.FindString(message); mappedVersion != "" {
+		if mappedVersion = matchMappedVersion.FindString(commit.Message); mappedVersion != "" {
 			// remove space, of course
 			mappedVersion = strings.TrimSpace(mappedVersion)
 			break
@@ -266,6 +246,16 @@ func (d *DefaultClient) checkMappedVersion(packageName string) (mappedVersion st
 	return
 }
 
+// MappedVersion returns the mapped version of the package declared by "Release-as" in current PR, e.g. v1.0.0
+func (d *DefaultClient) MappedVersion(packageName string) (string, error) {
+	version, err := d.checkMappedVersion(packageName)
+	if err != nil {
+		return "", err
+	}
+	_, mappedVersion, err := parseMappedVersion(version)
+	return mappedVersion, err
+}
+
 // commitMessage retrieves commit details by SHA
 // Parameters:
 //
@@ -273,50 +263,45 @@ func (d *DefaultClient) checkMappedVersion(packageName string) (mappedVersion st
 //
 // Returns:
 //
-//	*github.RepositoryCommit: Commit details object
-func (d *DefaultClient) commitMessage(sha string) (*github.RepositoryCommit, error) {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	commit, _, err := d.client.Repositories.GetCommit(ctx, d.owner, d.repo, sha, &github.ListOptions{})
-	if err != nil {
-		return nil, wrapActionError(err)
-	}
-	return commit, nil
+//	Commit: Commit details object
+func (d *DefaultClient) commitMessage(sha string) (Commit, error) {
+	return d.host.Commit(context.TODO(), sha)
 }
 
-// mappedVersion parses the latest commit's mapped version from "Release-as" directive
+// mappedVersions parses the mapped versions of all the packages from "Release-as" directives
+// in the latest commit's message, a pull request may release several packages.
 // Returns:
 //
-//	string: Parsed version string or empty if not found
-//
-// Panics:
-//
-//	If version format is invalid
-func (d *DefaultClient) mappedVersion() (string, error) {
-	sha, err := env.LatestCommitSHA()
+//	[]string: Parsed versions, e.g. cjson/v1.0.0
+func (d *DefaultClient) mappedVersions() ([]string, error) {
+	sha, err := d.ci.LatestCommitSHA()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	// get message
 	commit, err := d.commitMessage(sha)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	message := commit.GetCommit().GetMessage()
-
-	// parse the mapped version
-	mappedVersion := regex(".*").FindString(message)
+	// parse the mapped versions
+	mappedVersions := regex(anyPackage).FindAllString(commit.Message, -1)
 	// mapped version not found, a normal commit?
-	if mappedVersion == "" {
-		return "", ErrNoMappedVersion
-	}
-	version := strings.TrimPrefix(mappedVersion, MappedVersionPrefix)
-	if version == mappedVersion {
-		return "", fmt.Errorf("actions: invalid format")
+	if len(mappedVersions) == 0 {
+		return nil, ErrNoMappedVersion
+	}
+	var ret []string
+	for _, mappedVersion := range mappedVersions {
+		version := strings.TrimPrefix(mappedVersion, MappedVersionPrefix)
+		if version == mappedVersion {
+			return nil, fmt.Errorf("actions: invalid format")
+		}
+		version = strings.TrimSpace(version)
+		if !slices.Contains(ret, version) {
+			ret = append(ret, version)
+		}
 	}
-	return strings.TrimSpace(version), nil
+	return ret, nil
 }
 
 // createTag creates a new Git tag pointing to specific commit
@@ -329,19 +314,7 @@ func (d *DefaultClient) mappedVersion() (string, error) {
 //
 //	error: Error during tag creation
 func (d *DefaultClient) createTag(tag, sha string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	// tag the commit
-	tagRefName := tagRef(tag)
-	_, _, err := d.client.Git.CreateRef(ctx, d.owner, d.repo, &github.Reference{
-		Ref: &tagRefName,
-		Object: &github.GitObject{
-			SHA: &sha,
-		},
-	})
-
-	return wrapActionError(err)
+	return d.host.CreateTag(context.TODO(), tag, sha)
 }
 
 // createBranch creates a new branch pointing to specific commit
@@ -354,138 +327,147 @@ func (d *DefaultClient) createTag(tag, sha string) error {
 //
 //	error: Error during branch creation
 func (d *DefaultClient) createBranch(branchName, sha string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	branchRefName := branchRef(branchName)
-	_, _, err := d.client.Git.CreateRef(ctx, d.owner, d.repo, &github.Reference{
-		Ref: &branchRefName,
-		Object: &github.GitObject{
-			SHA: &sha,
-		},
-	})
-
-	return wrapActionError(err)
+	return d.host.CreateBranch(context.TODO(), branchName, sha)
 }
 
-func (d *DefaultClient) createReleaseByTag(tag string) (*github.RepositoryRelease, error) {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	branch := defaultReleaseBranch
-
+// createReleaseByTag creates a release of the tag with the release notes
+func (d *DefaultClient) createReleaseByTag(tag string, notes *ReleaseNotes) (Release, error) {
 	_, isLegacy, err := d.isLegacyVersion()
 	if err != nil {
-		return nil, err
+		return Release{}, err
 	}
 
-	makeLatest := "true"
-	if isLegacy {
-		makeLatest = "legacy"
-	}
-	generateRelease := true
-
-	release, _, err := d.client.Repositories.CreateRelease(ctx, d.owner, d.repo, &github.RepositoryRelease{
-		TagName:              &tag,
-		TargetCommitish:      &branch,
-		Name:                 &tag,
-		MakeLatest:           &makeLatest,
-		GenerateReleaseNotes: &generateRelease,
+	return d.host.CreateRelease(context.TODO(), ReleaseOptions{
+		Tag:    tag,
+		Target: defaultReleaseBranch,
+		Name:   tag,
+		Body:   notes.Markdown(),
+		Legacy: isLegacy,
 	})
-	if err != nil {
-		return nil, wrapActionError(err)
-	}
-
-	return release, nil
 }
 
-func (d *DefaultClient) uploadToRelease(
-	fileName string,
-	size int64,
-	reader io.Reader,
-	release *github.RepositoryRelease,
-) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	url := fmt.Sprintf("repos/%s/%s/releases/%d/assets?name=%s", d.owner, d.repo, release.GetID(), fileName)
-
-	req, err := d.client.NewUploadRequest(url, reader, size, "application/zip")
+// downloadArtifacts downloads the artifacts of current workflow run into dir, and returns the paths of the files in them.
+// An artifact is a zip archive of the files uploaded by actions/upload-artifact, it's extracted
+// so that the files are published as they were built.
+func (d *DefaultClient) downloadArtifacts(dir string) ([]string, error) {
+	id, err := d.ci.WorkflowRunID()
 	if err != nil {
-		return wrapActionError(err)
+		return nil, err
 	}
-
-	asset := new(github.ReleaseAsset)
-	_, err = d.client.Do(ctx, req, asset)
+	artifacts, err := d.host.Artifacts(context.TODO(), id)
 	if err != nil {
-		return wrapActionError(err)
+		return nil, err
 	}
-	return nil
-}
 
-func (d *DefaultClient) uploadArtifact(artifactID int64, release *github.RepositoryRelease) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	url, _, err := d.client.Actions.DownloadArtifact(ctx, d.owner, d.repo,
-		artifactID, 0)
+	var files []string
+	seen := map[string]bool{}
+	for _, artifact := range artifacts {
+		artifactDir := filepath.Join(dir, strconv.FormatInt(artifact.ID, 10))
+		if err := d.downloadArtifact(artifact, artifactDir); err != nil {
+			return nil, err
+		}
+		err := filepath.WalkDir(artifactDir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			// release assets are flat, files of the same name would overwrite each other
+			if seen[entry.Name()] {
+				return fmt.Errorf("actions: %s is found in more than one artifact", entry.Name())
+			}
+			seen[entry.Name()] = true
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return nil, wrapActionError(err)
+		}
+	}
+	return files, nil
+}
 
+// downloadArtifact downloads the artifact and extracts its files into dir
+func (d *DefaultClient) downloadArtifact(artifact Artifact, dir string) error {
+	_, _, body, err := d.host.DownloadArtifact(context.TODO(), artifact)
 	if err != nil {
-		return wrapActionError(err)
+		return err
 	}
+	defer body.Close()
 
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-
-	resp, err := httpClient.Get(url.String())
+	archive, err := os.CreateTemp("", "llpkg-artifact-*.zip")
 	if err != nil {
 		return wrapActionError(err)
 	}
-	defer resp.Body.Close()
-
-	disposition := resp.Header.Get("Content-Disposition")
-	_, params, err := mime.ParseMediaType(disposition)
+	defer os.Remove(archive.Name())
+	_, err = io.Copy(archive, body)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return wrapActionError(err)
 	}
-
-	fileName, ok := params["filename"]
-	if !ok {
-		return errors.New("actions: no filename found in Content-Disposition")
+	if err := metadata.Unzip(archive.Name(), dir); err != nil {
+		return fmt.Errorf("actions: artifact %s: %w", artifact.Name, err)
 	}
-
-	fmt.Printf("Upload %s to %s\n", fileName, release.GetName())
-
-	return d.uploadToRelease(fileName, resp.ContentLength, resp.Body, release)
+	return nil
 }
 
-func (d *DefaultClient) uploadArtifactsToRelease(release *github.RepositoryRelease) (files []*os.File, err error) {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
+// uploadFile uploads the file as an asset of the release,
+// and returns the hex encoded sha256 of the uploaded bytes.
+func (d *DefaultClient) uploadFile(fileName string, release Release) (checksum string, err error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", wrapActionError(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", wrapActionError(err)
+	}
 
-	id, err := env.WorkflowRunID()
-	artifacts, _, err := d.client.Actions.ListWorkflowRunArtifacts(ctx, d.owner, d.repo,
-		id, &github.ListOptions{})
+	fmt.Printf("Upload %s to %s\n", filepath.Base(fileName), release.Name)
 
+	// compute the digest while uploading
+	h := sha256.New()
+	err = d.host.UploadReleaseAsset(context.TODO(), release, filepath.Base(fileName), info.Size(), io.TeeReader(f, h))
 	if err != nil {
-		return nil, wrapActionError(err)
+		return "", err
 	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	if artifacts.GetTotalCount() == 0 {
+// uploadFilesToRelease uploads the files of the package to the release,
+// and returns the hex encoded sha256 of each uploaded asset keyed by file name.
+func (d *DefaultClient) uploadFilesToRelease(files []string, release Release) (checksums map[string]string, err error) {
+	if len(files) == 0 {
 		return nil, errors.New("actions: no artifact found")
 	}
 
 	errGroup, _ := errgroup.WithContext(context.TODO())
 
-	for _, artifact := range artifacts.Artifacts {
-		// make a copy to avoid for loop bug
-		artifactID := artifact.GetID()
+	var mu sync.Mutex
+	checksums = make(map[string]string, len(files))
 
+	for _, fileName := range files {
 		errGroup.Go(func() error {
-			return d.uploadArtifact(artifactID, release)
+			checksum, err := d.uploadFile(fileName, release)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			checksums[filepath.Base(fileName)] = checksum
+			mu.Unlock()
+			return nil
 		})
 	}
 
-	return nil, errGroup.Wait()
+	return checksums, errGroup.Wait()
+}
+
+// uploadChecksumsToRelease uploads the manifest of the checksums of the release assets keyed by file name,
+// so that the assets can be verified by sha256sum -c.
+func (d *DefaultClient) uploadChecksumsToRelease(checksums map[string]string, release Release) error {
+	manifest := checksumsManifest(checksums)
+	return d.host.UploadReleaseAsset(context.TODO(), release, metadata.ChecksumsFileName, int64(len(manifest)), bytes.NewReader(manifest))
 }
 
 // removeBranch deletes a branch from the repository
@@ -497,12 +479,7 @@ func (d *DefaultClient) uploadArtifactsToRelease(release *github.RepositoryRelea
 //
 //	error: Error during branch deletion
 func (d *DefaultClient) removeBranch(branchName string) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
-	defer cancel()
-
-	_, err := d.client.Git.DeleteRef(ctx, d.owner, d.repo, branchRef(branchName))
-
-	return wrapActionError(err)
+	return d.host.RemoveBranch(context.TODO(), branchName)
 }
 
 // checkVersion performs version validation and configuration checks
@@ -529,14 +506,15 @@ func (d *DefaultClient) checkVersion(ver *versions.Versions, cfg config.LLPkgCon
 	return checkLegacyVersion(ver, cfg, mappedVersion, isLegacy)
 }
 
-// CheckPR validates PR changes and returns affected packages
+// CheckPR validates PR changes and returns affected packages,
+// every package must have its own "Release-as" directive in the PR commits.
 // Returns:
 //
-//	[]string: List of affected package paths
+//	[]string: Sorted list of affected package paths
 func (d *DefaultClient) CheckPR() ([]string, error) {
 	// build a file path map
 	pathMap := map[string][]string{}
-	changedFilePaths, err := env.Changes()
+	changedFilePaths, err := d.ci.Changes()
 	if err != nil {
 		return nil, err
 	}
@@ -548,7 +526,10 @@ func (d *DefaultClient) CheckPR() ([]string, error) {
 
 	var allPaths []string
 
-	ver := versions.Read("llpkgstore.json")
+	ver, err := versions.Read("llpkgstore.json")
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
 
 	for path := range pathMap {
 		// don't retrieve files from pr changes, consider about maintenance case
@@ -585,24 +566,22 @@ func (d *DefaultClient) CheckPR() ([]string, error) {
 		allPaths = append(allPaths, path)
 	}
 
-	// 1. Check there's only one directory in PR
-	if len(pathMap) > 1 {
-		return nil, fmt.Errorf("actions: too many to-be-converted directory")
-	}
-
-	// 2. Check config files(llpkg.cfg and llcppg.cfg/llpyg.cfg)
+	// 1. Check config files(llpkg.cfg and llcppg.cfg/llpyg.cfg)
+	// a PR may convert several directories, each of them is released by its own Release-as
 	if len(pathMap) == 0 {
 		return nil, fmt.Errorf("actions: no valid config files, llpkg.cfg and llcppg.cfg/llpyg.cfg must exist")
 	}
 
+	sort.Strings(allPaths)
 	return allPaths, nil
 }
 
 // Postprocessing handles version tagging and record updates after PR merge
-// Creates Git tags, updates version records, and cleans up legacy branches
+// Creates Git tags, updates version records, and cleans up legacy branches.
+// Each package released by the PR is processed independently, a failed one doesn't stop the others.
 func (d *DefaultClient) Postprocessing() error {
 	// https://docs.github.com/en/actions/writing-workflows/choosing-when-your-workflow-runs/events-that-trigger-workflows#push
-	sha, err := env.LatestCommitSHA()
+	sha, err := d.ci.LatestCommitSHA()
 	if err != nil {
 		return err
 	}
@@ -612,42 +591,51 @@ func (d *DefaultClient) Postprocessing() error {
 		return fmt.Errorf("actions: not a merge request commit")
 	}
 
-	version, err := d.mappedVersion()
+	releases, err := d.mappedVersions()
 	if err != nil {
 		return err
 	}
 
-	clib, mappedVersion, err := parseMappedVersion(version)
-	if err != nil {
-		return err
+	var privateKey ed25519.PrivateKey
+	// llpkgstore.json is not written in a dry run, so the signing key is not required
+	if d.plan == nil {
+		signingKey, err := env.SigningKey()
+		if err != nil {
+			return err
+		}
+		privateKey, err = metadata.ParsePrivateKey(signingKey)
+		if err != nil {
+			return wrapActionError(err)
+		}
 	}
 
-	// the pr has merged, so we can read it.
-	cfg, err := config.ParseLLPkgConfig(filepath.Join(clib, "llpkg.cfg"))
+	artifactDir, err := os.MkdirTemp("", "llpkg-artifacts")
 	if err != nil {
-		return err
-	}
-
-	// write it to llpkgstore.json
-	ver := versions.Read("llpkgstore.json")
-	ver.Write(clib, cfg.Upstream.Package.Version, mappedVersion)
-
-	if hasTag(version) {
-		return fmt.Errorf("actions: tag has already existed")
+		return wrapActionError(err)
 	}
-
-	if err := d.createTag(version, sha); err != nil {
+	defer os.RemoveAll(artifactDir)
+	files, err := d.downloadArtifacts(artifactDir)
+	if err != nil {
 		return err
 	}
 
-	// create a release
-	release, err := d.createReleaseByTag(version)
-	if err != nil {
-		return err
+	var packages []string
+	for _, version := range releases {
+		clib, _, err := parseMappedVersion(version)
+		if err != nil {
+			return err
+		}
+		packages = append(packages, clib)
 	}
 
-	_, err = d.uploadArtifactsToRelease(release)
-	if err != nil {
+	var errs []error
+	for i, version := range releases {
+		err := d.postprocessPackage(version, sha, packageFiles(packages[i], packages, files), privateKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", version, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
 		return err
 	}
 
@@ -663,39 +651,146 @@ func (d *DefaultClient) Postprocessing() error {
 	// move to website in Github Action...
 }
 
-// Release must be called before Postprocessing
-func (d *DefaultClient) Release() error {
-	version, err := d.mappedVersion()
+// postprocessPackage tags and releases the mapped version of a package merged at sha,
+// uploads the files of the package from the artifacts to the release, and records it in llpkgstore.json.
+func (d *DefaultClient) postprocessPackage(version, sha string, files []string, privateKey ed25519.PrivateKey) error {
+	clib, mappedVersion, err := parseMappedVersion(version)
 	if err != nil {
 		return err
 	}
 
-	clibName, _, err := parseMappedVersion(version)
+	// the pr has merged, so we can read it.
+	cfg, err := config.ParseLLPkgConfig(filepath.Join(clib, "llpkg.cfg"))
 	if err != nil {
 		return err
 	}
 
-	// the pr has merged, so we can read it.
-	cfg, err := config.ParseLLPkgConfig(filepath.Join(clibName, "llpkg.cfg"))
+	if hasTag(version) {
+		return fmt.Errorf("actions: tag has already existed")
+	}
+
+	// stage the mapping before publishing anything, so an invalid mapping fails before tagging,
+	// and it's only written after the release is published.
+	ver, err := versions.Read("llpkgstore.json")
+	if err != nil {
+		return wrapActionError(err)
+	}
+	tx, err := ver.Begin()
 	if err != nil {
+		return wrapActionError(err)
+	}
+	releaseInfo := &metadata.Release{
+		PublishedAt: time.Now().UTC(),
+		Commit:      sha,
+		Installer:   cfg.Upstream.Installer.Name,
+	}
+	if err := tx.WriteRelease(clib, cfg.Upstream.Package.Version, mappedVersion, releaseInfo); err != nil {
+		return wrapActionError(err)
+	}
+	if err := tx.SetVersionScheme(clib, cfg.Upstream.Package.VersionScheme); err != nil {
+		return wrapActionError(err)
+	}
+
+	if err := d.createTag(version, sha); err != nil {
 		return err
 	}
 
+	// create a release
 	uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
 	if err != nil {
 		return err
 	}
+	notes := NewReleaseNotes(LocalOptions{RepoDir: "."}, ver, uc, cfg.Upstream.Installer.Name, clib, mappedVersion)
+	release, err := d.createReleaseByTag(version, notes)
+	if err != nil {
+		return err
+	}
 
-	zipFilename, zipFilePath, err := BuildBinaryZip(uc)
+	checksums, err := d.uploadFilesToRelease(files, release)
 	if err != nil {
 		return err
 	}
+	if err := d.uploadChecksumsToRelease(checksums, release); err != nil {
+		return err
+	}
+	// checksums of assets are only known after uploading
+	notes.Checksums = checksums
+	if err := d.host.EditReleaseNotes(context.TODO(), release, notes.Markdown()); err != nil {
+		return err
+	}
+	releaseInfo.Checksums = platformChecksums(clib, checksums)
+
+	if d.plan != nil {
+		d.plan.add("map %s %s => %s in llpkgstore.json with checksums %v",
+			clib, cfg.Upstream.Package.Version, mappedVersion, releaseInfo.Checksums)
+		return nil
+	}
+	// sign it, llpkgstore.json.sig MUST be published alongside llpkgstore.json
+	if err := tx.Commit(privateKey); err != nil {
+		return wrapActionError(err)
+	}
+	return nil
+}
+
+// Release must be called before Postprocessing, it builds the binary zip and the SBOM zip of each package released by the PR
+// into a directory, which is exported by BIN_PATH for the workflow to upload as the artifact named BIN_FILENAME by actions/upload-artifact.
+// The artifact name is the binary zip of the first package, so artifacts of different platforms don't collide.
+func (d *DefaultClient) Release() error {
+	releases, err := d.mappedVersions()
+	if err != nil {
+		return err
+	}
+
+	releaseDir, err := filepath.Abs(releaseDirName)
+	if err != nil {
+		return wrapActionError(err)
+	}
+	// never upload zips left by a previous run
+	if err := os.RemoveAll(releaseDir); err != nil {
+		return wrapActionError(err)
+	}
+	if err := os.MkdirAll(releaseDir, 0755); err != nil {
+		return wrapActionError(err)
+	}
+
+	var artifactName string
+	for _, version := range releases {
+		clibName, _, err := parseMappedVersion(version)
+		if err != nil {
+			return err
+		}
+
+		// the pr has merged, so we can read it.
+		cfg, err := config.ParseLLPkgConfig(filepath.Join(clibName, "llpkg.cfg"))
+		if err != nil {
+			return err
+		}
+
+		uc, err := config.NewUpstreamFromConfig(cfg.Upstream)
+		if err != nil {
+			return err
+		}
+
+		zipFilename, zipFilePath, err := BuildBinaryZip(uc)
+		if err != nil {
+			return err
+		}
+		sbomPath := filepath.Join(filepath.Dir(zipFilePath), sbomZip(zipFilename))
+		for _, fileName := range []string{zipFilePath, sbomPath} {
+			if err := os.Rename(fileName, filepath.Join(releaseDir, filepath.Base(fileName))); err != nil {
+				return wrapActionError(err)
+			}
+		}
+		if artifactName == "" {
+			artifactName = strings.TrimSuffix(zipFilename, ".zip")
+		}
+	}
 
 	// upload to artifacts in GitHub Action
 	// https://github.com/goplus/llpkg/pull/50/files#diff-95373be0ab51a56a2200c8c07981d82e81569f2cd1e4e2946e2002bb66de766fR56-R60
-	return env.Setenv(env.Env{
-		"BIN_PATH":     zipFilePath,
-		"BIN_FILENAME": strings.TrimSuffix(zipFilename, ".zip"),
+	return d.ci.Setenv(env.Env{
+		"BIN_PATH":     releaseDir,
+		"BIN_FILENAME": artifactName,
 	})
 }
 
@@ -726,15 +821,22 @@ func (d *DefaultClient) CreateBranchFromLabel(labelName string) error {
 	// according to branch maintenance strategy
 
 	// get latest version of the clib
-	ver := versions.Read("llpkgstore.json")
+	ver, err := versions.Read("llpkgstore.json")
+	if err != nil {
+		return wrapActionError(err)
+	}
 
-	cversions := ver.CVersions(clib)
-	if len(cversions) == 0 {
+	comparator, err := ver.Comparator(clib)
+	if err != nil {
+		return wrapActionError(err)
+	}
+
+	if len(ver.CVersions(clib)) == 0 {
 		return fmt.Errorf("actions: no clib found")
 	}
 
-	if !versions.IsSemver(cversions) {
-		return fmt.Errorf("actions: c version dones't follow semver, skip maintaining")
+	if len(ver.SortedCVersions(clib, comparator)) == 0 {
+		return fmt.Errorf("actions: c version dones't follow the version scheme, skip maintaining")
 	}
 
 	return d.createBranch(branchName, shaFromTag(version))
@@ -743,31 +845,24 @@ func (d *DefaultClient) CreateBranchFromLabel(labelName string) error {
 // CleanResource removes labels and resources after issue resolution
 // Verifies issue closure via PR merge before deletion
 func (d *DefaultClient) CleanResource() error {
-	issueEvent, err := IssueEvent()
+	issue, err := d.ci.Issue()
 	if err != nil {
 		return err
 	}
 
-	issueNumber := int(issueEvent["number"].(float64))
-	regex := regexp.MustCompile(fmt.Sprintf(`(f|F)ix.*#%d`, issueNumber))
+	regex := regexp.MustCompile(fmt.Sprintf(`(f|F)ix.*#%d`, issue.Number))
 
 	// 1. check this issue is closed by a PR
 	// In Github, close a issue with a commit whose message follows this format
 	// fix/Fix* #{IssueNumber}
-	found := false
-	allCommits, err := d.allCommits()
+	// stop at the first match instead of listing the whole history
+	found, err := d.findCommit(func(commit Commit) bool {
+		return regex.MatchString(commit.Message) &&
+			d.isAssociatedWithPullRequest(commit.SHA)
+	})
 	if err != nil {
 		return err
 	}
-	for _, commit := range allCommits {
-		message := commit.Commit.GetMessage()
-
-		if regex.MatchString(message) &&
-			d.isAssociatedWithPullRequest(commit.GetSHA()) {
-			found = true
-			break
-		}
-	}
 
 	if !found {
 		return fmt.Errorf("actions: current issue isn't closed by merged PR")
@@ -776,9 +871,7 @@ func (d *DefaultClient) CleanResource() error {
 	var labelName string
 
 	// 2. find out the branch name from the label
-	for _, labels := range issueEvent["labels"].([]map[string]any) {
-		label := labels["name"].(string)
-
+	for _, label := range issue.Labels {
 		if strings.HasPrefix(label, BranchPrefix) {
 			labelName = label
 			break
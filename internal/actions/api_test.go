@@ -45,7 +45,7 @@ func TestLegacyVersion1(t *testing.T) {
 	b := []byte(`{
 		"cjson": {
 			"versions" : {
-				"1.7.16": ["v0.1.0"],
+				"1.7.16": ["v0.0.1"],
 				"1.7.18": ["v0.1.2", "v0.1.3"],
 				"1.8.18": ["v0.1.0", "v0.1.1"]
 			}
@@ -56,9 +56,12 @@ func TestLegacyVersion1(t *testing.T) {
 	defer os.Remove(".llpkgstore.json")
 
 	cfg, _ := config.ParseLLPkgConfig(".llpkg.cfg")
-	ver := versions.Read(".llpkgstore.json")
+	ver, err := versions.Read(".llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := actionFn("main", func(legacy bool) error {
+	err = actionFn("main", func(legacy bool) error {
 		return checkLegacyVersion(ver, cfg, "v0.1.1", legacy)
 	})
 
@@ -86,7 +89,7 @@ func TestLegacyVersion2(t *testing.T) {
 			"versions" : {
 				"1.8.18": ["v0.2.0", "v0.2.1"],
 				"1.7.18": ["v0.1.0", "v0.1.1"],
-				"1.7.16: ["v1.1.0"]
+				"1.7.16": ["v1.1.0"]
 			}
 		}
 	}`)
@@ -95,9 +98,12 @@ func TestLegacyVersion2(t *testing.T) {
 	defer os.Remove(".llpkgstore.json")
 
 	cfg, _ := config.ParseLLPkgConfig(".llpkg.cfg")
-	ver := versions.Read(".llpkgstore.json")
+	ver, err := versions.Read(".llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := actionFn("release-branch.cjson/v0.1.1", func(legacy bool) error {
+	err = actionFn("release-branch.cjson/v0.1.1", func(legacy bool) error {
 		return checkLegacyVersion(ver, cfg, "v0.1.2", legacy)
 	})
 	isValid := err == nil
@@ -134,9 +140,12 @@ func TestLegacyVersion3(t *testing.T) {
 	defer os.Remove(".llpkgstore.json")
 
 	cfg, _ := config.ParseLLPkgConfig(".llpkg.cfg")
-	ver := versions.Read(".llpkgstore.json")
+	ver, err := versions.Read(".llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := actionFn("main", func(legacy bool) error {
+	err = actionFn("main", func(legacy bool) error {
 		return checkLegacyVersion(ver, cfg, "v0.3.0", legacy)
 	})
 	isValid := err == nil
@@ -173,9 +182,12 @@ func TestLegacyVersion4(t *testing.T) {
 	defer os.Remove(".llpkgstore.json")
 
 	cfg, _ := config.ParseLLPkgConfig(".llpkg.cfg")
-	ver := versions.Read(".llpkgstore.json")
+	ver, err := versions.Read(".llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := actionFn("main", func(legacy bool) error {
+	err = actionFn("main", func(legacy bool) error {
 		return checkLegacyVersion(ver, cfg, "v0.0.1", legacy)
 	})
 
@@ -211,9 +223,12 @@ func TestLegacyVersion5(t *testing.T) {
 	defer os.Remove(".llpkgstore.json")
 
 	cfg, _ := config.ParseLLPkgConfig(".llpkg.cfg")
-	ver := versions.Read(".llpkgstore.json")
+	ver, err := versions.Read(".llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := actionFn("main", func(legacy bool) error {
+	err = actionFn("main", func(legacy bool) error {
 		return checkLegacyVersion(ver, cfg, "v0.1.1", legacy)
 	})
 
@@ -222,3 +237,65 @@ func TestLegacyVersion5(t *testing.T) {
 		return
 	}
 }
+
+func TestLegacyVersionScheme(t *testing.T) {
+	b := []byte(`{
+		"sqlite3": {
+			"versionScheme": "sqlite",
+			"versions" : {
+				"3460000": ["v1.2.0"],
+				"3450100": ["v1.1.0"],
+				"3450300": ["v1.1.1"],
+				"3390400": ["v1.0.0"]
+			}
+		},
+		"openssl": {
+			"versionScheme": "numeric",
+			"versions" : {
+				"3.1.5": ["v1.2.0"],
+				"3.0.13a": ["v1.1.0"],
+				"1.1.1w": ["v1.0.0"]
+			}
+		}
+	}`)
+
+	os.WriteFile(".llpkgstore.json", []byte(b), 0755)
+	defer os.Remove(".llpkgstore.json")
+
+	ver, err := versions.Read(".llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		pkg           config.PackageConfig
+		mappedVersion string
+		branchName    string
+		valid         bool
+	}{
+		{"sqlite latest", config.PackageConfig{Name: "sqlite3", Version: "3470000", VersionScheme: "sqlite"}, "v1.3.0", "main", true},
+		{"sqlite latest with smaller mapped version", config.PackageConfig{Name: "sqlite3", Version: "3470000", VersionScheme: "sqlite"}, "v1.1.2", "main", false},
+		{"sqlite legacy to main", config.PackageConfig{Name: "sqlite3", Version: "3450400", VersionScheme: "sqlite"}, "v1.1.2", "main", false},
+		{"sqlite legacy", config.PackageConfig{Name: "sqlite3", Version: "3450400", VersionScheme: "sqlite"}, "v1.1.2", "release-branch.sqlite3/v1.1.1", true},
+		{"sqlite historical legacy", config.PackageConfig{Name: "sqlite3", Version: "3450200", VersionScheme: "sqlite"}, "v1.1.2", "release-branch.sqlite3/v1.1.1", false},
+		{"numeric legacy", config.PackageConfig{Name: "openssl", Version: "3.0.13b", VersionScheme: "numeric"}, "v1.1.1", "release-branch.openssl/v1.1.0", true},
+		{"numeric historical legacy", config.PackageConfig{Name: "openssl", Version: "3.0.12", VersionScheme: "numeric"}, "v1.1.1", "release-branch.openssl/v1.1.0", false},
+		{"numeric smaller mapped version", config.PackageConfig{Name: "openssl", Version: "3.0.13b", VersionScheme: "numeric"}, "v1.0.1", "release-branch.openssl/v1.1.0", false},
+		{"scheme changed", config.PackageConfig{Name: "sqlite3", Version: "3470000", VersionScheme: "numeric"}, "v1.3.0", "main", false},
+		{"scheme omitted", config.PackageConfig{Name: "sqlite3", Version: "3470000"}, "v1.3.0", "main", false},
+		{"invalid version", config.PackageConfig{Name: "sqlite3", Version: "3.47.0", VersionScheme: "sqlite"}, "v1.3.0", "main", false},
+		{"new clib", config.PackageConfig{Name: "zlib", Version: "1.3.1", VersionScheme: "numeric"}, "v1.0.0", "main", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.LLPkgConfig{Upstream: config.UpstreamConfig{Package: tc.pkg}}
+			err := actionFn(tc.branchName, func(legacy bool) error {
+				return checkLegacyVersion(ver, cfg, tc.mappedVersion, legacy)
+			})
+			if isValid := err == nil; isValid != tc.valid {
+				t.Errorf("Expected valid %v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
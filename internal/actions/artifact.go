@@ -0,0 +1,181 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+)
+
+// artifactService is the twirp service of the results service managing artifacts (artifact v4),
+// which is used by actions/upload-artifact.
+const artifactService = "twirp/github.actions.results.api.v1.ArtifactService/"
+
+// backendIDs extracts the backend IDs of the workflow run and the job from the runtime token,
+// which is a JWT with the scope "Actions.Results:{runBackendID}:{jobBackendID}".
+func backendIDs(token string) (runID, jobID string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", "", errors.New("actions: invalid runtime token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", fmt.Errorf("actions: invalid runtime token: %w", err)
+	}
+	var claims struct {
+		Scope string `json:"scp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", "", fmt.Errorf("actions: invalid runtime token: %w", err)
+	}
+	for _, scope := range strings.Fields(claims.Scope) {
+		ids, ok := strings.CutPrefix(scope, "Actions.Results:")
+		if !ok {
+			continue
+		}
+		if runID, jobID, ok = strings.Cut(ids, ":"); ok {
+			return runID, jobID, nil
+		}
+	}
+	return "", "", errors.New("actions: no backend IDs found in runtime token")
+}
+
+// resultsClient uploads artifacts to the results service
+type resultsClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// call calls the method of artifactService
+func (r *resultsClient) call(ctx context.Context, method string, in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(r.url, "/")+"/"+artifactService+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", method, resp.Status, bytes.TrimSpace(message))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// upload uploads the file as the artifact, and returns the ID of it:
+//  1. CreateArtifact returns a signed URL of the blob storage.
+//  2. The file is uploaded to the signed URL as a block blob.
+//  3. FinalizeArtifact commits the artifact with the size and the digest.
+func (r *resultsClient) upload(ctx context.Context, name, fileName string) (int64, error) {
+	runID, jobID, err := backendIDs(r.token)
+	if err != nil {
+		return 0, err
+	}
+	// the service responds in proto names or JSON names, accept both
+	var created struct {
+		Ok                   bool   `json:"ok"`
+		SignedUploadURL      string `json:"signed_upload_url"`
+		SignedUploadURLCamel string `json:"signedUploadUrl"`
+	}
+	err = r.call(ctx, "CreateArtifact", map[string]any{
+		"workflow_run_backend_id":     runID,
+		"workflow_job_run_backend_id": jobID,
+		"name":                        name,
+		"version":                     4,
+	}, &created)
+	if err != nil {
+		return 0, err
+	}
+	uploadURL := created.SignedUploadURL + created.SignedUploadURLCamel
+	if !created.Ok || uploadURL == "" {
+		return 0, fmt.Errorf("CreateArtifact: artifact %s is rejected", name)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("Content-Type", "application/zip")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("upload %s: %s", name, resp.Status)
+	}
+
+	digest := sha256.Sum256(data)
+	var finalized struct {
+		Ok              bool            `json:"ok"`
+		ArtifactID      json.RawMessage `json:"artifact_id"`
+		ArtifactIDCamel json.RawMessage `json:"artifactId"`
+	}
+	err = r.call(ctx, "FinalizeArtifact", map[string]any{
+		"workflow_run_backend_id":     runID,
+		"workflow_job_run_backend_id": jobID,
+		"name":                        name,
+		"size":                        strconv.Itoa(len(data)),
+		"hash":                        "sha256:" + hex.EncodeToString(digest[:]),
+	}, &finalized)
+	if err != nil {
+		return 0, err
+	}
+	// int64 is encoded as a string in protobuf JSON
+	id := strings.Trim(string(finalized.ArtifactID)+string(finalized.ArtifactIDCamel), `"`)
+	artifactID, err := strconv.ParseInt(id, 10, 64)
+	if !finalized.Ok || err != nil {
+		return 0, fmt.Errorf("FinalizeArtifact: artifact %s is rejected", name)
+	}
+	return artifactID, nil
+}
+
+// UploadArtifact uploads the file as an artifact by the results service,
+// which requires ACTIONS_RUNTIME_TOKEN and ACTIONS_RESULTS_URL.
+func (g *githubActionsEnv) UploadArtifact(name, fileName string) (string, error) {
+	url, token, err := env.ResultsService()
+	if err != nil {
+		return "", err
+	}
+	owner, repo, err := env.Repository()
+	if err != nil {
+		return "", err
+	}
+	runID, err := env.WorkflowRunID()
+	if err != nil {
+		return "", err
+	}
+	results := &resultsClient{url: url, token: token, client: newRetryClient()}
+	id, err := results.upload(context.TODO(), name, fileName)
+	if err != nil {
+		return "", wrapActionError(err)
+	}
+	return fmt.Sprintf("%s/%s/%s/actions/runs/%d/artifacts/%d", env.ServerURL(), owner, repo, runID, id), nil
+}
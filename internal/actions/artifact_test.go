@@ -0,0 +1,144 @@
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runtimeToken returns a JWT like ACTIONS_RUNTIME_TOKEN with the scope
+func runtimeToken(scope string) string {
+	claims, _ := json.Marshal(map[string]string{"scp": scope})
+	return "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+}
+
+// fakeResults serves the artifact service and the blob storage
+type fakeResults struct {
+	server    *httptest.Server
+	artifacts map[string]map[string]any
+	blobs     map[string][]byte
+}
+
+func newFakeResults(t *testing.T) *fakeResults {
+	f := &fakeResults{artifacts: map[string]map[string]any{}, blobs: map[string][]byte{}}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeResults) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if blob, ok := strings.CutPrefix(r.URL.Path, "/blob/"); ok {
+		if r.Method != http.MethodPut || r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.blobs[blob], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+runtimeToken("Actions.GenericRead:1 Actions.Results:run1:job1") {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var req map[string]any
+	json.NewDecoder(r.Body).Decode(&req)
+	if req["workflow_run_backend_id"] != "run1" || req["workflow_job_run_backend_id"] != "job1" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	name := req["name"].(string)
+	switch strings.TrimPrefix(r.URL.Path, "/"+artifactService) {
+	case "CreateArtifact":
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "signedUploadUrl": f.server.URL + "/blob/" + name})
+	case "FinalizeArtifact":
+		f.artifacts[name] = req
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "artifactId": "42"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestBackendIDs(t *testing.T) {
+	runID, jobID, err := backendIDs(runtimeToken("Actions.GenericRead:1 Actions.Results:run1:job1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runID != "run1" || jobID != "job1" {
+		t.Errorf("Expected run1 job1, got %s %s", runID, jobID)
+	}
+	for _, token := range []string{"", "a.b", runtimeToken("Actions.GenericRead:1")} {
+		if _, _, err := backendIDs(token); err == nil {
+			t.Errorf("Expected error for %q", token)
+		}
+	}
+}
+
+func TestResultsUpload(t *testing.T) {
+	fake := newFakeResults(t)
+	fileName := filepath.Join(t.TempDir(), "debug.zip")
+	content := []byte("zip content")
+	os.WriteFile(fileName, content, 0644)
+
+	httpClient, _ := newTestRetryClient(time.Now())
+	results := &resultsClient{
+		url:    fake.server.URL + "/",
+		token:  runtimeToken("Actions.GenericRead:1 Actions.Results:run1:job1"),
+		client: httpClient,
+	}
+	id, err := results.upload(context.Background(), "llpkg-debug-cjson", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Errorf("Expected 42, got %d", id)
+	}
+	if string(fake.blobs["llpkg-debug-cjson"]) != string(content) {
+		t.Errorf("unexpected blob: %q", fake.blobs["llpkg-debug-cjson"])
+	}
+	digest := sha256.Sum256(content)
+	artifact := fake.artifacts["llpkg-debug-cjson"]
+	if artifact["hash"] != "sha256:"+hex.EncodeToString(digest[:]) || artifact["size"] != "11" {
+		t.Errorf("unexpected artifact: %v", artifact)
+	}
+
+	// the token isn't accepted
+	results.token = runtimeToken("Actions.Results:run2:job2")
+	if _, err := results.upload(context.Background(), "llpkg-debug-cjson", fileName); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestGitHubActionsUploadArtifact(t *testing.T) {
+	fake := newFakeResults(t)
+	fileName := filepath.Join(t.TempDir(), "debug.zip")
+	os.WriteFile(fileName, []byte("zip content"), 0644)
+
+	t.Setenv("ACTIONS_RESULTS_URL", fake.server.URL)
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", runtimeToken("Actions.GenericRead:1 Actions.Results:run1:job1"))
+	t.Setenv("GITHUB_REPOSITORY", "goplus/llpkg")
+	t.Setenv("GITHUB_RUN_ID", "7")
+	t.Setenv("GITHUB_SERVER_URL", "")
+
+	link, err := NewGitHubActionsEnv().UploadArtifact("llpkg-debug-cjson", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://github.com/goplus/llpkg/actions/runs/7/artifacts/42" {
+		t.Errorf("unexpected link: %s", link)
+	}
+
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "")
+	if _, err := NewGitHubActionsEnv().UploadArtifact("llpkg-debug-cjson", fileName); err == nil {
+		t.Error("Expected error without runtime token")
+	}
+}
@@ -0,0 +1,134 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/config"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+)
+
+var (
+	ErrMissingTag      = errors.New("actions: mapped version has no tag")
+	ErrOrphanTag       = errors.New("actions: tag is not in the mapping")
+	ErrVersionMismatch = errors.New("actions: llpkg.cfg version disagrees with the mapping")
+)
+
+// mappedTags lists local git tags in the form of {CLibraryName}/{MappedVersion} in the repository,
+// other tags are skipped.
+func mappedTags(repoDir string) ([]string, error) {
+	ret, err := exec.Command("git", "-C", repoDir, "tag", "--list").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("actions: cannot list tags: %s", strings.TrimSpace(string(ret)))
+	}
+	var tags []string
+	for _, tag := range strings.Fields(string(ret)) {
+		if _, _, err := parseMappedVersion(tag); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// configFromTag parses {CLibraryName}/llpkg.cfg at the tag,
+// errors of git, e.g. llpkg.cfg is missing, are returned with its message.
+func configFromTag(repoDir, tag, clib string) (config.LLPkgConfig, error) {
+	var cfg config.LLPkgConfig
+	ret, err := exec.Command("git", "-C", repoDir, "show", tag+":"+clib+"/llpkg.cfg").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return cfg, fmt.Errorf("actions: cannot read llpkg.cfg at %s: %s", tag, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return cfg, fmt.Errorf("actions: cannot read llpkg.cfg at %s: %w", tag, err)
+	}
+	if err := json.Unmarshal(ret, &cfg); err != nil {
+		return cfg, fmt.Errorf("actions: invalid llpkg.cfg at %s: %w", tag, err)
+	}
+	return cfg, nil
+}
+
+// AuditMapping checks the consistency between the mapping table and the git tags in the repository,
+// and reports all the problems found:
+//  1. mapped versions without tags.
+//  2. tags without mapped versions.
+//  3. tags whose llpkg.cfg version disagrees with the mapped C version.
+//  4. violations of the mapping rules and the bumping rules, see [versions.Versions.Check] and
+//     [versions.Versions.CheckBumping].
+func AuditMapping(repoDir string, ver *versions.Versions) error {
+	tags, err := mappedTags(repoDir)
+	if err != nil {
+		return err
+	}
+	errs := []error{ver.Check(), ver.CheckBumping()}
+
+	clibs := make([]string, 0, len(ver.MetadataMap))
+	for clib := range ver.MetadataMap {
+		clibs = append(clibs, clib)
+	}
+	sort.Strings(clibs)
+
+	mapped := map[string]bool{}
+	for _, clib := range clibs {
+		for cversion, goVersions := range ver.MetadataMap[clib].Versions {
+			for _, goVersion := range goVersions {
+				tag := clib + "/" + goVersion
+				mapped[tag] = true
+
+				if !slices.Contains(tags, tag) {
+					errs = append(errs, fmt.Errorf("%w: %s", ErrMissingTag, tag))
+					continue
+				}
+				// a missing or invalid llpkg.cfg isn't a mismatch
+				cfg, err := configFromTag(repoDir, tag, clib)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if cfg.Upstream.Package.Version != cversion {
+					errs = append(errs, fmt.Errorf("%w: %s: llpkg.cfg has %s, but it's mapped to %s",
+						ErrVersionMismatch, tag, cfg.Upstream.Package.Version, cversion))
+				}
+			}
+		}
+	}
+
+	for _, tag := range tags {
+		if !mapped[tag] {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrOrphanTag, tag))
+		}
+	}
+	return sortedJoin(errs)
+}
+
+// sortedJoin joins the errors and sorts them by message for stable output,
+// joined errors are flattened.
+func sortedJoin(errs []error) error {
+	var flat []error
+	var flatten func(err error)
+	flatten = func(err error) {
+		if err == nil {
+			return
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				flatten(err)
+			}
+			return
+		}
+		flat = append(flat, err)
+	}
+	for _, err := range errs {
+		flatten(err)
+	}
+	slices.SortStableFunc(flat, func(a, b error) int {
+		return strings.Compare(a.Error(), b.Error())
+	})
+	return errors.Join(flat...)
+}
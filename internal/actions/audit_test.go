@@ -0,0 +1,103 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+)
+
+// commitAndTag commits llpkg.cfg of the clib with the version and tags it in the repository
+func commitAndTag(t *testing.T, repoDir, clib, cversion, tag string) {
+	t.Helper()
+	dir := filepath.Join(repoDir, clib)
+	os.MkdirAll(dir, 0755)
+	cfg := `{"upstream": {"package": {"name": "` + clib + `", "version": "` + cversion + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "llpkg.cfg"), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", tag},
+		{"tag", tag},
+	} {
+		if ret, err := exec.Command("git", append([]string{"-C", repoDir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, ret)
+		}
+	}
+}
+
+func TestAuditMapping(t *testing.T) {
+	repoDir := t.TempDir()
+	if ret, err := exec.Command("git", "init", "-q", repoDir).CombinedOutput(); err != nil {
+		t.Skipf("git is not available: %s", ret)
+	}
+	commitAndTag(t, repoDir, "cjson", "1.7.18", "cjson/v1.0.0")
+	commitAndTag(t, repoDir, "cjson", "1.7.19", "cjson/v1.1.0")
+	// orphan
+	commitAndTag(t, repoDir, "cjson", "1.7.20", "cjson/v1.2.0")
+	// other tags are ignored
+	commitAndTag(t, repoDir, "cjson", "1.7.20", "release")
+
+	mappingFile := filepath.Join(t.TempDir(), "llpkgstore.json")
+	os.WriteFile(mappingFile, []byte(`{
+		"cjson": {
+			"versions": {
+				"1.7.18": ["v1.0.0"],
+				"1.7.17": ["v1.1.0"],
+				"1.7.21": ["v1.3.0"]
+			}
+		}
+	}`), 0644)
+	ver, err := versions.Read(mappingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = AuditMapping(repoDir, ver)
+	expected := map[error]string{
+		ErrMissingTag:                "cjson/v1.3.0",
+		ErrOrphanTag:                 "cjson/v1.2.0",
+		ErrVersionMismatch:           "llpkg.cfg has 1.7.19, but it's mapped to 1.7.17",
+		versions.ErrUnorderedMapping: "1.7.18 => v1.0.0 is not greater than 1.7.17 => v1.1.0",
+	}
+	for sentinel, message := range expected {
+		if !errors.Is(err, sentinel) {
+			t.Errorf("Expected %v, got %v", sentinel, err)
+		} else if !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q, got %v", message, err)
+		}
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != len(expected) {
+		t.Errorf("Expected %d problems, got %d: %v", len(expected), n, err)
+	}
+
+	// the tag without llpkg.cfg is reported as is, instead of a mismatch
+	if ret, err := exec.Command("git", "-C", repoDir, "tag", "zlib/v1.0.0", "cjson/v1.0.0").CombinedOutput(); err != nil {
+		t.Fatalf("git tag: %s", ret)
+	}
+	os.WriteFile(mappingFile, []byte(`{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.19": ["v1.1.0"], "1.7.20": ["v1.2.0"]}}, "zlib": {"versions": {"1.3.1": ["v1.0.0"]}}}`), 0644)
+	ver, err = versions.Read(mappingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = AuditMapping(repoDir, ver)
+	if err == nil || errors.Is(err, ErrVersionMismatch) || !strings.Contains(err.Error(), "cannot read llpkg.cfg at zlib/v1.0.0") {
+		t.Errorf("Expected llpkg.cfg missing, got %v", err)
+	}
+	exec.Command("git", "-C", repoDir, "tag", "-d", "zlib/v1.0.0").Run()
+
+	// consistent
+	os.WriteFile(mappingFile, []byte(`{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.19": ["v1.1.0"], "1.7.20": ["v1.2.0"]}}}`), 0644)
+	ver, err = versions.Read(mappingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AuditMapping(repoDir, ver); err != nil {
+		t.Errorf("unexpected problems: %v", err)
+	}
+}
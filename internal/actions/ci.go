@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+)
+
+// CIEnv is the environment of the CI runner (e.g. GitHub Actions) required by actions.
+type CIEnv interface {
+	// PullRequest returns the pull request triggering current run,
+	// nil is returned if it's not triggered by a pull request.
+	PullRequest() (*PullRequest, error)
+	// Issue returns the issue triggering current run
+	Issue() (*Issue, error)
+	// LatestCommitSHA returns the commit triggering current run
+	LatestCommitSHA() (string, error)
+	// WorkflowRunID returns the ID of current run
+	WorkflowRunID() (int64, error)
+	// Changes returns the changed files in current pull request
+	Changes() ([]string, error)
+	// Setenv exports environment variables to the following steps
+	Setenv(env.Env) error
+	// UploadArtifact uploads the file as an artifact of current run, and returns the link to it
+	UploadArtifact(name, fileName string) (link string, err error)
+}
+
+// Issue is an issue of the source host
+type Issue struct {
+	Number int
+	Labels []string
+}
+
+// githubEvent is the part of GitHub event payload used by actions
+type githubEvent struct {
+	PullRequest *struct {
+		Number int    `json:"number"`
+		State  string `json:"state"`
+		Base   struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Issue *struct {
+		Number int `json:"number"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"issue"`
+}
+
+// githubActionsEnv is CIEnv of GitHub Actions,
+// it also works with Gitea Actions, which is compatible with GitHub Actions.
+type githubActionsEnv struct {
+	// event caches parsed GitHub event data from GITHUB_EVENT_PATH
+	event func() (*githubEvent, error)
+}
+
+// NewGitHubActionsEnv returns CIEnv of GitHub Actions
+func NewGitHubActionsEnv() CIEnv {
+	return &githubActionsEnv{event: sync.OnceValues(parseGitHubEvent)}
+}
+
+// parseGitHubEvent parses the GitHub event payload from GITHUB_EVENT_PATH
+func parseGitHubEvent() (*githubEvent, error) {
+	eventFile, err := env.EventFile()
+	if err != nil {
+		return nil, err
+	}
+	event := &githubEvent{}
+	if err := json.Unmarshal(eventFile, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (g *githubActionsEnv) PullRequest() (*PullRequest, error) {
+	event, err := g.event()
+	if err != nil {
+		return nil, err
+	}
+	if event.PullRequest == nil {
+		return nil, nil
+	}
+	return &PullRequest{
+		Number:  event.PullRequest.Number,
+		State:   event.PullRequest.State,
+		BaseRef: event.PullRequest.Base.Ref,
+		HeadSHA: event.PullRequest.Head.SHA,
+	}, nil
+}
+
+func (g *githubActionsEnv) Issue() (*Issue, error) {
+	event, err := g.event()
+	if err != nil {
+		return nil, err
+	}
+	if event.Issue == nil {
+		return nil, fmt.Errorf("env: cannot parse GITHUB_EVENT_PATH issue")
+	}
+	issue := &Issue{Number: event.Issue.Number}
+	for _, label := range event.Issue.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	return issue, nil
+}
+
+func (g *githubActionsEnv) LatestCommitSHA() (string, error) {
+	return env.LatestCommitSHA()
+}
+
+func (g *githubActionsEnv) WorkflowRunID() (int64, error) {
+	return env.WorkflowRunID()
+}
+
+func (g *githubActionsEnv) Changes() ([]string, error) {
+	return env.Changes()
+}
+
+func (g *githubActionsEnv) Setenv(e env.Env) error {
+	return env.Setenv(e)
+}
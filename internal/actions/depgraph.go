@@ -0,0 +1,102 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+var ErrDependencyCycle = errors.New("actions: dependency cycle")
+
+// PackageDependencies returns the dependencies of each package among the packages,
+// the packages are keyed by the package name, which equals to the directory name of the llpkg.
+// Dependencies out of the packages are ignored, since they're not changed together.
+func PackageDependencies(packages map[string]*upstream.Upstream) (map[string][]string, error) {
+	deps := make(map[string][]string, len(packages))
+	for name, uc := range packages {
+		dependencies, err := uc.Installer.Dependency(uc.Pkg)
+		if err != nil {
+			return nil, fmt.Errorf("actions: cannot resolve dependencies of %s: %w", name, err)
+		}
+		deps[name] = nil
+		for _, dep := range dependencies {
+			if _, ok := packages[dep.Name]; ok && dep.Name != name && !slices.Contains(deps[name], dep.Name) {
+				deps[name] = append(deps[name], dep.Name)
+			}
+		}
+		slices.Sort(deps[name])
+	}
+	return deps, nil
+}
+
+// SortByDependency orders the packages topologically by deps,
+// and groups them into levels: a package only depends on packages of previous levels,
+// so packages in the same level can be processed in parallel.
+// Packages are sorted by name in each level for stable output.
+// An error wrapping ErrDependencyCycle is returned if the dependencies have a cycle.
+func SortByDependency(packages []string, deps map[string][]string) ([][]string, error) {
+	pending := make(map[string][]string, len(packages))
+	for _, name := range packages {
+		pending[name] = nil
+		for _, dep := range deps[name] {
+			if slices.Contains(packages, dep) {
+				pending[name] = append(pending[name], dep)
+			}
+		}
+	}
+
+	var levels [][]string
+	done := make(map[string]bool, len(packages))
+	for len(pending) > 0 {
+		var level []string
+		for name, deps := range pending {
+			ready := true
+			for _, dep := range deps {
+				ready = ready && done[dep]
+			}
+			if ready {
+				level = append(level, name)
+			}
+		}
+		if len(level) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(findCycle(pending), " -> "))
+		}
+		slices.Sort(level)
+		for _, name := range level {
+			done[name] = true
+			delete(pending, name)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// findCycle returns a cycle in the remaining graph, every node of which has an unresolved dependency,
+// the first package is repeated at the end, e.g. [libxml2 zlib libxml2].
+func findCycle(pending map[string][]string) []string {
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// walk unresolved dependencies until a visited package is met
+	var path []string
+	for name := names[0]; ; {
+		if i := slices.Index(path, name); i >= 0 {
+			return append(path[i:], name)
+		}
+		path = append(path, name)
+		deps := slices.Clone(pending[name])
+		slices.Sort(deps)
+		for _, dep := range deps {
+			if _, ok := pending[dep]; ok {
+				name = dep
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package actions
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+// fakeInstaller resolves dependencies and search results from maps keyed by package name
+type fakeInstaller struct {
+	upstream.Installer
+	name   string
+	deps   map[string][]upstream.Package
+	search map[string][]string
+}
+
+func (f *fakeInstaller) Name() string {
+	return f.name
+}
+
+func (f *fakeInstaller) Search(pkg upstream.Package) ([]string, error) {
+	results, ok := f.search[pkg.Name]
+	if !ok {
+		return nil, errors.New("package not found")
+	}
+	return results, nil
+}
+
+func (f *fakeInstaller) Dependency(pkg upstream.Package) ([]upstream.Package, error) {
+	deps, ok := f.deps[pkg.Name]
+	if !ok {
+		return nil, errors.New("package not found")
+	}
+	return deps, nil
+}
+
+func TestPackageDependencies(t *testing.T) {
+	installer := &fakeInstaller{deps: map[string][]upstream.Package{
+		"libxml2": {{Name: "zlib", Version: "1.3.1"}, {Name: "libiconv", Version: "1.17"}},
+		"zlib":    {},
+	}}
+	packages := map[string]*upstream.Upstream{
+		"libxml2": {Installer: installer, Pkg: upstream.Package{Name: "libxml2", Version: "2.13.6"}},
+		"zlib":    {Installer: installer, Pkg: upstream.Package{Name: "zlib", Version: "1.3.1"}},
+	}
+	deps, err := PackageDependencies(packages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// libiconv isn't changed together
+	expected := map[string][]string{"libxml2": {"zlib"}, "zlib": nil}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected %v, got %v", expected, deps)
+	}
+
+	packages["cjson"] = &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: "cjson"}}
+	if _, err := PackageDependencies(packages); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestSortByDependency(t *testing.T) {
+	deps := map[string][]string{
+		"libxml2": {"zlib"},
+		"libxslt": {"libxml2", "zlib"},
+		"curl":    {"openssl", "zlib"},
+	}
+	levels, err := SortByDependency([]string{"libxslt", "zlib", "libxml2", "curl", "cjson"}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// openssl isn't changed together, so curl is ready at first
+	expected := [][]string{{"cjson", "zlib"}, {"curl", "libxml2"}, {"libxslt"}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected %v, got %v", expected, levels)
+	}
+
+	deps["zlib"] = []string{"libxslt"}
+	_, err = SortByDependency([]string{"libxslt", "zlib", "libxml2", "cjson"}, deps)
+	if !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("Expected dependency cycle, got %v", err)
+	}
+	if err.Error() != "actions: dependency cycle: libxml2 -> zlib -> libxslt -> libxml2" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Plan records the changes to be made in a dry run, it's safe for concurrent use.
+type Plan struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+// add records a step
+func (p *Plan) add(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, fmt.Sprintf(format, args...))
+}
+
+// Steps returns the recorded steps in order
+func (p *Plan) Steps() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.steps...)
+}
+
+// WriteTo writes the numbered steps to w
+func (p *Plan) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	steps := p.Steps()
+	n, err := fmt.Fprintf(w, "Dry run plan, %d step(s):\n", len(steps))
+	written += int64(n)
+	for i, step := range steps {
+		if err != nil {
+			break
+		}
+		n, err = fmt.Fprintf(w, "%d. %s\n", i+1, step)
+		written += int64(n)
+	}
+	return written, err
+}
+
+// dryRunHost is Host recording changes into the plan instead of making them,
+// queries are delegated to the underlying Host.
+type dryRunHost struct {
+	Host
+	plan *Plan
+}
+
+// NewDryRunHost returns Host recording the changes into plan instead of making them to host
+func NewDryRunHost(host Host, plan *Plan) Host {
+	return &dryRunHost{Host: host, plan: plan}
+}
+
+func (d *dryRunHost) CreateBranch(_ context.Context, branchName, sha string) error {
+	d.plan.add("create branch %s at %s", branchName, sha)
+	return nil
+}
+
+func (d *dryRunHost) RemoveBranch(_ context.Context, branchName string) error {
+	d.plan.add("remove branch %s", branchName)
+	return nil
+}
+
+func (d *dryRunHost) CreateTag(_ context.Context, tag, sha string) error {
+	d.plan.add("create tag %s at %s", tag, sha)
+	return nil
+}
+
+func (d *dryRunHost) RemoveLabel(_ context.Context, labelName string) error {
+	d.plan.add("remove label %s", labelName)
+	return nil
+}
+
+func (d *dryRunHost) CreatePullRequest(_ context.Context, opts PullRequestOptions) (PullRequest, error) {
+	d.plan.add("open pull request %q from %s to %s:\n%s", opts.Title, opts.Head, opts.Base, opts.Body)
+	return PullRequest{State: "open", BaseRef: opts.Base}, nil
+}
+
+func (d *dryRunHost) CreateComment(_ context.Context, number int, body string) error {
+	d.plan.add("comment on pull request #%d:\n%s", number, body)
+	return nil
+}
+
+func (d *dryRunHost) EditComment(_ context.Context, id int64, body string) error {
+	d.plan.add("edit comment %d:\n%s", id, body)
+	return nil
+}
+
+func (d *dryRunHost) CreateCheckRun(_ context.Context, run CheckRun) error {
+	d.plan.add("create check run %q for %s: %s, %d annotation(s)", run.Name, run.HeadSHA, run.Title, len(run.Annotations))
+	return nil
+}
+
+func (d *dryRunHost) CreateRelease(_ context.Context, opts ReleaseOptions) (Release, error) {
+	latest := "latest"
+	if opts.Legacy {
+		latest = "legacy"
+	}
+	d.plan.add("create %s release %s for tag %s", latest, opts.Name, opts.Tag)
+	return Release{Name: opts.Name}, nil
+}
+
+func (d *dryRunHost) EditReleaseNotes(_ context.Context, release Release, body string) error {
+	d.plan.add("edit notes of release %s:\n%s", release.Name, body)
+	return nil
+}
+
+// UploadReleaseAsset consumes reader, so that the digest of the asset is still computed
+func (d *dryRunHost) UploadReleaseAsset(_ context.Context, release Release, fileName string, size int64, reader io.Reader) error {
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return wrapActionError(err)
+	}
+	d.plan.add("upload %s (%d bytes) to release %s", fileName, size, release.Name)
+	return nil
+}
+
+// dryRunCIEnv is CIEnv recording artifact uploads into the plan instead of making them,
+// other calls are delegated to the underlying CIEnv.
+type dryRunCIEnv struct {
+	CIEnv
+	plan *Plan
+}
+
+// NewDryRunCIEnv returns CIEnv recording artifact uploads into plan instead of making them by ci
+func NewDryRunCIEnv(ci CIEnv, plan *Plan) CIEnv {
+	return &dryRunCIEnv{CIEnv: ci, plan: plan}
+}
+
+// UploadArtifact keeps the artifact as the local file, and returns the absolute path of it
+func (d *dryRunCIEnv) UploadArtifact(name, fileName string) (string, error) {
+	path, err := filepath.Abs(fileName)
+	if err != nil {
+		return "", wrapActionError(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", wrapActionError(err)
+	}
+	d.plan.add("upload artifact %s (%d bytes) from %s", name, info.Size(), path)
+	return path, nil
+}
+
+// NewDryRunClient creates a client rehearsing actions on the pull request in the local git repository,
+// changes to the source host and llpkgstore.json are recorded into the returned plan instead,
+// and environment variables are written to out.
+func NewDryRunClient(opts LocalOptions, out io.Writer) (*DefaultClient, *Plan) {
+	plan := &Plan{}
+	client := NewClient(NewDryRunHost(NewLocalHost(opts), plan), NewDryRunCIEnv(NewLocalCIEnv(opts, out), plan))
+	client.plan = plan
+	return client, plan
+}
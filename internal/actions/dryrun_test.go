@@ -0,0 +1,236 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+)
+
+// newDryRunRepo creates a repository with an initial commit on main,
+// and a pull request branch adding cjson with the commit message.
+func newDryRunRepo(t *testing.T, message string) string {
+	t.Helper()
+	repoDir := t.TempDir()
+	if ret, err := exec.Command("git", "init", "-q", "-b", "main", repoDir).CombinedOutput(); err != nil {
+		t.Skipf("git is not available: %s", ret)
+	}
+	git := func(args ...string) {
+		args = append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if ret, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, ret)
+		}
+	}
+	git("commit", "-q", "--allow-empty", "-m", "initial commit")
+	git("checkout", "-q", "-b", "pr")
+	os.MkdirAll(filepath.Join(repoDir, "cjson"), 0755)
+	cfg := `{"upstream": {"package": {"name": "cjson", "version": "1.7.18"}, "installer": {"name": "conan"}}}`
+	os.WriteFile(filepath.Join(repoDir, "cjson", "llpkg.cfg"), []byte(cfg), 0644)
+	git("add", "-A")
+	git("commit", "-q", "-m", message)
+	return repoDir
+}
+
+func TestLocalHost(t *testing.T) {
+	repoDir := newDryRunRepo(t, "feat: add cjson\n\nRelease-as: cjson/v1.0.0")
+	opts := LocalOptions{RepoDir: repoDir, Base: "main", Head: "pr", Number: 1}
+	var out bytes.Buffer
+	client, plan := NewDryRunClient(opts, &out)
+
+	version, err := client.checkMappedVersion("cjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "Release-as: cjson/v1.0.0" {
+		t.Errorf("Expected Release-as: cjson/v1.0.0, got %s", version)
+	}
+	changes, err := client.ci.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, []string{"cjson/llpkg.cfg"}) {
+		t.Errorf("Expected cjson/llpkg.cfg changed, got %v", changes)
+	}
+	if !client.hasBranch("pr") || client.hasBranch("release-branch.cjson/v1.0.0") {
+		t.Error("unexpected branches")
+	}
+	if _, isLegacy, err := client.isLegacyVersion(); err != nil || isLegacy {
+		t.Errorf("Expected not legacy, got %v %v", isLegacy, err)
+	}
+
+	if err := client.Setenv(map[string]string{"BIN_PATH": "cjson.zip"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "BIN_PATH=cjson.zip\n" {
+		t.Errorf("Expected env written to out, got %q", out.String())
+	}
+	if err := client.createBranch("release-branch.cjson/v1.0.0", "sha"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.removeLabel("branch:release-branch.cjson/v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"create branch release-branch.cjson/v1.0.0 at sha",
+		"remove label branch:release-branch.cjson/v1.0.0",
+	}
+	if !reflect.DeepEqual(plan.Steps(), expected) {
+		t.Errorf("Expected %v, got %v", expected, plan.Steps())
+	}
+	if !client.hasBranch("pr") || client.hasBranch("release-branch.cjson/v1.0.0") {
+		t.Error("Expected branches unchanged in dry run")
+	}
+}
+
+func TestDryRunPostprocessing(t *testing.T) {
+	repoDir := newDryRunRepo(t, "feat: add cjson\n\nRelease-as: cjson/v1.0.0")
+	artifact := filepath.Join(t.TempDir(), "cjson_linux_amd64.zip")
+	os.WriteFile(artifact, []byte("zip"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "llpkgstore.json"), []byte("{}"), 0644)
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(repoDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	opts := LocalOptions{RepoDir: repoDir, Base: "main", Head: "pr", Number: 1, Artifacts: []string{artifact}}
+	client, plan := NewDryRunClient(opts, &bytes.Buffer{})
+	if err := client.Postprocessing(); err != nil {
+		t.Fatal(err)
+	}
+
+	sha, _ := client.ci.LatestCommitSHA()
+	checksum := sha256.Sum256([]byte("zip"))
+	expected := []string{
+		"create tag cjson/v1.0.0 at " + sha,
+		"create latest release cjson/v1.0.0 for tag cjson/v1.0.0",
+		"upload cjson_linux_amd64.zip (3 bytes) to release cjson/v1.0.0",
+		"upload SHA256SUMS (88 bytes) to release cjson/v1.0.0",
+		"edit notes of release cjson/v1.0.0:",
+		"map cjson 1.7.18 => v1.0.0 in llpkgstore.json with checksums map[linux_amd64:" + hex.EncodeToString(checksum[:]) + "]",
+	}
+	steps := plan.Steps()
+	// the notes vary with the dependencies resolved by conan
+	notes := ""
+	if len(steps) == len(expected) {
+		steps[4], notes, _ = strings.Cut(steps[4], "\n")
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("Expected %v, got %v", expected, steps)
+	}
+	if !strings.Contains(notes, "| cjson_linux_amd64.zip | `"+hex.EncodeToString(checksum[:])+"` |") {
+		t.Errorf("Expected checksum in release notes, got %s", notes)
+	}
+	if data, _ := os.ReadFile("llpkgstore.json"); string(data) != "{}" {
+		t.Errorf("Expected llpkgstore.json unchanged in dry run, got %s", data)
+	}
+
+	var out bytes.Buffer
+	plan.WriteTo(&out)
+	if !strings.HasPrefix(out.String(), "Dry run plan, 6 step(s):\n1. create tag cjson/v1.0.0") {
+		t.Errorf("unexpected plan: %s", out.String())
+	}
+}
+
+func TestDryRunPostprocessingDuplicateMapping(t *testing.T) {
+	repoDir := newDryRunRepo(t, "feat: add cjson\n\nRelease-as: cjson/v1.0.0")
+	artifact := filepath.Join(t.TempDir(), "cjson_linux_amd64.zip")
+	os.WriteFile(artifact, []byte("zip"), 0644)
+	// v1.0.0 has been mapped to another C version
+	mapping := `{"cjson":{"versions":{"1.7.17":["v1.0.0"]}}}`
+	os.WriteFile(filepath.Join(repoDir, "llpkgstore.json"), []byte(mapping), 0644)
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(repoDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	opts := LocalOptions{RepoDir: repoDir, Base: "main", Head: "pr", Number: 1, Artifacts: []string{artifact}}
+	client, plan := NewDryRunClient(opts, &bytes.Buffer{})
+	if err := client.Postprocessing(); err == nil || !strings.Contains(err.Error(), versions.ErrDuplicateVersion.Error()) {
+		t.Errorf("Expected ErrDuplicateVersion, got %v", err)
+	}
+	// nothing is published for an invalid mapping
+	if steps := plan.Steps(); len(steps) != 0 {
+		t.Errorf("Expected no step, got %v", steps)
+	}
+}
+
+func TestDryRunPostprocessingPackages(t *testing.T) {
+	repoDir := newDryRunRepo(t, "feat: add cjson and zlib\n\nRelease-as: cjson/v1.0.0\nRelease-as: zlib/v1.1.0")
+	os.MkdirAll(filepath.Join(repoDir, "zlib"), 0755)
+	cfg := `{"upstream": {"package": {"name": "zlib", "version": "1.3.1"}, "installer": {"name": "conan"}}}`
+	os.WriteFile(filepath.Join(repoDir, "zlib", "llpkg.cfg"), []byte(cfg), 0644)
+	os.WriteFile(filepath.Join(repoDir, "llpkgstore.json"), []byte("{}"), 0644)
+	var artifacts []string
+	for _, name := range []string{"cjson_linux_amd64.zip", "zlib_linux_amd64.zip", "zlib_darwin_arm64.zip"} {
+		artifact := filepath.Join(t.TempDir(), name)
+		os.WriteFile(artifact, []byte(name), 0644)
+		artifacts = append(artifacts, artifact)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(repoDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	opts := LocalOptions{RepoDir: repoDir, Base: "main", Head: "pr", Number: 1, Artifacts: artifacts}
+	client, plan := NewDryRunClient(opts, &bytes.Buffer{})
+	if err := client.Postprocessing(); err != nil {
+		t.Fatal(err)
+	}
+
+	// each package is tagged and released with its own artifacts
+	uploads := map[string][]string{}
+	tags := 0
+	for _, step := range plan.Steps() {
+		if strings.HasPrefix(step, "create tag ") {
+			tags++
+		}
+		if upload, ok := strings.CutPrefix(step, "upload "); ok {
+			fileName, release, _ := strings.Cut(upload, " ")
+			release = release[strings.LastIndex(release, " ")+1:]
+			uploads[release] = append(uploads[release], fileName)
+		}
+	}
+	if tags != 2 {
+		t.Errorf("Expected 2 tags, got %v", plan.Steps())
+	}
+	// with the manifest of checksums
+	if len(uploads["cjson/v1.0.0"]) != 2 || len(uploads["zlib/v1.1.0"]) != 3 || !slices.Contains(uploads["zlib/v1.1.0"], "SHA256SUMS") {
+		t.Errorf("unexpected uploads: %v", uploads)
+	}
+}
+
+func TestDryRunUploadArtifact(t *testing.T) {
+	plan := &Plan{}
+	ci := NewDryRunCIEnv(&fakeCIEnv{}, plan)
+	fileName := filepath.Join(t.TempDir(), "llpkg-debug-cjson.zip")
+	os.WriteFile(fileName, []byte("zip"), 0644)
+
+	link, err := ci.UploadArtifact("llpkg-debug-cjson", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != fileName {
+		t.Errorf("Expected the local file %s, got %s", fileName, link)
+	}
+	expected := []string{"upload artifact llpkg-debug-cjson (3 bytes) from " + fileName}
+	if steps := plan.Steps(); !reflect.DeepEqual(steps, expected) {
+		t.Errorf("Expected %v, got %v", expected, steps)
+	}
+	if _, err := ci.UploadArtifact("missing", filepath.Join(t.TempDir(), "missing.zip")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
@@ -83,6 +83,51 @@ func Repository() (owner, repo string, err error) {
 	return
 }
 
+// SourceHost returns the source host of the repository from LLPKGSTORE_SOURCE_HOST,
+// "github" is returned if it's not set.
+func SourceHost() string {
+	host := strings.TrimSpace(os.Getenv("LLPKGSTORE_SOURCE_HOST"))
+	if host == "" {
+		return "github"
+	}
+	return strings.ToLower(host)
+}
+
+// APIURL returns the API URL of the source host from GITHUB_API_URL,
+// which is also set by Gitea Actions, e.g. https://gitea.example.com/api/v1
+func APIURL() (url string, err error) {
+	url = os.Getenv("GITHUB_API_URL")
+	if url == "" {
+		err = newEnvError("GITHUB_API_URL")
+	}
+	return
+}
+
+// ServerURL returns the URL of the source host from GITHUB_SERVER_URL, e.g. https://github.com
+func ServerURL() string {
+	url := os.Getenv("GITHUB_SERVER_URL")
+	if url == "" {
+		return "https://github.com"
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+// ResultsService returns the URL and the token of the results service storing artifacts of the workflow run,
+// which are only exposed to actions, a step can receive them by exporting
+// ACTIONS_RUNTIME_TOKEN and ACTIONS_RESULTS_URL from an action, e.g. actions/github-script.
+func ResultsService() (url, token string, err error) {
+	url = os.Getenv("ACTIONS_RESULTS_URL")
+	if url == "" {
+		err = newEnvError("ACTIONS_RESULTS_URL")
+		return
+	}
+	token = os.Getenv("ACTIONS_RUNTIME_TOKEN")
+	if token == "" {
+		err = newEnvError("ACTIONS_RUNTIME_TOKEN")
+	}
+	return
+}
+
 // Token returns Github Token for current runner
 func Token() (token string, err error) {
 	token = os.Getenv("GITHUB_TOKEN")
@@ -92,6 +137,15 @@ func Token() (token string, err error) {
 	return
 }
 
+// SigningKey returns the base64 encoded ed25519 private key for signing llpkgstore.json
+func SigningKey() (key string, err error) {
+	key = os.Getenv("LLPKGSTORE_SIGNING_KEY")
+	if key == "" {
+		err = newEnvError("LLPKGSTORE_SIGNING_KEY")
+	}
+	return
+}
+
 // LatestCommitSHA returns the current commit SHA from GITHUB_SHA environment variable
 func LatestCommitSHA() (sha string, err error) {
 	sha = os.Getenv("GITHUB_SHA")
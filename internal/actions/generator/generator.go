@@ -1,6 +1,78 @@
 package generator
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
 type Generator interface {
 	Generate(toDir string) error
 	Check(baseDir string) error
 }
+
+// Recorder is implemented by generators recording the stderr of the generation tool for debugging
+type Recorder interface {
+	// Stderr returns the stderr of the last generation
+	Stderr() []byte
+}
+
+// Reasons of FileError
+const (
+	ReasonUnexpected = "unexpected file"
+	ReasonNotEqual   = "file not equal"
+	ReasonMissing    = "missing file"
+)
+
+// hunkHeader matches the start line of the old file in a unified diff hunk header, e.g. @@ -12,7 +12,8 @@
+var hunkHeader = regexp.MustCompile(`(?m)^@@ -(\d+)`)
+
+// FileError is a difference between a committed file and the generated one found by Check
+type FileError struct {
+	// Name is the file name relative to the package directory
+	Name string
+	// Reason is one of ReasonUnexpected, ReasonNotEqual and ReasonMissing
+	Reason string
+	// Diff is the diff from the committed file to the generated one if the reason is ReasonNotEqual
+	Diff string
+}
+
+func (f *FileError) Error() string {
+	if f.Diff != "" {
+		return fmt.Sprintf("%s: %s %s", f.Reason, f.Name, f.Diff)
+	}
+	return fmt.Sprintf("%s: %s", f.Reason, f.Name)
+}
+
+// Line returns the first line of the committed file changed in Diff, or 1 if it's unknown.
+func (f *FileError) Line() int {
+	if match := hunkHeader.FindStringSubmatch(f.Diff); match != nil {
+		if line, err := strconv.Atoi(match[1]); err == nil && line > 0 {
+			return line
+		}
+	}
+	return 1
+}
+
+// FileErrors returns all the FileError in the tree of err, in order.
+func FileErrors(err error) []*FileError {
+	var ret []*FileError
+	var walk func(err error)
+	walk = func(err error) {
+		var fileErr *FileError
+		switch e := err.(type) {
+		case nil:
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				walk(err)
+			}
+		default:
+			if errors.As(err, &fileErr) {
+				ret = append(ret, fileErr)
+			}
+		}
+	}
+	walk(err)
+	return ret
+}
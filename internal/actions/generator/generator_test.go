@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFileErrors(t *testing.T) {
+	notEqual := &FileError{
+		Name:   "cJSON.go",
+		Reason: ReasonNotEqual,
+		Diff:   "diff --git a/cJSON.go b/cJSON.go\n--- a/cJSON.go\n+++ b/cJSON.go\n@@ -12,7 +12,8 @@ import\n-a\n+b\n@@ -40 +41 @@\n",
+	}
+	missing := &FileError{Name: "go.mod", Reason: ReasonMissing}
+	err := errors.Join(errors.New("llcppg: check fail: "), notEqual, missing)
+
+	fileErrs := FileErrors(err)
+	if len(fileErrs) != 2 || fileErrs[0] != notEqual || fileErrs[1] != missing {
+		t.Errorf("unexpected file errors: %v", fileErrs)
+	}
+	if notEqual.Line() != 12 {
+		t.Errorf("Expected line 12, got %d", notEqual.Line())
+	}
+	if missing.Line() != 1 {
+		t.Errorf("Expected line 1, got %d", missing.Line())
+	}
+	if missing.Error() != "missing file: go.mod" {
+		t.Errorf("unexpected message: %s", missing.Error())
+	}
+	if FileErrors(errors.New("llcppg: cannot generate: ")) != nil {
+		t.Error("Expected no file error")
+	}
+}
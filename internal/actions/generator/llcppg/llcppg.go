@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator"
@@ -68,16 +71,26 @@ type llcppgGenerator struct {
 	dir         string // llcppg.cfg abs path
 	pcDir       string
 	packageName string
+	// stderr records the stderr of the last generation
+	stderr bytes.Buffer
+	// proxy serves unreleased dependencies if it's not nil
+	proxy *generator.LocalProxy
 }
 
 func New(dir, packageName, pcDir string) generator.Generator {
 	return &llcppgGenerator{dir: dir, packageName: packageName, pcDir: pcDir}
 }
 
+// ModulePath returns the module path of the llpkg like
+// cjson => github.com/goplus/llpkg/cjson
+func ModulePath(packageName string) string {
+	return goplusRepo + packageName
+}
+
 // normalizeModulePath returns a normalized module path like
 // cjson => github.com/goplus/llpkg/cjson
 func (l *llcppgGenerator) normalizeModulePath() string {
-	return goplusRepo + l.packageName
+	return ModulePath(l.packageName)
 }
 
 func (l *llcppgGenerator) findSymbJSON() string {
@@ -114,6 +127,16 @@ func (l *llcppgGenerator) copyConfigFileTo(path string) error {
 	return nil
 }
 
+// UseProxy resolves dependencies from the proxy first during generation
+func (l *llcppgGenerator) UseProxy(proxy *generator.LocalProxy) {
+	l.proxy = proxy
+}
+
+// Stderr returns the stderr of the last generation
+func (l *llcppgGenerator) Stderr() []byte {
+	return l.stderr.Bytes()
+}
+
 func (l *llcppgGenerator) Generate(toDir string) error {
 	path, err := filepath.Abs(toDir)
 	if err != nil {
@@ -125,8 +148,12 @@ func (l *llcppgGenerator) Generate(toDir string) error {
 	cmd := exec.Command("llcppg", "-mod", l.normalizeModulePath(), llcppgConfigFile)
 	cmd.Dir = path
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	l.stderr.Reset()
+	cmd.Stderr = io.MultiWriter(os.Stderr, &l.stderr)
 	lockGoVersion(cmd, l.pcDir)
+	if l.proxy != nil {
+		cmd.Env = append(cmd.Env, l.proxy.Env()...)
+	}
 
 	// llcppg may exit with an error, which may be caused by Stderr.
 	// To avoid that case, we have to check its exit code.
@@ -166,28 +193,36 @@ func (l *llcppgGenerator) Check(dir string) error {
 		return errors.Join(ErrLLCppgCheck, err)
 	}
 
-	// 2. check hash
-	for name, hash := range userGenerated {
+	// 2. check hash, and report all the differences sorted by name
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(userGenerated)) {
+		hash := userGenerated[name]
 		generatedHash, ok := generated[name]
 		if !ok {
 			// if this file is hashable, it's unexpected
 			// if not, we can skip it safely.
 			if canHash(name) {
-				return errors.Join(ErrLLCppgCheck, fmt.Errorf("unexpected file: %s", name))
+				errs = append(errs, &generator.FileError{Name: name, Reason: generator.ReasonUnexpected})
 			}
 			// skip file
 			continue
 		}
 		if !bytes.Equal(hash, generatedHash) {
-			return errors.Join(ErrLLCppgCheck, fmt.Errorf("file not equal: %s %s", name,
-				diffTwoFiles(filepath.Join(l.dir, name), filepath.Join(baseDir, name))))
+			errs = append(errs, &generator.FileError{
+				Name:   name,
+				Reason: generator.ReasonNotEqual,
+				Diff:   diffTwoFiles(filepath.Join(l.dir, name), filepath.Join(baseDir, name)),
+			})
 		}
 	}
 	// 3. check missing file
-	for name := range generated {
+	for _, name := range slices.Sorted(maps.Keys(generated)) {
 		if _, ok := userGenerated[name]; !ok {
-			return errors.Join(ErrLLCppgCheck, fmt.Errorf("missing file: %s", name))
+			errs = append(errs, &generator.FileError{Name: name, Reason: generator.ReasonMissing})
 		}
 	}
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrLLCppgCheck}, errs...)...)
+	}
 	return nil
 }
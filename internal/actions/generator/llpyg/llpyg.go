@@ -1,11 +1,15 @@
 package llpyg
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator"
@@ -76,6 +80,8 @@ type llpygGenerator struct {
 	dir         string // llpyg.cfg abs path
 	pyDir       string
 	packageName string
+	// stderr records the stderr of the last generation
+	stderr bytes.Buffer
 }
 
 func New(dir, packageName, pyDir string) generator.Generator {
@@ -122,6 +128,11 @@ func (l *llpygGenerator) copyConfigFileTo(path string) error {
 	return nil
 }
 
+// Stderr returns the stderr of the last generation
+func (l *llpygGenerator) Stderr() []byte {
+	return l.stderr.Bytes()
+}
+
 func (l *llpygGenerator) Generate(toDir string) error {
 	path, err := filepath.Abs(toDir)
 	if err != nil {
@@ -143,7 +154,8 @@ func (l *llpygGenerator) Generate(toDir string) error {
 	cmd := exec.Command("llpyg", l.normalizeModulePath())
 	cmd.Dir = path
 	cmd.Stdout = file
-	cmd.Stderr = os.Stderr
+	l.stderr.Reset()
+	cmd.Stderr = io.MultiWriter(os.Stderr, &l.stderr)
 
 	// Set environment variables for llpyg
 	cmd.Env = append(os.Environ(),
@@ -186,29 +198,37 @@ func (l *llpygGenerator) Check(dir string) error {
 		return errors.Join(ErrLLPygCheck, err)
 	}
 
-	// 2. check hash
-	for name, hash := range userGenerated {
+	// 2. check hash, and report all the differences sorted by name
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(userGenerated)) {
+		hash := userGenerated[name]
 		generatedHash, ok := generated[name]
 		if !ok {
 			// if this file is hashable, it's unexpected
 			// if not, we can skip it safely.
 			if canHash(name) {
-				return errors.Join(ErrLLPygCheck, fmt.Errorf("unexpected file: %s", name))
+				errs = append(errs, &generator.FileError{Name: name, Reason: generator.ReasonUnexpected})
 			}
 			// skip file
 			continue
 		}
 		if !strings.EqualFold(string(hash), string(generatedHash)) {
-			return errors.Join(ErrLLPygCheck, fmt.Errorf("file not equal: %s %s", name,
-				diffTwoFiles(filepath.Join(l.dir, name), filepath.Join(baseDir, name))))
+			errs = append(errs, &generator.FileError{
+				Name:   name,
+				Reason: generator.ReasonNotEqual,
+				Diff:   diffTwoFiles(filepath.Join(l.dir, name), filepath.Join(baseDir, name)),
+			})
 		}
 	}
 	// 3. check missing file
-	for name := range generated {
+	for _, name := range slices.Sorted(maps.Keys(generated)) {
 		if _, ok := userGenerated[name]; !ok {
-			return errors.Join(ErrLLPygCheck, fmt.Errorf("missing file: %s", name))
+			errs = append(errs, &generator.FileError{Name: name, Reason: generator.ReasonMissing})
 		}
 	}
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrLLPygCheck}, errs...)...)
+	}
 	return nil
 }
 
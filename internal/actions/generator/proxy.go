@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// defaultGoProxy is the default value of GOPROXY of the go command
+const defaultGoProxy = "https://proxy.golang.org,direct"
+
+// ProxyUser is implemented by generators resolving llpkgs with the go command,
+// which can resolve unreleased llpkgs from the local proxy,
+// e.g. dependencies updated by the same pull request.
+type ProxyUser interface {
+	UseProxy(proxy *LocalProxy)
+}
+
+// LocalProxy is a module proxy in the file system serving local modules at specific versions,
+// other modules fall back to GOPROXY. See https://go.dev/ref/mod#goproxy-protocol
+type LocalProxy struct {
+	dir     string
+	modules []string
+	// versions maps module paths to the versions served
+	versions map[string]string
+}
+
+// NewLocalProxy returns an empty proxy in the directory
+func NewLocalProxy(dir string) *LocalProxy {
+	return &LocalProxy{dir: dir, versions: map[string]string{}}
+}
+
+// Modules returns the versions of modules served keyed by module path
+func (p *LocalProxy) Modules() map[string]string {
+	return p.versions
+}
+
+// Add serves the module in moduleDir as modulePath@version
+func (p *LocalProxy) Add(modulePath, version, moduleDir string) error {
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return err
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return err
+	}
+	versionDir := filepath.Join(p.dir, escapedPath, "@v")
+	if err := os.MkdirAll(versionDir, 0777); err != nil {
+		return err
+	}
+	base := filepath.Join(versionDir, escapedVersion)
+
+	mod, err := os.ReadFile(filepath.Join(moduleDir, "go.mod"))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".mod", mod, 0666); err != nil {
+		return err
+	}
+	info, _ := json.Marshal(map[string]any{"Version": version, "Time": time.Time{}})
+	if err := os.WriteFile(base+".info", info, 0666); err != nil {
+		return err
+	}
+	zipFile, err := os.Create(base + ".zip")
+	if err != nil {
+		return err
+	}
+	err = modzip.CreateFromDir(zipFile, module.Version{Path: modulePath, Version: version}, moduleDir)
+	if closeErr := zipFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	list, err := os.OpenFile(filepath.Join(versionDir, "list"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := list.WriteString(version + "\n"); err != nil {
+		list.Close()
+		return err
+	}
+	p.modules = append(p.modules, modulePath)
+	p.versions[modulePath] = version
+	return list.Close()
+}
+
+// Env returns the environment variables for the go command to resolve modules from the proxy first,
+// the checksum database is skipped for local modules since they're not published yet.
+func (p *LocalProxy) Env() []string {
+	goProxy := os.Getenv("GOPROXY")
+	if goProxy == "" {
+		goProxy = defaultGoProxy
+	}
+	dir := filepath.ToSlash(p.dir)
+	// file URLs of Windows paths start with an extra slash, e.g. file:///C:/proxy
+	if !strings.HasPrefix(dir, "/") {
+		dir = "/" + dir
+	}
+	env := []string{"GOPROXY=file://" + dir + "," + goProxy}
+	if len(p.modules) > 0 {
+		noSumDB := p.modules
+		if v := os.Getenv("GONOSUMDB"); v != "" {
+			noSumDB = append([]string{v}, noSumDB...)
+		}
+		env = append(env, "GONOSUMDB="+strings.Join(noSumDB, ","))
+	}
+	return env
+}
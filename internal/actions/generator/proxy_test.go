@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLocalProxy(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not available")
+	}
+	moduleDir := t.TempDir()
+	os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module github.com/goplus/llpkg/zlib\n\ngo 1.20\n"), 0644)
+	os.WriteFile(filepath.Join(moduleDir, "zlib.go"), []byte("package zlib\n"), 0644)
+
+	proxy := NewLocalProxy(t.TempDir())
+	if err := proxy.Add("github.com/goplus/llpkg/zlib", "v1.1.0", moduleDir); err != nil {
+		t.Fatal(err)
+	}
+	// no fallback to the network in the test
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GONOSUMDB", "example.com")
+	env := proxy.Env()
+	if !slices.Contains(env, "GONOSUMDB=example.com,github.com/goplus/llpkg/zlib") {
+		t.Errorf("unexpected env: %v", env)
+	}
+
+	cmd := exec.Command("go", "mod", "download", "-json", "github.com/goplus/llpkg/zlib@v1.1.0")
+	cmd.Dir = t.TempDir()
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-modcacherw", "GOWORK=off", "GOMODCACHE="+t.TempDir())
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	var download struct {
+		Version string
+		Dir     string
+	}
+	if err := json.Unmarshal(out, &download); err != nil {
+		t.Fatal(err)
+	}
+	if download.Version != "v1.1.0" {
+		t.Errorf("Expected v1.1.0, got %s", download.Version)
+	}
+	if data, err := os.ReadFile(filepath.Join(download.Dir, "zlib.go")); err != nil || !strings.HasPrefix(string(data), "package zlib") {
+		t.Errorf("unexpected module content: %s %v", data, err)
+	}
+}
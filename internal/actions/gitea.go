@@ -0,0 +1,449 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// giteaPageSize is the number of items per page when listing
+const giteaPageSize = 50
+
+// errGiteaNotFound is returned when Gitea responds 404
+var errGiteaNotFound = errors.New("gitea: not found")
+
+// giteaHost is Host of Gitea, it uses Gitea API v1:
+// https://gitea.com/api/swagger
+type giteaHost struct {
+	// apiURL: API endpoint, e.g. https://gitea.example.com/api/v1
+	// repo: Target repository name
+	// owner: Repository owner organization/user
+	apiURL string
+	repo   string
+	owner  string
+	token  string
+	client *http.Client
+}
+
+// NewGiteaHost returns Host of the Gitea repository owner/repo authenticated with the token,
+// apiURL is the API endpoint, e.g. https://gitea.example.com/api/v1.
+// Requests are retried on server errors and rate limits.
+func NewGiteaHost(apiURL, owner, repo, token string) Host {
+	return &giteaHost{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		owner:  owner,
+		repo:   repo,
+		token:  token,
+		client: newRetryClient(),
+	}
+}
+
+// repoPath returns the API path of the repository with the elements escaped and appended,
+// slashes in elements are kept, since Gitea accepts branch names with slashes as they are.
+func (g *giteaHost) repoPath(elem ...string) string {
+	path := "/repos/" + url.PathEscape(g.owner) + "/" + url.PathEscape(g.repo)
+	for _, e := range elem {
+		segments := strings.Split(e, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		path += "/" + strings.Join(segments, "/")
+	}
+	return path
+}
+
+// newRequest creates an authorized request to the API path,
+// body is encoded as JSON unless it's an io.Reader.
+func (g *giteaHost) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	u := g.apiURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+		contentType = ""
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" && reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "token "+g.token)
+	return req, nil
+}
+
+// do sends the request and decodes the JSON response into out if it's not nil
+func (g *giteaHost) do(req *http.Request, out any) error {
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s %s", errGiteaNotFound, req.Method, req.URL.Path)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("gitea: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(message))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// call sends a request to the API path
+func (g *giteaHost) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	req, err := g.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return g.do(req, out)
+}
+
+// giteaList retrieves all the pages of the API path
+func giteaList[T any](ctx context.Context, g *giteaHost, path string, query url.Values) ([]T, error) {
+	return giteaListOf(ctx, g, path, query, func(items *[]T) []T { return *items })
+}
+
+// giteaListOf retrieves all the pages of the API path, the page is decoded into R,
+// and items are extracted from it, for endpoints wrapping the list in an object.
+func giteaListOf[T, R any](ctx context.Context, g *giteaHost, path string, query url.Values, extract func(*R) []T) ([]T, error) {
+	var all []T
+	err := giteaEach(ctx, g, path, query, extract, func(item T) bool {
+		all = append(all, item)
+		return true
+	})
+	return all, err
+}
+
+// giteaEach retrieves the pages of the API path and calls yield for each item,
+// the next page isn't retrieved once yield returns false.
+func giteaEach[T, R any](ctx context.Context, g *giteaHost, path string, query url.Values, extract func(*R) []T, yield func(T) bool) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(giteaPageSize))
+
+		var resp R
+		if err := g.call(ctx, http.MethodGet, path, query, nil, &resp); err != nil {
+			return err
+		}
+		items := extract(&resp)
+		for _, item := range items {
+			if !yield(item) {
+				return nil
+			}
+		}
+		if len(items) < giteaPageSize {
+			return nil
+		}
+	}
+}
+
+type giteaCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+	} `json:"commit"`
+}
+
+func (c giteaCommit) toCommit() Commit {
+	return Commit{SHA: c.SHA, Message: c.Commit.Message}
+}
+
+func fromGiteaCommits(commits []giteaCommit) []Commit {
+	ret := make([]Commit, 0, len(commits))
+	for _, commit := range commits {
+		ret = append(ret, commit.toCommit())
+	}
+	return ret
+}
+
+func (g *giteaHost) HasBranch(ctx context.Context, branchName string) bool {
+	return g.call(ctx, http.MethodGet, g.repoPath("branches", branchName), nil, nil, nil) == nil
+}
+
+func (g *giteaHost) CreateBranch(ctx context.Context, branchName, sha string) error {
+	err := g.call(ctx, http.MethodPost, g.repoPath("branches"), nil, map[string]string{
+		"new_branch_name": branchName,
+		"old_ref_name":    sha,
+	}, nil)
+	return wrapActionError(err)
+}
+
+func (g *giteaHost) RemoveBranch(ctx context.Context, branchName string) error {
+	err := g.call(ctx, http.MethodDelete, g.repoPath("branches", branchName), nil, nil, nil)
+	return wrapActionError(err)
+}
+
+func (g *giteaHost) CreateTag(ctx context.Context, tag, sha string) error {
+	err := g.call(ctx, http.MethodPost, g.repoPath("tags"), nil, map[string]string{
+		"tag_name": tag,
+		"target":   sha,
+	}, nil)
+	return wrapActionError(err)
+}
+
+type giteaPullRequest struct {
+	Number int    `json:"number"`
+	State  string `json:"state"`
+	Base   struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+func (p giteaPullRequest) toPullRequest() PullRequest {
+	return PullRequest{Number: p.Number, State: p.State, BaseRef: p.Base.Ref, HeadSHA: p.Head.SHA}
+}
+
+// PullRequestsWithCommit returns the pull request merging the commit,
+// Gitea only supports finding the one merged the commit.
+func (g *giteaHost) PullRequestsWithCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	var pull giteaPullRequest
+	err := g.call(ctx, http.MethodGet, g.repoPath("commits", sha, "pull"), nil, nil, &pull)
+	if errors.Is(err, errGiteaNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	return []PullRequest{pull.toPullRequest()}, nil
+}
+
+func (g *giteaHost) PullRequestCommits(ctx context.Context, number int) ([]Commit, error) {
+	commits, err := giteaList[giteaCommit](ctx, g, g.repoPath("pulls", strconv.Itoa(number), "commits"), nil)
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	return fromGiteaCommits(commits), nil
+}
+
+func (g *giteaHost) FindCommit(ctx context.Context, match func(Commit) bool) (commit Commit, found bool, err error) {
+	// skip expensive fields
+	query := url.Values{"stat": {"false"}, "verification": {"false"}, "files": {"false"}}
+	err = giteaEach(ctx, g, g.repoPath("commits"), query, func(commits *[]giteaCommit) []giteaCommit {
+		return *commits
+	}, func(c giteaCommit) bool {
+		commit = c.toCommit()
+		found = match(commit)
+		return !found
+	})
+	if err != nil || !found {
+		return Commit{}, false, wrapActionError(err)
+	}
+	return commit, true, nil
+}
+
+func (g *giteaHost) Commit(ctx context.Context, sha string) (Commit, error) {
+	var commit giteaCommit
+	if err := g.call(ctx, http.MethodGet, g.repoPath("git", "commits", sha), nil, nil, &commit); err != nil {
+		return Commit{}, wrapActionError(err)
+	}
+	return commit.toCommit(), nil
+}
+
+// RemoveLabel deletes the label by name, Gitea deletes labels by ID, so it's looked up first.
+func (g *giteaHost) RemoveLabel(ctx context.Context, labelName string) error {
+	labels, err := giteaList[struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}](ctx, g, g.repoPath("labels"), nil)
+	if err != nil {
+		return wrapActionError(err)
+	}
+	for _, label := range labels {
+		if label.Name == labelName {
+			err := g.call(ctx, http.MethodDelete, g.repoPath("labels", strconv.FormatInt(label.ID, 10)), nil, nil, nil)
+			return wrapActionError(err)
+		}
+	}
+	return fmt.Errorf("actions: label %s not found", labelName)
+}
+
+// Comments lists comments of the pull request
+func (g *giteaHost) Comments(ctx context.Context, number int) ([]Comment, error) {
+	path := g.repoPath("issues", strconv.Itoa(number), "comments")
+	comments, err := giteaList[struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+	}](ctx, g, path, nil)
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	ret := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		ret = append(ret, Comment{ID: comment.ID, Body: comment.Body})
+	}
+	return ret, nil
+}
+
+func (g *giteaHost) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (PullRequest, error) {
+	var pull giteaPullRequest
+	err := g.call(ctx, http.MethodPost, g.repoPath("pulls"), nil, map[string]string{
+		"title": opts.Title,
+		"body":  opts.Body,
+		"head":  opts.Head,
+		"base":  opts.Base,
+	}, &pull)
+	if err != nil {
+		return PullRequest{}, wrapActionError(err)
+	}
+	return pull.toPullRequest(), nil
+}
+
+func (g *giteaHost) CreateComment(ctx context.Context, number int, body string) error {
+	path := g.repoPath("issues", strconv.Itoa(number), "comments")
+	err := g.call(ctx, http.MethodPost, path, nil, map[string]string{"body": body}, nil)
+	return wrapActionError(err)
+}
+
+func (g *giteaHost) EditComment(ctx context.Context, id int64, body string) error {
+	path := g.repoPath("issues", "comments", strconv.FormatInt(id, 10))
+	err := g.call(ctx, http.MethodPatch, path, nil, map[string]string{"body": body}, nil)
+	return wrapActionError(err)
+}
+
+// CreateCheckRun creates a commit status instead, since Gitea has no check runs,
+// annotations are dropped and only the title is shown.
+func (g *giteaHost) CreateCheckRun(ctx context.Context, run CheckRun) error {
+	state := "success"
+	if !run.Success {
+		state = "failure"
+	}
+	err := g.call(ctx, http.MethodPost, g.repoPath("statuses", run.HeadSHA), nil, map[string]string{
+		"state":       state,
+		"context":     run.Name,
+		"description": run.Title,
+	}, nil)
+	return wrapActionError(err)
+}
+
+// CreateRelease creates a release for the tag, Gitea has no concept of latest release,
+// so opts.Legacy is ignored.
+func (g *giteaHost) CreateRelease(ctx context.Context, opts ReleaseOptions) (Release, error) {
+	var release struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	err := g.call(ctx, http.MethodPost, g.repoPath("releases"), nil, map[string]any{
+		"tag_name":         opts.Tag,
+		"target_commitish": opts.Target,
+		"name":             opts.Name,
+		"body":             opts.Body,
+	}, &release)
+	if err != nil {
+		return Release{}, wrapActionError(err)
+	}
+	return Release{ID: release.ID, Name: release.Name}, nil
+}
+
+func (g *giteaHost) EditReleaseNotes(ctx context.Context, release Release, body string) error {
+	path := g.repoPath("releases", strconv.FormatInt(release.ID, 10))
+	err := g.call(ctx, http.MethodPatch, path, nil, map[string]any{"body": body}, nil)
+	return wrapActionError(err)
+}
+
+// UploadReleaseAsset uploads the asset as multipart form, the body is streamed from reader.
+func (g *giteaHost) UploadReleaseAsset(ctx context.Context, release Release, fileName string, size int64, reader io.Reader) error {
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("attachment", fileName)
+		if err == nil {
+			_, err = io.Copy(part, reader)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	path := g.repoPath("releases", strconv.FormatInt(release.ID, 10), "assets")
+	req, err := g.newRequest(ctx, http.MethodPost, path, url.Values{"name": {fileName}}, pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return wrapActionError(err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	err = g.do(req, nil)
+	// unblock the writer if the request fails before reading all
+	pr.CloseWithError(io.ErrClosedPipe)
+	return wrapActionError(err)
+}
+
+type giteaArtifact struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func (g *giteaHost) Artifacts(ctx context.Context, runID int64) ([]Artifact, error) {
+	path := g.repoPath("actions", "runs", strconv.FormatInt(runID, 10), "artifacts")
+	artifacts, err := giteaListOf(ctx, g, path, nil, func(resp *struct {
+		Artifacts []giteaArtifact `json:"artifacts"`
+	}) []giteaArtifact {
+		return resp.Artifacts
+	})
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	ret := make([]Artifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		ret = append(ret, Artifact{ID: artifact.ID, Name: artifact.Name})
+	}
+	return ret, nil
+}
+
+// DownloadArtifact downloads the artifact as a zip file,
+// the file name is retrieved from Content-Disposition, or named after the artifact.
+func (g *giteaHost) DownloadArtifact(ctx context.Context, artifact Artifact) (fileName string, size int64, body io.ReadCloser, err error) {
+	path := g.repoPath("actions", "artifacts", strconv.FormatInt(artifact.ID, 10), "zip")
+	req, err := g.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		err = wrapActionError(err)
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err = fmt.Errorf("actions: gitea: download artifact %s: %s", artifact.Name, resp.Status)
+		return
+	}
+
+	fileName = artifact.Name + ".zip"
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		fileName = params["filename"]
+	}
+	return fileName, resp.ContentLength, resp.Body, nil
+}
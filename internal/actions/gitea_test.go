@@ -0,0 +1,436 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+)
+
+// fakeGitea is an in-memory Gitea repository serving a subset of Gitea API v1
+type fakeGitea struct {
+	mu       sync.Mutex
+	branches map[string]string
+	tags     map[string]string
+	labels   map[int64]string
+	commits  []giteaCommit
+	// pulls maps commit SHA to the pull request merging it
+	pulls     map[string]PullRequest
+	prCommits map[int][]giteaCommit
+	releases  map[int64]map[string]string
+	assets    map[string]string
+	artifacts map[int64]string
+	// comments maps comment ID to the issue number and the body
+	comments map[int64][2]string
+	// statuses maps commit SHA to the state of each context
+	statuses map[string]map[string]string
+}
+
+func newFakeGitea() *fakeGitea {
+	return &fakeGitea{
+		branches:  map[string]string{"main": "sha0"},
+		tags:      map[string]string{},
+		labels:    map[int64]string{},
+		pulls:     map[string]PullRequest{},
+		prCommits: map[int][]giteaCommit{},
+		releases:  map[int64]map[string]string{},
+		assets:    map[string]string{},
+		artifacts: map[int64]string{},
+		comments:  map[int64][2]string{},
+		statuses:  map[string]map[string]string{},
+	}
+}
+
+func commitOf(sha, message string) giteaCommit {
+	c := giteaCommit{SHA: sha}
+	c.Commit.Message = message
+	return c
+}
+
+// page returns items of the page according to page and limit in query
+func page[T any](r *http.Request, items []T) []T {
+	p, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	start := min((p-1)*limit, len(items))
+	end := min(start+limit, len(items))
+	return items[start:end]
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/api/v1/repos/goplus/llpkg/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(path, "/")
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	var body map[string]any
+	if r.Header.Get("Content-Type") == "application/json" {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch {
+	case parts[0] == "branches" && len(parts) >= 2 && r.Method == http.MethodGet:
+		branchName := strings.Join(parts[1:], "/")
+		if _, ok := f.branches[branchName]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		reply(map[string]any{"name": branchName})
+	case parts[0] == "branches" && len(parts) >= 2 && r.Method == http.MethodDelete:
+		delete(f.branches, strings.Join(parts[1:], "/"))
+		w.WriteHeader(http.StatusNoContent)
+	case parts[0] == "branches" && r.Method == http.MethodPost:
+		f.branches[body["new_branch_name"].(string)] = body["old_ref_name"].(string)
+		w.WriteHeader(http.StatusCreated)
+	case parts[0] == "tags" && r.Method == http.MethodPost:
+		f.tags[body["tag_name"].(string)] = body["target"].(string)
+		w.WriteHeader(http.StatusCreated)
+	case parts[0] == "commits" && len(parts) == 3 && parts[2] == "pull":
+		pull, ok := f.pulls[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		reply(map[string]any{"number": pull.Number, "state": pull.State, "base": map[string]any{"ref": pull.BaseRef}})
+	case parts[0] == "commits" && len(parts) == 1:
+		reply(page(r, f.commits))
+	case parts[0] == "git" && parts[1] == "commits":
+		for _, commit := range f.commits {
+			if commit.SHA == parts[2] {
+				reply(commit)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case parts[0] == "pulls" && parts[2] == "commits":
+		number, _ := strconv.Atoi(parts[1])
+		reply(page(r, f.prCommits[number]))
+	case parts[0] == "labels" && len(parts) == 1:
+		var labels []map[string]any
+		for id := int64(1); id <= int64(len(f.labels))+10; id++ {
+			if name, ok := f.labels[id]; ok {
+				labels = append(labels, map[string]any{"id": id, "name": name})
+			}
+		}
+		reply(page(r, labels))
+	case parts[0] == "labels" && r.Method == http.MethodDelete:
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		delete(f.labels, id)
+		w.WriteHeader(http.StatusNoContent)
+	case parts[0] == "releases" && len(parts) == 1 && r.Method == http.MethodPost:
+		id := int64(len(f.releases) + 1)
+		f.releases[id] = map[string]string{"tag": body["tag_name"].(string), "target": body["target_commitish"].(string), "body": body["body"].(string)}
+		w.WriteHeader(http.StatusCreated)
+		reply(map[string]any{"id": id, "name": body["name"]})
+	case parts[0] == "releases" && len(parts) == 2 && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		f.releases[id]["body"] = body["body"].(string)
+		reply(map[string]any{"id": id})
+	case parts[0] == "releases" && len(parts) == 3 && parts[2] == "assets":
+		file, header, err := r.FormFile("attachment")
+		if err != nil || header.Filename != r.URL.Query().Get("name") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		f.assets[parts[1]+"/"+header.Filename] = string(content)
+		w.WriteHeader(http.StatusCreated)
+		reply(map[string]any{"id": 1})
+	case parts[0] == "actions" && parts[1] == "runs" && parts[3] == "artifacts":
+		var artifacts []map[string]any
+		for id := int64(1); id <= int64(len(f.artifacts))+10; id++ {
+			if name, ok := f.artifacts[id]; ok {
+				artifacts = append(artifacts, map[string]any{"id": id, "name": name})
+			}
+		}
+		reply(map[string]any{"total_count": len(artifacts), "artifacts": page(r, artifacts)})
+	case parts[0] == "actions" && parts[1] == "artifacts" && parts[3] == "zip":
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, f.artifacts[id]))
+		fmt.Fprintf(w, "content of %s", f.artifacts[id])
+	case parts[0] == "issues" && len(parts) == 3 && parts[2] == "comments" && r.Method == http.MethodGet:
+		var comments []map[string]any
+		for id := int64(1); id <= int64(len(f.comments)); id++ {
+			if f.comments[id][0] == parts[1] {
+				comments = append(comments, map[string]any{"id": id, "body": f.comments[id][1]})
+			}
+		}
+		reply(page(r, comments))
+	case parts[0] == "issues" && len(parts) == 3 && parts[2] == "comments" && r.Method == http.MethodPost:
+		f.comments[int64(len(f.comments)+1)] = [2]string{parts[1], body["body"].(string)}
+		w.WriteHeader(http.StatusCreated)
+	case parts[0] == "issues" && parts[1] == "comments" && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		comment, ok := f.comments[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f.comments[id] = [2]string{comment[0], body["body"].(string)}
+	case parts[0] == "statuses" && r.Method == http.MethodPost:
+		if f.statuses[parts[1]] == nil {
+			f.statuses[parts[1]] = map[string]string{}
+		}
+		f.statuses[parts[1]][body["context"].(string)] = body["state"].(string)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newGiteaTestHost(t *testing.T) (*fakeGitea, Host) {
+	fake := newFakeGitea()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, NewGiteaHost(server.URL+"/api/v1/", "goplus", "llpkg", "secret")
+}
+
+func TestGiteaHostRefs(t *testing.T) {
+	fake, host := newGiteaTestHost(t)
+	ctx := context.Background()
+
+	if !host.HasBranch(ctx, "main") || host.HasBranch(ctx, "release-branch.cjson/v1.0.0") {
+		t.Error("unexpected branches")
+	}
+	if err := host.CreateBranch(ctx, "release-branch.cjson/v1.0.0", "sha1"); err != nil {
+		t.Fatal(err)
+	}
+	if !host.HasBranch(ctx, "release-branch.cjson/v1.0.0") {
+		t.Error("Expected branch created")
+	}
+	if err := host.RemoveBranch(ctx, "release-branch.cjson/v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if host.HasBranch(ctx, "release-branch.cjson/v1.0.0") {
+		t.Error("Expected branch removed")
+	}
+	if err := host.CreateTag(ctx, "cjson/v1.0.0", "sha1"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.tags["cjson/v1.0.0"] != "sha1" {
+		t.Errorf("unexpected tags: %v", fake.tags)
+	}
+}
+
+func TestGiteaHostCommits(t *testing.T) {
+	fake, host := newGiteaTestHost(t)
+	ctx := context.Background()
+
+	// more than a page
+	for i := 0; i < giteaPageSize*2+1; i++ {
+		fake.commits = append(fake.commits, commitOf(fmt.Sprintf("sha%d", i), fmt.Sprintf("commit %d", i)))
+	}
+	fake.prCommits[1] = fake.commits[:giteaPageSize]
+	fake.pulls["sha1"] = PullRequest{Number: 1, State: "closed", BaseRef: "main"}
+
+	commit, found, err := host.FindCommit(ctx, func(c Commit) bool { return c.Message == "commit 100" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || commit.SHA != "sha100" {
+		t.Errorf("Expected sha100 found, got %v %v", commit, found)
+	}
+	if _, found, err := host.FindCommit(ctx, func(Commit) bool { return false }); err != nil || found {
+		t.Errorf("Expected no commit found, got %v %v", found, err)
+	}
+	prCommits, err := host.PullRequestCommits(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prCommits) != giteaPageSize {
+		t.Errorf("Expected %d commits, got %d", giteaPageSize, len(prCommits))
+	}
+	commit, err = host.Commit(ctx, "sha7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "commit 7" {
+		t.Errorf("unexpected commit: %v", commit)
+	}
+
+	pulls, err := host.PullRequestsWithCommit(ctx, "sha1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pulls) != 1 || pulls[0] != fake.pulls["sha1"] {
+		t.Errorf("unexpected pull requests: %v", pulls)
+	}
+	pulls, err = host.PullRequestsWithCommit(ctx, "sha2")
+	if err != nil || len(pulls) != 0 {
+		t.Errorf("Expected no pull request, got %v %v", pulls, err)
+	}
+}
+
+func TestGiteaHostRelease(t *testing.T) {
+	fake, host := newGiteaTestHost(t)
+	ctx := context.Background()
+	fake.artifacts[7] = "cjson_linux_amd64"
+
+	release, err := host.CreateRelease(ctx, ReleaseOptions{Tag: "cjson/v1.0.0", Target: "main", Name: "cjson/v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Name != "cjson/v1.0.0" || fake.releases[release.ID]["tag"] != "cjson/v1.0.0" {
+		t.Errorf("unexpected release: %v", release)
+	}
+	if err := host.EditReleaseNotes(ctx, release, "notes"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.releases[release.ID]["body"] != "notes" {
+		t.Errorf("Expected release notes edited, got %v", fake.releases[release.ID])
+	}
+
+	artifacts, err := host.Artifacts(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 1 || artifacts[0].ID != 7 {
+		t.Fatalf("unexpected artifacts: %v", artifacts)
+	}
+	fileName, size, body, err := host.DownloadArtifact(ctx, artifacts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if fileName != "cjson_linux_amd64.zip" {
+		t.Errorf("unexpected file name: %s", fileName)
+	}
+	if err := host.UploadReleaseAsset(ctx, release, fileName, size, body); err != nil {
+		t.Fatal(err)
+	}
+	if content := fake.assets["1/cjson_linux_amd64.zip"]; content != "content of cjson_linux_amd64" {
+		t.Errorf("unexpected asset: %q", content)
+	}
+}
+
+func TestGiteaHostPagination(t *testing.T) {
+	fake, host := newGiteaTestHost(t)
+	ctx := context.Background()
+
+	// more than a page
+	for i := 1; i <= giteaPageSize+1; i++ {
+		fake.artifacts[int64(i)] = fmt.Sprintf("artifact%d", i)
+		fake.comments[int64(i)] = [2]string{"1", fmt.Sprintf("comment %d", i)}
+	}
+	artifacts, err := host.Artifacts(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != giteaPageSize+1 || artifacts[giteaPageSize].Name != fmt.Sprintf("artifact%d", giteaPageSize+1) {
+		t.Errorf("Expected %d artifacts, got %d", giteaPageSize+1, len(artifacts))
+	}
+	comments, err := host.Comments(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != giteaPageSize+1 || comments[giteaPageSize].Body != fmt.Sprintf("comment %d", giteaPageSize+1) {
+		t.Errorf("Expected %d comments, got %d", giteaPageSize+1, len(comments))
+	}
+}
+
+func TestGiteaHostUnauthorized(t *testing.T) {
+	server := httptest.NewServer(newFakeGitea())
+	defer server.Close()
+	host := NewGiteaHost(server.URL+"/api/v1", "goplus", "llpkg", "wrong")
+	if err := host.CreateTag(context.Background(), "cjson/v1.0.0", "sha1"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected 401 error, got %v", err)
+	}
+}
+
+// fakeCIEnv is CIEnv with fixed values
+type fakeCIEnv struct {
+	pullRequest *PullRequest
+	issue       *Issue
+	sha         string
+	runID       int64
+	changes     []string
+	env         env.Env
+}
+
+func (f *fakeCIEnv) PullRequest() (*PullRequest, error) { return f.pullRequest, nil }
+
+func (f *fakeCIEnv) Issue() (*Issue, error) {
+	if f.issue == nil {
+		return nil, fmt.Errorf("env: cannot parse GITHUB_EVENT_PATH issue")
+	}
+	return f.issue, nil
+}
+
+func (f *fakeCIEnv) LatestCommitSHA() (string, error) { return f.sha, nil }
+func (f *fakeCIEnv) WorkflowRunID() (int64, error)    { return f.runID, nil }
+func (f *fakeCIEnv) Changes() ([]string, error)       { return f.changes, nil }
+
+func (f *fakeCIEnv) Setenv(e env.Env) error {
+	if f.env == nil {
+		f.env = env.Env{}
+	}
+	for k, v := range e {
+		f.env[k] = v
+	}
+	return nil
+}
+
+func (f *fakeCIEnv) UploadArtifact(name, _ string) (string, error) {
+	return "https://example.com/artifacts/" + name, nil
+}
+
+func TestClientWithGitea(t *testing.T) {
+	fake, host := newGiteaTestHost(t)
+	fake.commits = []giteaCommit{
+		commitOf("sha1", "Fix typo #3"),
+		commitOf("sha2", "cjson: 1.7.18\n\nRelease-as: cjson/v1.0.0"),
+	}
+	fake.pulls["sha1"] = PullRequest{Number: 2, State: "closed", BaseRef: "main"}
+	fake.pulls["sha2"] = PullRequest{Number: 4, State: "closed", BaseRef: "release-branch.cjson/v0.1.0"}
+	fake.prCommits[4] = fake.commits[1:]
+	fake.labels[5] = "release-branch.cjson/v0.1.0"
+	fake.labels[6] = "bug"
+
+	ci := &fakeCIEnv{sha: "sha2", issue: &Issue{Number: 3, Labels: []string{"bug", "release-branch.cjson/v0.1.0"}}}
+	client := NewClient(host, ci)
+
+	versions, err := client.mappedVersions()
+	if err != nil || !slices.Equal(versions, []string{"cjson/v1.0.0"}) {
+		t.Errorf("unexpected mapped versions: %v %v", versions, err)
+	}
+	branchName, legacy, err := client.isLegacyVersion()
+	if err != nil || !legacy || branchName != "release-branch.cjson/v0.1.0" {
+		t.Errorf("unexpected legacy version: %s %v %v", branchName, legacy, err)
+	}
+
+	ci.pullRequest = &PullRequest{Number: 4, BaseRef: "main"}
+	mappedVersion, err := client.checkMappedVersion("cjson")
+	if err != nil || mappedVersion != "Release-as: cjson/v1.0.0" {
+		t.Errorf("unexpected mapped version: %s %v", mappedVersion, err)
+	}
+
+	if err := client.CleanResource(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.labels[5]; ok {
+		t.Error("Expected label removed")
+	}
+	if _, ok := fake.labels[6]; !ok {
+		t.Error("unexpected label removed")
+	}
+}
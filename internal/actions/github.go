@@ -0,0 +1,406 @@
+package actions
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// githubPageSize is the number of items per page when listing, which is the max allowed by GitHub
+const githubPageSize = 100
+
+// maxAnnotations is the max number of annotations in a request of check runs
+const maxAnnotations = 50
+
+// githubHost is Host of GitHub
+type githubHost struct {
+	// repo: Target repository name
+	// owner: Repository owner organization/user
+	// client: Authenticated GitHub API client instance
+	repo   string
+	owner  string
+	client *github.Client
+}
+
+// NewGitHubHost returns Host of the GitHub repository owner/repo authenticated with the token,
+// requests are retried on server errors and rate limits.
+func NewGitHubHost(owner, repo, token string) Host {
+	return newGitHubHost(owner, repo, github.NewClient(newRetryClient()).WithAuthToken(token))
+}
+
+func newGitHubHost(owner, repo string, client *github.Client) *githubHost {
+	return &githubHost{owner: owner, repo: repo, client: client}
+}
+
+// withoutRateLimitCheck disables the check of go-github rejecting requests once the rate limit is exhausted,
+// so that retryTransport can wait for the rate limit to reset.
+func withoutRateLimitCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, github.BypassRateLimitCheck, true)
+}
+
+// listAll retrieves all the pages by list, which lists the page in opts
+func listAll[T any](opts *github.ListOptions, list func(opts *github.ListOptions) ([]T, *github.Response, error)) ([]T, error) {
+	var all []T
+	err := listEach(opts, list, func(item T) bool {
+		all = append(all, item)
+		return true
+	})
+	return all, err
+}
+
+// listEach retrieves the pages by list and calls yield for each item,
+// the next page isn't retrieved once yield returns false.
+func listEach[T any](opts *github.ListOptions, list func(opts *github.ListOptions) ([]T, *github.Response, error), yield func(T) bool) error {
+	opts.PerPage = githubPageSize
+	for {
+		items, resp, err := list(opts)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if !yield(item) {
+				return nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (g *githubHost) HasBranch(ctx context.Context, branchName string) bool {
+	ctx = withoutRateLimitCheck(ctx)
+
+	branch, resp, err := g.client.Repositories.GetBranch(
+		ctx, g.owner, g.repo, branchName, 0,
+	)
+
+	return err == nil && branch != nil &&
+		resp.StatusCode == http.StatusOK
+}
+
+func (g *githubHost) createRef(ctx context.Context, ref, sha string) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	_, _, err := g.client.Git.CreateRef(ctx, g.owner, g.repo, &github.Reference{
+		Ref: &ref,
+		Object: &github.GitObject{
+			SHA: &sha,
+		},
+	})
+	return wrapActionError(err)
+}
+
+func (g *githubHost) CreateBranch(ctx context.Context, branchName, sha string) error {
+	return g.createRef(ctx, branchRef(branchName), sha)
+}
+
+func (g *githubHost) RemoveBranch(ctx context.Context, branchName string) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	_, err := g.client.Git.DeleteRef(ctx, g.owner, g.repo, branchRef(branchName))
+
+	return wrapActionError(err)
+}
+
+func (g *githubHost) CreateTag(ctx context.Context, tag, sha string) error {
+	return g.createRef(ctx, tagRef(tag), sha)
+}
+
+func (g *githubHost) PullRequestsWithCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	pulls, err := listAll(&github.ListOptions{}, func(opts *github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+		return g.client.PullRequests.ListPullRequestsWithCommit(ctx, g.owner, g.repo, sha, opts)
+	})
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	ret := make([]PullRequest, 0, len(pulls))
+	for _, pull := range pulls {
+		ret = append(ret, PullRequest{
+			Number:  pull.GetNumber(),
+			State:   pull.GetState(),
+			BaseRef: pull.GetBase().GetRef(),
+			HeadSHA: pull.GetHead().GetSHA(),
+		})
+	}
+	return ret, nil
+}
+
+// fromGitHubCommits converts GitHub commits to Commit
+func fromGitHubCommits(commits []*github.RepositoryCommit) []Commit {
+	ret := make([]Commit, 0, len(commits))
+	for _, commit := range commits {
+		ret = append(ret, Commit{SHA: commit.GetSHA(), Message: commit.GetCommit().GetMessage()})
+	}
+	return ret
+}
+
+func (g *githubHost) PullRequestCommits(ctx context.Context, number int) ([]Commit, error) {
+	ctx = withoutRateLimitCheck(ctx)
+	// use authorized API to avoid Github RateLimit
+	commits, err := listAll(&github.ListOptions{}, func(opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+		return g.client.PullRequests.ListCommits(ctx, g.owner, g.repo, number, opts)
+	})
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	return fromGitHubCommits(commits), nil
+}
+
+func (g *githubHost) FindCommit(ctx context.Context, match func(Commit) bool) (commit Commit, found bool, err error) {
+	ctx = withoutRateLimitCheck(ctx)
+	// use authorized API to avoid Github RateLimit
+	listOpts := &github.CommitsListOptions{}
+	err = listEach(&listOpts.ListOptions, func(*github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+		return g.client.Repositories.ListCommits(ctx, g.owner, g.repo, listOpts)
+	}, func(c *github.RepositoryCommit) bool {
+		commit = Commit{SHA: c.GetSHA(), Message: c.GetCommit().GetMessage()}
+		found = match(commit)
+		return !found
+	})
+	if err != nil || !found {
+		return Commit{}, false, wrapActionError(err)
+	}
+	return commit, true, nil
+}
+
+func (g *githubHost) Commit(ctx context.Context, sha string) (Commit, error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	commit, _, err := g.client.Repositories.GetCommit(ctx, g.owner, g.repo, sha, &github.ListOptions{})
+	if err != nil {
+		return Commit{}, wrapActionError(err)
+	}
+	return Commit{SHA: commit.GetSHA(), Message: commit.GetCommit().GetMessage()}, nil
+}
+
+func (g *githubHost) RemoveLabel(ctx context.Context, labelName string) error {
+	ctx = withoutRateLimitCheck(ctx)
+	// use authorized API to avoid Github RateLimit
+	_, err := g.client.Issues.DeleteLabel(
+		ctx, g.owner, g.repo, labelName,
+	)
+	return wrapActionError(err)
+}
+
+func (g *githubHost) Comments(ctx context.Context, number int) ([]Comment, error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	listOpts := &github.IssueListCommentsOptions{}
+	comments, err := listAll(&listOpts.ListOptions, func(*github.ListOptions) ([]*github.IssueComment, *github.Response, error) {
+		return g.client.Issues.ListComments(ctx, g.owner, g.repo, number, listOpts)
+	})
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	ret := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		ret = append(ret, Comment{ID: comment.GetID(), Body: comment.GetBody()})
+	}
+	return ret, nil
+}
+
+func (g *githubHost) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (PullRequest, error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	pull, _, err := g.client.PullRequests.Create(ctx, g.owner, g.repo, &github.NewPullRequest{
+		Title: &opts.Title,
+		Body:  &opts.Body,
+		Head:  &opts.Head,
+		Base:  &opts.Base,
+	})
+	if err != nil {
+		return PullRequest{}, wrapActionError(err)
+	}
+	return PullRequest{
+		Number:  pull.GetNumber(),
+		State:   pull.GetState(),
+		BaseRef: pull.GetBase().GetRef(),
+		HeadSHA: pull.GetHead().GetSHA(),
+	}, nil
+}
+
+func (g *githubHost) CreateComment(ctx context.Context, number int, body string) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	_, _, err := g.client.Issues.CreateComment(ctx, g.owner, g.repo, number, &github.IssueComment{Body: &body})
+	return wrapActionError(err)
+}
+
+func (g *githubHost) EditComment(ctx context.Context, id int64, body string) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	_, _, err := g.client.Issues.EditComment(ctx, g.owner, g.repo, id, &github.IssueComment{Body: &body})
+	return wrapActionError(err)
+}
+
+// CreateCheckRun creates the check run with the first maxAnnotations annotations,
+// and adds the rest by updating it, since GitHub limits the number of annotations per request.
+func (g *githubHost) CreateCheckRun(ctx context.Context, run CheckRun) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	conclusion := "success"
+	if !run.Success {
+		conclusion = "failure"
+	}
+	output := func(annotations []Annotation) *github.CheckRunOutput {
+		ret := &github.CheckRunOutput{Title: &run.Title, Summary: &run.Summary}
+		for _, annotation := range annotations {
+			ret.Annotations = append(ret.Annotations, &github.CheckRunAnnotation{
+				Path:            github.Ptr(annotation.Path),
+				StartLine:       github.Ptr(annotation.Line),
+				EndLine:         github.Ptr(annotation.Line),
+				AnnotationLevel: github.Ptr(annotation.Level),
+				Title:           github.Ptr(annotation.Title),
+				Message:         github.Ptr(annotation.Message),
+			})
+		}
+		return ret
+	}
+	first := run.Annotations[:min(len(run.Annotations), maxAnnotations)]
+	checkRun, _, err := g.client.Checks.CreateCheckRun(ctx, g.owner, g.repo, github.CreateCheckRunOptions{
+		Name:       run.Name,
+		HeadSHA:    run.HeadSHA,
+		Status:     github.Ptr("completed"),
+		Conclusion: &conclusion,
+		Output:     output(first),
+	})
+	if err != nil {
+		return wrapActionError(err)
+	}
+	for rest := run.Annotations[len(first):]; len(rest) > 0; {
+		batch := rest[:min(len(rest), maxAnnotations)]
+		rest = rest[len(batch):]
+		_, _, err := g.client.Checks.UpdateCheckRun(ctx, g.owner, g.repo, checkRun.GetID(), github.UpdateCheckRunOptions{
+			Name:   run.Name,
+			Output: output(batch),
+		})
+		if err != nil {
+			return wrapActionError(err)
+		}
+	}
+	return nil
+}
+
+func (g *githubHost) CreateRelease(ctx context.Context, opts ReleaseOptions) (Release, error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	makeLatest := "true"
+	if opts.Legacy {
+		makeLatest = "legacy"
+	}
+
+	release, _, err := g.client.Repositories.CreateRelease(ctx, g.owner, g.repo, &github.RepositoryRelease{
+		TagName:         &opts.Tag,
+		TargetCommitish: &opts.Target,
+		Name:            &opts.Name,
+		Body:            &opts.Body,
+		MakeLatest:      &makeLatest,
+	})
+	if err != nil {
+		return Release{}, wrapActionError(err)
+	}
+	return Release{ID: release.GetID(), Name: release.GetName()}, nil
+}
+
+func (g *githubHost) EditReleaseNotes(ctx context.Context, release Release, body string) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	_, _, err := g.client.Repositories.EditRelease(ctx, g.owner, g.repo, release.ID, &github.RepositoryRelease{
+		Body: &body,
+	})
+	return wrapActionError(err)
+}
+
+func (g *githubHost) UploadReleaseAsset(ctx context.Context, release Release, fileName string, size int64, reader io.Reader) error {
+	ctx = withoutRateLimitCheck(ctx)
+
+	url := fmt.Sprintf("repos/%s/%s/releases/%d/assets?name=%s", g.owner, g.repo, release.ID, fileName)
+
+	mediaType := "application/zip"
+	if path.Ext(fileName) != ".zip" {
+		mediaType = cmp.Or(mime.TypeByExtension(path.Ext(fileName)), "application/octet-stream")
+	}
+	req, err := g.client.NewUploadRequest(url, reader, size, mediaType)
+	if err != nil {
+		return wrapActionError(err)
+	}
+
+	asset := new(github.ReleaseAsset)
+	_, err = g.client.Do(ctx, req, asset)
+	if err != nil {
+		return wrapActionError(err)
+	}
+	return nil
+}
+
+func (g *githubHost) Artifacts(ctx context.Context, runID int64) ([]Artifact, error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	artifacts, err := listAll(&github.ListOptions{}, func(opts *github.ListOptions) ([]*github.Artifact, *github.Response, error) {
+		list, resp, err := g.client.Actions.ListWorkflowRunArtifacts(ctx, g.owner, g.repo, runID, opts)
+		if err != nil {
+			return nil, resp, err
+		}
+		return list.Artifacts, resp, nil
+	})
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	ret := make([]Artifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		ret = append(ret, Artifact{ID: artifact.GetID(), Name: artifact.GetName()})
+	}
+	return ret, nil
+}
+
+// DownloadArtifact downloads the artifact from the redirected URL,
+// the file name is retrieved from Content-Disposition.
+// The download is retried as API calls, and reading the body is bound to apiTimeout.
+func (g *githubHost) DownloadArtifact(ctx context.Context, artifact Artifact) (fileName string, size int64, body io.ReadCloser, err error) {
+	ctx = withoutRateLimitCheck(ctx)
+
+	url, _, err := g.client.Actions.DownloadArtifact(ctx, g.owner, g.repo,
+		artifact.ID, 0)
+	if err != nil {
+		err = wrapActionError(err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		err = wrapActionError(err)
+		return
+	}
+	resp, err := newRetryClient().Do(req)
+	if err != nil {
+		err = wrapActionError(err)
+		return
+	}
+
+	disposition := resp.Header.Get("Content-Disposition")
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		resp.Body.Close()
+		err = wrapActionError(err)
+		return
+	}
+
+	fileName, ok := params["filename"]
+	if !ok {
+		resp.Body.Close()
+		err = errors.New("actions: no filename found in Content-Disposition")
+		return
+	}
+	return fileName, resp.ContentLength, resp.Body, nil
+}
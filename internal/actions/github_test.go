@@ -0,0 +1,223 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// fakeGitHub serves paginated lists of the GitHub REST API,
+// it responds 502 to the first request and rate limits the second one,
+// a POST isn't retried on server errors, so it's rate limited if it's the first request.
+type fakeGitHub struct {
+	now      time.Time
+	requests atomic.Int32
+	commits  []map[string]any
+	pulls    []map[string]any
+	// artifacts is the number of artifacts of the workflow run
+	artifacts int
+	// annotations is the number of annotations in each request of check runs
+	annotations []int
+	// branches are the existing branches, created are the pull requests opened
+	branches []string
+	created  []map[string]any
+}
+
+// paginate writes the page of items with the Link header like GitHub
+func paginate(w http.ResponseWriter, r *http.Request, items []map[string]any) []map[string]any {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = max(page, 1)
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage == 0 {
+		perPage = 30
+	}
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		next := *r.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.String()))
+	}
+	return items[start:end]
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := f.requests.Add(1)
+	if n == 1 && !isIdempotent(r.Method) {
+		n = f.requests.Add(1)
+	}
+	switch n {
+	case 1:
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	case 2:
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(f.now.Unix()+60, 10))
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"message": "API rate limit exceeded"})
+		return
+	}
+
+	var body any
+	switch path := strings.TrimPrefix(r.URL.Path, "/repos/goplus/llpkg/"); {
+	case path == "commits":
+		body = paginate(w, r, f.commits)
+	case path == "pulls/1/commits":
+		body = paginate(w, r, f.commits)
+	case path == "pulls" && r.Method == http.MethodPost:
+		var pull map[string]any
+		json.NewDecoder(r.Body).Decode(&pull)
+		f.created = append(f.created, pull)
+		body = map[string]any{"number": len(f.created), "state": "open"}
+	case strings.HasPrefix(path, "branches/"):
+		name, _ := url.PathUnescape(strings.TrimPrefix(path, "branches/"))
+		if !slices.Contains(f.branches, name) {
+			http.NotFound(w, r)
+			return
+		}
+		body = map[string]any{"name": name}
+	case strings.HasPrefix(path, "commits/") && strings.HasSuffix(path, "/pulls"):
+		body = paginate(w, r, f.pulls)
+	case path == "check-runs" || path == "check-runs/1":
+		var run struct {
+			Conclusion string `json:"conclusion"`
+			Output     struct {
+				Annotations []any `json:"annotations"`
+			} `json:"output"`
+		}
+		json.NewDecoder(r.Body).Decode(&run)
+		if r.Method == http.MethodPost && run.Conclusion != "failure" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.annotations = append(f.annotations, len(run.Output.Annotations))
+		body = map[string]any{"id": 1}
+	case path == "actions/runs/1/artifacts":
+		var artifacts []map[string]any
+		for i := 0; i < f.artifacts; i++ {
+			artifacts = append(artifacts, map[string]any{"id": i, "name": fmt.Sprintf("artifact%d", i)})
+		}
+		body = map[string]any{"total_count": f.artifacts, "artifacts": paginate(w, r, artifacts)}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func newGitHubTestHost(t *testing.T, fake *fakeGitHub) Host {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	httpClient, _ := newTestRetryClient(fake.now)
+	client := github.NewClient(httpClient)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return newGitHubHost("goplus", "llpkg", client)
+}
+
+func TestGitHubHostPagination(t *testing.T) {
+	fake := &fakeGitHub{now: time.Now(), artifacts: 250}
+	for i := 0; i < 2*githubPageSize+1; i++ {
+		fake.commits = append(fake.commits, map[string]any{
+			"sha":    fmt.Sprintf("sha%d", i),
+			"commit": map[string]any{"message": fmt.Sprintf("commit %d", i)},
+		})
+	}
+	for i := 0; i < githubPageSize+1; i++ {
+		fake.pulls = append(fake.pulls, map[string]any{"number": i, "state": "closed", "base": map[string]any{"ref": "main"}})
+	}
+	host := newGitHubTestHost(t, fake)
+	ctx := context.Background()
+
+	// the first requests are retried
+	commit, found, err := host.FindCommit(ctx, func(c Commit) bool { return c.Message == "commit 200" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || commit.SHA != "sha200" {
+		t.Errorf("Expected sha200 found, got %v %v", commit, found)
+	}
+	// the next page isn't retrieved after the match
+	requests := fake.requests.Load()
+	if _, found, err := host.FindCommit(ctx, func(c Commit) bool { return c.Message == "commit 1" }); err != nil || !found {
+		t.Fatalf("Expected commit 1 found, got %v %v", found, err)
+	}
+	if n := fake.requests.Load() - requests; n != 1 {
+		t.Errorf("Expected 1 request, got %d", n)
+	}
+	prCommits, err := host.PullRequestCommits(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prCommits) != len(fake.commits) {
+		t.Errorf("Expected %d commits, got %d", len(fake.commits), len(prCommits))
+	}
+	pulls, err := host.PullRequestsWithCommit(ctx, "sha1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pulls) != len(fake.pulls) || pulls[100].Number != 100 {
+		t.Errorf("Expected %d pull requests, got %d", len(fake.pulls), len(pulls))
+	}
+	artifacts, err := host.Artifacts(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != fake.artifacts || artifacts[249].Name != "artifact249" {
+		t.Errorf("Expected %d artifacts, got %d", fake.artifacts, len(artifacts))
+	}
+}
+
+func TestClientPagination(t *testing.T) {
+	fake := &fakeGitHub{now: time.Now()}
+	// Release-as is in the last commit of a large pull request
+	for i := 0; i < githubPageSize+10; i++ {
+		fake.commits = append(fake.commits, map[string]any{
+			"sha":    fmt.Sprintf("sha%d", i),
+			"commit": map[string]any{"message": fmt.Sprintf("commit %d", i)},
+		})
+	}
+	fake.commits[len(fake.commits)-1]["commit"] = map[string]any{"message": "feat: cjson\n\nRelease-as: cjson/v1.0.0"}
+
+	client := NewClient(newGitHubTestHost(t, fake), &fakeCIEnv{pullRequest: &PullRequest{Number: 1, BaseRef: "main"}})
+	version, err := client.checkMappedVersion("cjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "Release-as: cjson/v1.0.0" {
+		t.Errorf("Expected Release-as: cjson/v1.0.0, got %s", version)
+	}
+}
+
+func TestGitHubHostCheckRun(t *testing.T) {
+	fake := &fakeGitHub{now: time.Now()}
+	host := newGitHubTestHost(t, fake)
+
+	run := CheckRun{Name: "llpkgstore verification", HeadSHA: "sha1", Title: "verification failed"}
+	for i := 0; i < 2*maxAnnotations+1; i++ {
+		run.Annotations = append(run.Annotations, Annotation{Path: "cjson/cJSON.go", Line: i + 1, Level: AnnotationFailure})
+	}
+	if err := host.CreateCheckRun(context.Background(), run); err != nil {
+		t.Fatal(err)
+	}
+	// annotations are added in batches
+	expected := []int{maxAnnotations, maxAnnotations, 1}
+	if !slices.Equal(fake.annotations, expected) {
+		t.Errorf("Expected %v, got %v", expected, fake.annotations)
+	}
+}
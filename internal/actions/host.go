@@ -0,0 +1,182 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+)
+
+// apiTimeout is the timeout of each request to the source host, see retryTransport
+const apiTimeout = 30 * time.Second
+
+// Source hosts supported, selected by LLPKGSTORE_SOURCE_HOST,
+// GitLab isn't supported yet, since GitLab CI needs its own CIEnv.
+const (
+	SourceHostGitHub = "github"
+	SourceHostGitea  = "gitea"
+)
+
+// Host is the operations of a source host (e.g. GitHub, Gitea) required by actions.
+// Implementations should send requests by retryTransport, which applies apiTimeout to each request,
+// and list all the pages, except FindCommit, which stops at the first match.
+type Host interface {
+	// HasBranch reports whether the branch exists
+	HasBranch(ctx context.Context, branchName string) bool
+	// CreateBranch creates a branch pointing to the commit
+	CreateBranch(ctx context.Context, branchName, sha string) error
+	// RemoveBranch deletes the branch
+	RemoveBranch(ctx context.Context, branchName string) error
+	// CreateTag creates a lightweight tag pointing to the commit
+	CreateTag(ctx context.Context, tag, sha string) error
+
+	// PullRequestsWithCommit lists pull requests containing the commit
+	PullRequestsWithCommit(ctx context.Context, sha string) ([]PullRequest, error)
+	// PullRequestCommits lists commits of the pull request
+	PullRequestCommits(ctx context.Context, number int) ([]Commit, error)
+	// FindCommit returns the latest commit of the default branch reported by match,
+	// found is false if no commit matches.
+	FindCommit(ctx context.Context, match func(Commit) bool) (commit Commit, found bool, err error)
+	// Commit retrieves the commit by SHA
+	Commit(ctx context.Context, sha string) (Commit, error)
+
+	// CreatePullRequest opens a pull request merging the head branch into the base branch
+	CreatePullRequest(ctx context.Context, opts PullRequestOptions) (PullRequest, error)
+
+	// RemoveLabel deletes the label from the repository
+	RemoveLabel(ctx context.Context, labelName string) error
+
+	// Comments lists comments of the pull request
+	Comments(ctx context.Context, number int) ([]Comment, error)
+	// CreateComment comments on the pull request
+	CreateComment(ctx context.Context, number int, body string) error
+	// EditComment replaces the body of the comment
+	EditComment(ctx context.Context, id int64, body string) error
+	// CreateCheckRun creates a completed check run for the commit
+	CreateCheckRun(ctx context.Context, run CheckRun) error
+
+	// CreateRelease creates a release for the tag
+	CreateRelease(ctx context.Context, opts ReleaseOptions) (Release, error)
+	// EditReleaseNotes replaces the notes of the release, which are in Markdown
+	EditReleaseNotes(ctx context.Context, release Release, body string) error
+	// UploadReleaseAsset uploads an asset with the size read from reader to the release
+	UploadReleaseAsset(ctx context.Context, release Release, fileName string, size int64, reader io.Reader) error
+
+	// Artifacts lists artifacts uploaded by the workflow run
+	Artifacts(ctx context.Context, runID int64) ([]Artifact, error)
+	// DownloadArtifact downloads the artifact as a zip archive of its files, the caller must close body.
+	DownloadArtifact(ctx context.Context, artifact Artifact) (fileName string, size int64, body io.ReadCloser, err error)
+}
+
+// PullRequest is a pull request of the source host
+type PullRequest struct {
+	Number int
+	// State is "open" or "closed"
+	State string
+	// BaseRef is the name of the target branch
+	BaseRef string
+	// HeadSHA is the latest commit of the pull request
+	HeadSHA string
+}
+
+// PullRequestOptions describes a pull request to be opened
+type PullRequestOptions struct {
+	Title string
+	// Body is in Markdown
+	Body string
+	// Head is the branch containing the changes, Base is the branch to merge into
+	Head string
+	Base string
+}
+
+// Commit is a commit of the source host
+type Commit struct {
+	SHA     string
+	Message string
+}
+
+// Comment is a comment of a pull request
+type Comment struct {
+	ID   int64
+	Body string
+}
+
+// Levels of Annotation
+const (
+	AnnotationNotice  = "notice"
+	AnnotationWarning = "warning"
+	AnnotationFailure = "failure"
+)
+
+// Annotation points out a problem at the line of a file in a check run
+type Annotation struct {
+	// Path is relative to the root of the repository
+	Path  string
+	Line  int
+	Level string
+	Title string
+	// Message is shown at the line
+	Message string
+}
+
+// CheckRun is the result of a check on a commit
+type CheckRun struct {
+	Name    string
+	HeadSHA string
+	Success bool
+	// Title and Summary are shown in the check run page, Summary is in Markdown.
+	Title       string
+	Summary     string
+	Annotations []Annotation
+}
+
+// Release is a release of the source host
+type Release struct {
+	ID   int64
+	Name string
+}
+
+// ReleaseOptions describes a release to be created
+type ReleaseOptions struct {
+	Tag string
+	// Target is the branch the tag is created from if the tag doesn't exist
+	Target string
+	Name   string
+	// Body is the release notes in Markdown
+	Body string
+	// Legacy is true if the release shouldn't be marked as latest
+	Legacy bool
+}
+
+// Artifact is an artifact uploaded by a workflow run
+type Artifact struct {
+	ID   int64
+	Name string
+}
+
+// NewHostFromEnv creates a source host client selected by LLPKGSTORE_SOURCE_HOST,
+// using the token and repository of current runner.
+func NewHostFromEnv() (Host, error) {
+	token, err := env.Token()
+	if err != nil {
+		return nil, err
+	}
+	owner, repo, err := env.Repository()
+	if err != nil {
+		return nil, err
+	}
+	switch sourceHost := env.SourceHost(); sourceHost {
+	case SourceHostGitHub:
+		return NewGitHubHost(owner, repo, token), nil
+	case SourceHostGitea:
+		apiURL, err := env.APIURL()
+		if err != nil {
+			return nil, err
+		}
+		return NewGiteaHost(apiURL, owner, repo, token), nil
+	default:
+		return nil, fmt.Errorf("actions: unknown source host: %s", sourceHost)
+	}
+}
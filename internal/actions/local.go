@@ -0,0 +1,266 @@
+package actions
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/env"
+)
+
+// errReadOnly is returned when a local host is asked to change the repository
+var errReadOnly = errors.New("actions: local host is read-only, use it with dry run")
+
+// LocalOptions describes a pull request in a local git repository,
+// the pull request consists of the commits in the range Base..Head.
+type LocalOptions struct {
+	// RepoDir is the path to the git repository
+	RepoDir string
+	// Base is the target branch of the pull request, e.g. main
+	Base string
+	// Head is the revision of the pull request, e.g. HEAD
+	Head string
+	// Number is the number of the pull request
+	Number int
+	// Artifacts are paths to the local files regarded as artifacts of the workflow run,
+	// each of them is an artifact uploaded by actions/upload-artifact containing only the file
+	Artifacts []string
+}
+
+// git runs the git command in the repository and returns the output
+func (o LocalOptions) git(args ...string) (string, error) {
+	ret, err := exec.Command("git", append([]string{"-C", o.RepoDir}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("actions: git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", wrapActionError(err)
+	}
+	return string(ret), nil
+}
+
+// localHost is Host reading from a local git repository,
+// it's used to rehearse actions without accessing the source host.
+type localHost struct {
+	opts LocalOptions
+}
+
+// NewLocalHost returns a read-only Host of the local git repository
+func NewLocalHost(opts LocalOptions) Host {
+	return &localHost{opts: opts}
+}
+
+// commits lists commits by git log with the arguments
+func (l *localHost) commits(args ...string) ([]Commit, error) {
+	// separate SHA and message by NUL, and commits by RS
+	out, err := l.opts.git(append([]string{"log", "--format=%H%x00%B%x1e"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		sha, message, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x00")
+		if !ok {
+			continue
+		}
+		commits = append(commits, Commit{SHA: sha, Message: message})
+	}
+	return commits, nil
+}
+
+func (l *localHost) HasBranch(_ context.Context, branchName string) bool {
+	_, err := l.opts.git("rev-parse", "--verify", "--quiet", branchRef(branchName))
+	return err == nil
+}
+
+func (l *localHost) CreateBranch(context.Context, string, string) error {
+	return errReadOnly
+}
+
+func (l *localHost) RemoveBranch(context.Context, string) error {
+	return errReadOnly
+}
+
+func (l *localHost) CreateTag(context.Context, string, string) error {
+	return errReadOnly
+}
+
+// PullRequestsWithCommit regards every commit as merged by the pull request in options
+func (l *localHost) PullRequestsWithCommit(context.Context, string) ([]PullRequest, error) {
+	head, err := l.opts.git("rev-parse", l.opts.Head)
+	if err != nil {
+		return nil, err
+	}
+	return []PullRequest{{Number: l.opts.Number, State: "closed", BaseRef: l.opts.Base, HeadSHA: strings.TrimSpace(head)}}, nil
+}
+
+// PullRequestCommits lists commits in the range Base..Head
+func (l *localHost) PullRequestCommits(context.Context, int) ([]Commit, error) {
+	return l.commits(l.opts.Base + ".." + l.opts.Head)
+}
+
+func (l *localHost) FindCommit(_ context.Context, match func(Commit) bool) (Commit, bool, error) {
+	commits, err := l.commits(l.opts.Head)
+	if err != nil {
+		return Commit{}, false, err
+	}
+	for _, commit := range commits {
+		if match(commit) {
+			return commit, true, nil
+		}
+	}
+	return Commit{}, false, nil
+}
+
+func (l *localHost) Commit(_ context.Context, sha string) (Commit, error) {
+	commits, err := l.commits("-n", "1", sha)
+	if err != nil {
+		return Commit{}, err
+	}
+	if len(commits) == 0 {
+		return Commit{}, fmt.Errorf("actions: commit %s not found", sha)
+	}
+	return commits[0], nil
+}
+
+func (l *localHost) RemoveLabel(context.Context, string) error {
+	return errReadOnly
+}
+
+// Comments returns no comment, since there's no pull request on the source host
+func (l *localHost) Comments(context.Context, int) ([]Comment, error) {
+	return nil, nil
+}
+
+func (l *localHost) CreatePullRequest(context.Context, PullRequestOptions) (PullRequest, error) {
+	return PullRequest{}, errReadOnly
+}
+
+func (l *localHost) CreateComment(context.Context, int, string) error {
+	return errReadOnly
+}
+
+func (l *localHost) EditComment(context.Context, int64, string) error {
+	return errReadOnly
+}
+
+func (l *localHost) CreateCheckRun(context.Context, CheckRun) error {
+	return errReadOnly
+}
+
+func (l *localHost) CreateRelease(context.Context, ReleaseOptions) (Release, error) {
+	return Release{}, errReadOnly
+}
+
+func (l *localHost) EditReleaseNotes(context.Context, Release, string) error {
+	return errReadOnly
+}
+
+func (l *localHost) UploadReleaseAsset(context.Context, Release, string, int64, io.Reader) error {
+	return errReadOnly
+}
+
+// Artifacts lists the artifact files in options, IDs are the indexes of the files
+func (l *localHost) Artifacts(context.Context, int64) ([]Artifact, error) {
+	artifacts := make([]Artifact, 0, len(l.opts.Artifacts))
+	for i, path := range l.opts.Artifacts {
+		artifacts = append(artifacts, Artifact{
+			ID:   int64(i),
+			Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		})
+	}
+	return artifacts, nil
+}
+
+// DownloadArtifact archives the artifact file into a zip as actions/upload-artifact does
+func (l *localHost) DownloadArtifact(_ context.Context, artifact Artifact) (fileName string, size int64, body io.ReadCloser, err error) {
+	if artifact.ID < 0 || artifact.ID >= int64(len(l.opts.Artifacts)) {
+		err = fmt.Errorf("actions: artifact %s not found", artifact.Name)
+		return
+	}
+	path := l.opts.Artifacts[artifact.ID]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = wrapActionError(err)
+		return
+	}
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	entry, err := w.Create(filepath.Base(path))
+	if err == nil {
+		_, err = entry.Write(data)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		err = wrapActionError(err)
+		return
+	}
+	return artifact.Name + ".zip", int64(archive.Len()), io.NopCloser(&archive), nil
+}
+
+// localCIEnv is CIEnv of a local git repository,
+// the pull request is described by options, and environment variables are written to out.
+type localCIEnv struct {
+	opts LocalOptions
+	out  io.Writer
+}
+
+// NewLocalCIEnv returns CIEnv of the pull request in the local git repository,
+// exported environment variables are written to out instead of GITHUB_ENV.
+func NewLocalCIEnv(opts LocalOptions, out io.Writer) CIEnv {
+	return &localCIEnv{opts: opts, out: out}
+}
+
+func (l *localCIEnv) PullRequest() (*PullRequest, error) {
+	head, err := l.LatestCommitSHA()
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: l.opts.Number, State: "open", BaseRef: l.opts.Base, HeadSHA: head}, nil
+}
+
+func (l *localCIEnv) Issue() (*Issue, error) {
+	return nil, errors.New("actions: no issue in local environment")
+}
+
+func (l *localCIEnv) LatestCommitSHA() (string, error) {
+	sha, err := l.opts.git("rev-parse", l.opts.Head)
+	return strings.TrimSpace(sha), err
+}
+
+// WorkflowRunID always returns 0, since there's no workflow run locally
+func (l *localCIEnv) WorkflowRunID() (int64, error) {
+	return 0, nil
+}
+
+// Changes returns the files changed by Head since it forked from Base
+func (l *localCIEnv) Changes() ([]string, error) {
+	out, err := l.opts.git("diff", "--name-only", l.opts.Base+"..."+l.opts.Head)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+func (l *localCIEnv) Setenv(e env.Env) error {
+	_, err := fmt.Fprintln(l.out, e.String())
+	return err
+}
+
+// UploadArtifact keeps the artifact as the local file, and returns the absolute path of it,
+// the upload is recorded by the dry-run CIEnv wrapping it.
+func (l *localCIEnv) UploadArtifact(_, fileName string) (string, error) {
+	path, err := filepath.Abs(fileName)
+	return path, wrapActionError(err)
+}
@@ -0,0 +1,308 @@
+package actions
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator/llcppg"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// maxListedSymbols is the max number of symbols listed in each of added and removed,
+// which keeps the release notes readable and under the limit of GitHub (125000 characters).
+const maxListedSymbols = 100
+
+// ReleaseNotes describes a released llpkg, which is published as the body of the release
+type ReleaseNotes struct {
+	Package       string
+	MappedVersion string
+	// Installer and Version are the upstream installer and C version of the package
+	Installer string
+	Version   string
+	// Dependencies are the C dependencies, Version of a requirement is empty if go.mod doesn't require its llpkg.
+	// DependencyErr is set if they cannot be resolved.
+	Dependencies  []Requirement
+	DependencyErr error
+	// PreviousVersion is the latest mapped version before MappedVersion, empty for the first release
+	PreviousVersion string
+	// Symbols is the number of exported Go symbols, Added and Removed are compared to PreviousVersion.
+	// SymbolsErr is set if they cannot be compared.
+	Symbols        int
+	Added, Removed []string
+	SymbolsErr     error
+	// Checksums are hex encoded sha256 of the release assets keyed by file name
+	Checksums map[string]string
+}
+
+// previousMappedVersion returns the latest mapped version of the C library before mappedVersion,
+// including the ones of history series.
+func previousMappedVersion(ver *versions.Versions, clib, mappedVersion string) string {
+	var previous string
+	for _, goVersion := range ver.GoVersions(clib) {
+		if semver.Compare(goVersion, mappedVersion) < 0 && semver.Compare(goVersion, previous) > 0 {
+			previous = goVersion
+		}
+	}
+	return previous
+}
+
+// receiverName returns the name of the receiver type, e.g. T of *T or T[K]
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// exportedSymbols returns the sorted exported symbols declared in Go source files keyed by file name,
+// methods are named after their receivers, e.g. Json.Print. Test files are skipped.
+func exportedSymbols(files map[string][]byte) ([]string, error) {
+	fset := token.NewFileSet()
+	var symbols []string
+	for name, src := range files {
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				if decl.Recv == nil {
+					symbols = append(symbols, decl.Name.Name)
+				} else if recv := receiverName(decl.Recv.List[0].Type); token.IsExported(recv) {
+					symbols = append(symbols, recv+"."+decl.Name.Name)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							symbols = append(symbols, spec.Name.Name)
+						}
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							if name.IsExported() {
+								symbols = append(symbols, name.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(symbols)
+	return slices.Compact(symbols), nil
+}
+
+// packageSymbols returns the exported symbols of the Go package in the directory
+func packageSymbols(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if files[entry.Name()], err = os.ReadFile(filepath.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		}
+	}
+	return exportedSymbols(files)
+}
+
+// packageSymbolsAt returns the exported symbols of the Go package in the directory at the revision of the repository
+func packageSymbolsAt(repo LocalOptions, rev, dir string) ([]string, error) {
+	out, err := repo.git("ls-tree", "--name-only", rev, "--", dir+"/")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, name := range strings.Fields(out) {
+		if path.Dir(name) != dir {
+			continue
+		}
+		src, err := repo.git("show", rev+":"+name)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = []byte(src)
+	}
+	return exportedSymbols(files)
+}
+
+// diffSymbols compares sorted symbols
+func diffSymbols(previous, current []string) (added, removed []string) {
+	for _, symbol := range current {
+		if _, found := slices.BinarySearch(previous, symbol); !found {
+			added = append(added, symbol)
+		}
+	}
+	for _, symbol := range previous {
+		if _, found := slices.BinarySearch(current, symbol); !found {
+			removed = append(removed, symbol)
+		}
+	}
+	return
+}
+
+// dependencyRequirements resolves the C dependencies of the package,
+// with the versions of their llpkgs required by go.mod in the directory.
+func dependencyRequirements(uc *upstream.Upstream, dir string) ([]Requirement, error) {
+	deps, err := uc.Installer.Dependency(uc.Pkg)
+	if err != nil {
+		return nil, err
+	}
+	required := map[string]string{}
+	fileName := filepath.Join(dir, "go.mod")
+	if data, err := os.ReadFile(fileName); err == nil {
+		f, err := modfile.ParseLax(fileName, data, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range f.Require {
+			required[req.Mod.Path] = req.Mod.Version
+		}
+	}
+	var reqs []Requirement
+	for _, dep := range deps {
+		if dep.Name == uc.Pkg.Name {
+			continue
+		}
+		modulePath := llcppg.ModulePath(dep.Name)
+		reqs = append(reqs, Requirement{Dependency: dep, Path: modulePath, Version: required[modulePath]})
+	}
+	slices.SortFunc(reqs, func(a, b Requirement) int {
+		return strings.Compare(a.Dependency.Name, b.Dependency.Name)
+	})
+	return reqs, nil
+}
+
+// writeSymbols writes the list of symbols folded in details
+func writeSymbols(b *strings.Builder, summary string, symbols []string) {
+	if len(symbols) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n<details><summary>%s (%d)</summary>\n\n", summary, len(symbols))
+	for _, symbol := range symbols[:min(len(symbols), maxListedSymbols)] {
+		fmt.Fprintf(b, "- `%s`\n", symbol)
+	}
+	if len(symbols) > maxListedSymbols {
+		fmt.Fprintf(b, "- ... and %d more\n", len(symbols)-maxListedSymbols)
+	}
+	b.WriteString("\n</details>\n")
+}
+
+// Markdown renders the release notes
+func (r *ReleaseNotes) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Generated from **%s %s** installed by **%s**.\n\n", r.Package, r.Version, r.Installer)
+	if r.PreviousVersion == "" {
+		fmt.Fprintf(&b, "This is the first release of %s.\n", r.Package)
+	} else {
+		fmt.Fprintf(&b, "Previous version: `%s/%s`\n", r.Package, r.PreviousVersion)
+	}
+
+	b.WriteString("\n### Dependencies\n\n")
+	switch {
+	case r.DependencyErr != nil:
+		fmt.Fprintf(&b, "Dependencies cannot be resolved:\n\n```\n%s\n```\n", strings.TrimSpace(r.DependencyErr.Error()))
+	case len(r.Dependencies) == 0:
+		b.WriteString("None.\n")
+	default:
+		b.WriteString("| C library | Version | llpkg |\n|-----------|---------|-------|\n")
+		for _, req := range r.Dependencies {
+			llpkg := "-"
+			if req.Version != "" {
+				llpkg = fmt.Sprintf("`%s %s`", req.Path, req.Version)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", req.Dependency.Name, req.Dependency.Version, llpkg)
+		}
+	}
+
+	b.WriteString("\n### Go API\n\n")
+	switch {
+	case r.SymbolsErr != nil:
+		fmt.Fprintf(&b, "Exported symbols cannot be compared:\n\n```\n%s\n```\n", strings.TrimSpace(r.SymbolsErr.Error()))
+	case r.PreviousVersion == "":
+		fmt.Fprintf(&b, "%d exported symbols.\n", r.Symbols)
+	case len(r.Added) == 0 && len(r.Removed) == 0:
+		fmt.Fprintf(&b, "%d exported symbols, no change since %s.\n", r.Symbols, r.PreviousVersion)
+	default:
+		fmt.Fprintf(&b, "%d exported symbols, %d added and %d removed since %s.\n",
+			r.Symbols, len(r.Added), len(r.Removed), r.PreviousVersion)
+		writeSymbols(&b, "Added", r.Added)
+		writeSymbols(&b, "Removed", r.Removed)
+	}
+
+	if len(r.Checksums) > 0 {
+		b.WriteString("\n### Checksums\n\n| File | SHA-256 |\n|------|---------|\n")
+		fileNames := make([]string, 0, len(r.Checksums))
+		for fileName := range r.Checksums {
+			fileNames = append(fileNames, fileName)
+		}
+		slices.Sort(fileNames)
+		for _, fileName := range fileNames {
+			fmt.Fprintf(&b, "| %s | `%s` |\n", fileName, r.Checksums[fileName])
+		}
+	}
+	return b.String()
+}
+
+// NewReleaseNotes describes the mapped version of the package in the directory of the repository,
+// the exported symbols are compared to the tag of the previous mapped version in llpkgstore.json.
+// Failures to resolve dependencies or compare symbols are noted instead of returned,
+// since they shouldn't block the release.
+func NewReleaseNotes(repo LocalOptions, ver *versions.Versions, uc *upstream.Upstream, installer, dir, mappedVersion string) *ReleaseNotes {
+	clib := uc.Pkg.Name
+	notes := &ReleaseNotes{
+		Package:         clib,
+		MappedVersion:   mappedVersion,
+		Installer:       installer,
+		Version:         uc.Pkg.Version,
+		PreviousVersion: previousMappedVersion(ver, clib, mappedVersion),
+	}
+	pkgDir := filepath.Join(repo.RepoDir, dir)
+	notes.Dependencies, notes.DependencyErr = dependencyRequirements(uc, pkgDir)
+
+	current, err := packageSymbols(pkgDir)
+	if err != nil {
+		notes.SymbolsErr = err
+		return notes
+	}
+	notes.Symbols = len(current)
+	if notes.PreviousVersion == "" {
+		return notes
+	}
+	previous, err := packageSymbolsAt(repo, tagRef(clib+"/"+notes.PreviousVersion), dir)
+	if err != nil {
+		notes.SymbolsErr = err
+		return notes
+	}
+	notes.Added, notes.Removed = diffSymbols(previous, current)
+	return notes
+}
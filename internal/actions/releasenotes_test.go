@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+const cjsonV1 = `package cjson
+
+import "github.com/goplus/lib/c"
+
+type Json struct{ Next *Json }
+
+type hooks struct{}
+
+const CJSON_VERSION_MAJOR = 1
+
+var Version, minor = 1, 7
+
+//go:linkname Parse C.cJSON_Parse
+func Parse(value *c.Char) *Json
+
+func (o *Json) Print() *c.Char { return nil }
+
+func (o *hooks) Init() {}
+
+func internal() {}
+`
+
+func TestExportedSymbols(t *testing.T) {
+	symbols, err := exportedSymbols(map[string][]byte{
+		"cjson.go":      []byte(cjsonV1),
+		"cjson_test.go": []byte("package cjson\n\nfunc TestParse() {}\n"),
+		"llcppg.pub":    []byte("cJSON Json"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"CJSON_VERSION_MAJOR", "Json", "Json.Print", "Parse", "Version"}
+	if !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("Expected %v, got %v", expected, symbols)
+	}
+
+	added, removed := diffSymbols(expected, []string{"Json", "Json.Print", "Minify", "Parse", "Version"})
+	if !reflect.DeepEqual(added, []string{"Minify"}) || !reflect.DeepEqual(removed, []string{"CJSON_VERSION_MAJOR"}) {
+		t.Errorf("unexpected diff: added %v, removed %v", added, removed)
+	}
+}
+
+func TestPreviousMappedVersion(t *testing.T) {
+	ver, _ := versions.Read(filepath.Join(t.TempDir(), "llpkgstore.json"))
+	ver.Add("cjson", "1.7.17", "v1.0.0")
+	ver.Add("cjson", "1.7.18", "v1.1.0")
+	ver.Add("cjson", "1.7.17", "v1.0.1")
+	ver.Add("cjson", "1.7.19", "v1.2.0")
+
+	tests := map[string]string{"v1.0.0": "", "v1.0.1": "v1.0.0", "v1.1.0": "v1.0.1", "v1.3.0": "v1.2.0"}
+	for mappedVersion, expected := range tests {
+		if got := previousMappedVersion(ver, "cjson", mappedVersion); got != expected {
+			t.Errorf("Expected previous version of %s is %q, got %q", mappedVersion, expected, got)
+		}
+	}
+}
+
+func TestNewReleaseNotes(t *testing.T) {
+	repoDir := t.TempDir()
+	if ret, err := exec.Command("git", "init", "-q", "-b", "main", repoDir).CombinedOutput(); err != nil {
+		t.Skipf("git is not available: %s", ret)
+	}
+	git := func(args ...string) {
+		args = append([]string{"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		if ret, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, ret)
+		}
+	}
+	pkgDir := filepath.Join(repoDir, "cjson")
+	os.MkdirAll(pkgDir, 0755)
+	os.WriteFile(filepath.Join(pkgDir, "cjson.go"), []byte(cjsonV1), 0644)
+	git("add", "-A")
+	git("commit", "-q", "-m", "feat: add cjson")
+	git("tag", "cjson/v1.0.0")
+
+	// Version is removed and Minify is added by the new version
+	v2 := strings.Replace(cjsonV1, "var Version, minor = 1, 7", "func Minify(json *c.Char)", 1)
+	os.WriteFile(filepath.Join(pkgDir, "cjson.go"), []byte(v2), 0644)
+	os.WriteFile(filepath.Join(pkgDir, "go.mod"), []byte("module github.com/goplus/llpkg/cjson\n\ngo 1.20\n\nrequire github.com/goplus/llpkg/zlib v1.0.0\n"), 0644)
+
+	ver, _ := versions.Read(filepath.Join(repoDir, "llpkgstore.json"))
+	ver.Add("cjson", "1.7.18", "v1.0.0")
+	installer := &fakeInstaller{deps: map[string][]upstream.Package{
+		"cjson": {{Name: "zlib", Version: "1.3.1"}, {Name: "libiconv", Version: "1.17"}},
+	}}
+	uc := &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: "cjson", Version: "1.7.19"}}
+
+	notes := NewReleaseNotes(LocalOptions{RepoDir: repoDir}, ver, uc, "conan", "cjson", "v1.1.0")
+	if notes.DependencyErr != nil || notes.SymbolsErr != nil {
+		t.Fatal(notes.DependencyErr, notes.SymbolsErr)
+	}
+	if notes.PreviousVersion != "v1.0.0" {
+		t.Errorf("Expected previous version v1.0.0, got %s", notes.PreviousVersion)
+	}
+	if !reflect.DeepEqual(notes.Added, []string{"Minify"}) || !reflect.DeepEqual(notes.Removed, []string{"Version"}) {
+		t.Errorf("unexpected diff: added %v, removed %v", notes.Added, notes.Removed)
+	}
+	notes.Checksums = map[string]string{"cjson_linux_amd64.zip": "abc", "cjson_darwin_arm64.zip": "def"}
+
+	markdown := notes.Markdown()
+	for _, expected := range []string{
+		"Generated from **cjson 1.7.19** installed by **conan**.",
+		"Previous version: `cjson/v1.0.0`",
+		"| libiconv | 1.17 | - |\n| zlib | 1.3.1 | `github.com/goplus/llpkg/zlib v1.0.0` |",
+		"5 exported symbols, 1 added and 1 removed since v1.0.0.",
+		"<summary>Added (1)</summary>\n\n- `Minify`",
+		"<summary>Removed (1)</summary>\n\n- `Version`",
+		"| cjson_darwin_arm64.zip | `def` |\n| cjson_linux_amd64.zip | `abc` |",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Expected %q in release notes:\n%s", expected, markdown)
+		}
+	}
+}
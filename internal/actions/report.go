@@ -0,0 +1,219 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// reportMarker identifies the comment of reports in a pull request
+	reportMarker = "<!-- llpkgstore:report -->"
+	reportHeader = "## llpkgstore report\n" + reportMarker + "\n"
+	// maxDetails is the max length of details of a section
+	maxDetails = 8000
+	// maxComment is the max length of the report comment in bytes,
+	// which is under the limit of GitHub (65536 characters) since a character is at least a byte.
+	maxComment = 65000
+)
+
+// ReportSection is the result of a check in the report
+type ReportSection struct {
+	Title string
+	// Err is nil if the check passed
+	Err error
+	// Details is preformatted text shown under the result, e.g. files of the binary zip
+	Details     string
+	Annotations []Annotation
+}
+
+// Report is the result of checks made by a step (e.g. verification) on a pull request,
+// which is published as a section of the report comment, and a check run.
+type Report struct {
+	// Step is the name of the step making the checks, e.g. verification
+	Step     string
+	Sections []ReportSection
+}
+
+// NewReport returns an empty report of the step
+func NewReport(step string) *Report {
+	return &Report{Step: step}
+}
+
+// Add adds the result of a check, and returns err for convenience.
+func (r *Report) Add(title string, err error, details string, annotations ...Annotation) error {
+	r.Sections = append(r.Sections, ReportSection{
+		Title:       title,
+		Err:         err,
+		Details:     details,
+		Annotations: annotations,
+	})
+	return err
+}
+
+// Failed reports whether any of the checks failed
+func (r *Report) Failed() bool {
+	for _, section := range r.Sections {
+		if section.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotations returns annotations of all the checks
+func (r *Report) Annotations() []Annotation {
+	var ret []Annotation
+	for _, section := range r.Sections {
+		ret = append(ret, section.Annotations...)
+	}
+	return ret
+}
+
+// title summarizes the result in a line
+func (r *Report) title() string {
+	failed := 0
+	for _, section := range r.Sections {
+		if section.Err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return fmt.Sprintf("%s passed", r.Step)
+	}
+	return fmt.Sprintf("%s failed: %d of %d check(s)", r.Step, failed, len(r.Sections))
+}
+
+// cutString cuts s to at most n bytes, without splitting a UTF-8 character
+func cutString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// truncate cuts s to limit
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return cutString(s, limit) + "\n... (truncated)"
+}
+
+// Markdown renders the report as a block of the report comment
+func (r *Report) Markdown() string {
+	return r.markdown(maxDetails)
+}
+
+// markdown renders the report with details of each section cut to detailsLimit,
+// details are omitted if detailsLimit is 0.
+func (r *Report) markdown(detailsLimit int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!-- llpkgstore:step:%s -->\n", r.Step)
+	status := ":white_check_mark:"
+	if r.Failed() {
+		status = ":x:"
+	}
+	fmt.Fprintf(&b, "### %s %s\n\n", status, r.title())
+	b.WriteString("| Check | Result |\n|-------|--------|\n")
+	for _, section := range r.Sections {
+		result := ":white_check_mark: passed"
+		if section.Err != nil {
+			result = ":x: failed"
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", section.Title, result)
+	}
+	for _, section := range r.Sections {
+		details := section.Details
+		if section.Err != nil {
+			details = strings.TrimSpace(section.Err.Error() + "\n" + details)
+		}
+		if details == "" || detailsLimit == 0 {
+			continue
+		}
+		lang := ""
+		if strings.Contains(details, "\n@@ ") {
+			lang = "diff"
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n````%s\n%s\n````\n\n</details>\n",
+			section.Title, lang, truncate(details, detailsLimit))
+	}
+	fmt.Fprintf(&b, "<!-- llpkgstore:step:%s:end -->\n", r.Step)
+	return b.String()
+}
+
+// mergeReport replaces the block of the report in the report comment body,
+// the block is appended if the step hasn't reported yet.
+// Details of the block are cut further to keep the body within maxComment,
+// and the block is truncated at last if the checks alone don't fit.
+func mergeReport(body string, report *Report) string {
+	end := fmt.Sprintf("<!-- llpkgstore:step:%s:end -->\n", report.Step)
+	prefix, suffix := reportHeader, ""
+	if strings.Contains(body, reportMarker) {
+		start := fmt.Sprintf("<!-- llpkgstore:step:%s -->\n", report.Step)
+		i := strings.Index(body, start)
+		j := strings.Index(body, end)
+		if i < 0 || j < i {
+			prefix = strings.TrimSuffix(body, "\n") + "\n"
+		} else {
+			prefix, suffix = body[:i], body[j+len(end):]
+		}
+	}
+
+	budget := maxComment - len(prefix) - len(suffix)
+	block := report.Markdown()
+	for limit := maxDetails / 2; len(block) > budget && limit > 0; limit /= 2 {
+		block = report.markdown(limit)
+	}
+	if len(block) > budget {
+		block = report.markdown(0)
+	}
+	if len(block) > budget {
+		// keep the end marker, so that the block is replaced by the next report
+		const note = "\n... (truncated)\n"
+		block = cutString(block, max(budget-len(note)-len(end), 0)) + note + end
+	}
+	return prefix + block + suffix
+}
+
+// PublishReport publishes the report as a section of the report comment of current pull request,
+// which is created on the first report and edited then, and as a check run with annotations.
+// Nothing is published if it's not triggered by a pull request.
+func (d *DefaultClient) PublishReport(report *Report) error {
+	pullRequest, err := d.ci.PullRequest()
+	if err != nil || pullRequest == nil {
+		return err
+	}
+	ctx := context.TODO()
+	comments, err := d.host.Comments(ctx, pullRequest.Number)
+	if err != nil {
+		return err
+	}
+	var commentErr error
+	found := false
+	for _, comment := range comments {
+		if strings.Contains(comment.Body, reportMarker) {
+			found = true
+			commentErr = d.host.EditComment(ctx, comment.ID, mergeReport(comment.Body, report))
+			break
+		}
+	}
+	if !found {
+		commentErr = d.host.CreateComment(ctx, pullRequest.Number, mergeReport("", report))
+	}
+
+	checkErr := d.host.CreateCheckRun(ctx, CheckRun{
+		Name:        "llpkgstore " + report.Step,
+		HeadSHA:     pullRequest.HeadSHA,
+		Success:     !report.Failed(),
+		Title:       report.title(),
+		Summary:     report.Markdown(),
+		Annotations: report.Annotations(),
+	})
+	return errors.Join(commentErr, checkErr)
+}
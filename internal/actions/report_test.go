@@ -0,0 +1,145 @@
+package actions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReportMarkdown(t *testing.T) {
+	report := NewReport("verification")
+	report.Add("cjson: config", nil, "")
+	report.Add("cjson: check generated files", errors.New("file not equal: cJSON.go"),
+		"diff --git a/cJSON.go b/cJSON.go\n@@ -3,1 +3,1 @@\n-a\n+b",
+		Annotation{Path: "cjson/cJSON.go", Line: 3, Level: AnnotationFailure})
+
+	if !report.Failed() {
+		t.Error("Expected failed")
+	}
+	if annotations := report.Annotations(); len(annotations) != 1 || annotations[0].Path != "cjson/cJSON.go" {
+		t.Errorf("unexpected annotations: %v", annotations)
+	}
+	markdown := report.Markdown()
+	for _, expected := range []string{
+		"<!-- llpkgstore:step:verification -->\n",
+		"### :x: verification failed: 1 of 2 check(s)",
+		"| cjson: config | :white_check_mark: passed |",
+		"| cjson: check generated files | :x: failed |",
+		"<details><summary>cjson: check generated files</summary>\n\n````diff\nfile not equal: cJSON.go\ndiff --git",
+		"<!-- llpkgstore:step:verification:end -->\n",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, markdown)
+		}
+	}
+	// passed checks without details are not expanded
+	if strings.Contains(markdown, "<summary>cjson: config</summary>") {
+		t.Errorf("unexpected details:\n%s", markdown)
+	}
+
+	report = NewReport("demotest")
+	report.Add("cjson: demo", nil, strings.Repeat("x", maxDetails*2))
+	markdown = report.Markdown()
+	if !strings.Contains(markdown, "### :white_check_mark: demotest passed") || !strings.Contains(markdown, "... (truncated)") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+}
+
+func TestMergeReport(t *testing.T) {
+	verification := NewReport("verification")
+	verification.Add("cjson: config", errors.New("invalid"), "")
+	demotest := NewReport("demotest")
+	demotest.Add("cjson: demo", nil, "")
+
+	body := mergeReport("", verification)
+	if body != reportHeader+verification.Markdown() {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+	body = mergeReport(body, demotest)
+	if body != reportHeader+verification.Markdown()+demotest.Markdown() {
+		t.Errorf("Expected demotest appended, got:\n%s", body)
+	}
+
+	// the block of the step is replaced
+	verification = NewReport("verification")
+	verification.Add("cjson: config", nil, "")
+	body = mergeReport(body, verification)
+	if body != reportHeader+verification.Markdown()+demotest.Markdown() {
+		t.Errorf("Expected verification replaced, got:\n%s", body)
+	}
+
+	// many large sections are cut to fit the comment
+	verification = NewReport("verification")
+	for i := 0; i < 20; i++ {
+		verification.Add("cjson: check generated files", errors.New("file not equal"), strings.Repeat("差", maxDetails))
+	}
+	body = mergeReport(body, verification)
+	if len(body) > maxComment || !utf8.ValidString(body) {
+		t.Errorf("Expected valid body within %d bytes, got %d bytes", maxComment, len(body))
+	}
+	if !strings.Contains(body, "<details>") || !strings.HasSuffix(body, demotest.Markdown()) {
+		t.Errorf("Expected details cut and demotest kept, got:\n%s", body)
+	}
+
+	// the checks alone don't fit
+	verification = NewReport("verification")
+	for i := 0; i < 2000; i++ {
+		verification.Add(strings.Repeat("检", 20), nil, "")
+	}
+	body = mergeReport(body, verification)
+	if len(body) > maxComment || !utf8.ValidString(body) || !strings.Contains(body, "<!-- llpkgstore:step:verification:end -->") {
+		t.Errorf("Expected valid body within %d bytes with the end marker, got %d bytes", maxComment, len(body))
+	}
+	// still replaced by the next report
+	verification = NewReport("verification")
+	verification.Add("cjson: config", nil, "")
+	body = mergeReport(body, verification)
+	if body != reportHeader+verification.Markdown()+demotest.Markdown() {
+		t.Errorf("Expected verification replaced, got:\n%s", body)
+	}
+}
+
+func TestPublishReport(t *testing.T) {
+	fake, host := newGiteaTestHost(t)
+	fake.comments[1] = [2]string{"1", "LGTM"}
+	client := NewClient(host, &fakeCIEnv{pullRequest: &PullRequest{Number: 1, HeadSHA: "sha1"}})
+
+	verification := NewReport("verification")
+	verification.Add("cjson: config", errors.New("invalid"), "")
+	if err := client.PublishReport(verification); err != nil {
+		t.Fatal(err)
+	}
+	demotest := NewReport("demotest")
+	demotest.Add("cjson: demo", nil, "")
+	if err := client.PublishReport(demotest); err != nil {
+		t.Fatal(err)
+	}
+	verification = NewReport("verification")
+	verification.Add("cjson: config", nil, "")
+	if err := client.PublishReport(verification); err != nil {
+		t.Fatal(err)
+	}
+
+	// a single comment is updated
+	if len(fake.comments) != 2 {
+		t.Fatalf("Expected 2 comments, got %v", fake.comments)
+	}
+	expected := reportHeader + verification.Markdown() + demotest.Markdown()
+	if fake.comments[2] != [2]string{"1", expected} {
+		t.Errorf("Expected %q, got %q", expected, fake.comments[2][1])
+	}
+	if fake.statuses["sha1"]["llpkgstore verification"] != "success" ||
+		fake.statuses["sha1"]["llpkgstore demotest"] != "success" {
+		t.Errorf("unexpected statuses: %v", fake.statuses)
+	}
+
+	// nothing is published if it's not a pull request
+	client = NewClient(host, &fakeCIEnv{})
+	if err := client.PublishReport(verification); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.comments) != 2 {
+		t.Errorf("Expected no comment, got %v", fake.comments)
+	}
+}
@@ -0,0 +1,136 @@
+package actions
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/generator/llcppg"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+	"golang.org/x/mod/modfile"
+)
+
+var (
+	ErrNoLLPkg             = errors.New("actions: dependency has no llpkg")
+	ErrIncomparableVersion = errors.New("actions: dependency version can't be compared with mapped C versions")
+)
+
+// Requirement is the llpkg module required for a C dependency
+type Requirement struct {
+	Dependency upstream.Package
+	// Path is the module path of the llpkg, e.g. github.com/goplus/llpkg/zlib
+	Path string
+	// Version is the mapped version of the llpkg, e.g. v1.0.0
+	Version string
+}
+
+// resolveRequirement finds the best matching Go version of the llpkg for the dependency:
+// the latest Go version mapped from the same C version, or the one mapped from the smallest newer C version,
+// which are compared by the version scheme recorded for the llpkg.
+func resolveRequirement(ctx context.Context, mgr metadata.Manager, dep upstream.Package) (Requirement, error) {
+	exists, err := mgr.ModuleExists(ctx, dep.Name)
+	if err != nil {
+		return Requirement{}, err
+	}
+	if !exists {
+		return Requirement{}, fmt.Errorf("%w: %s %s is required, please create the llpkg %s first, e.g. submit a PR adding %s/llpkg.cfg with version %s",
+			ErrNoLLPkg, dep.Name, dep.Version, dep.Name, dep.Name, dep.Version)
+	}
+	req := Requirement{Dependency: dep, Path: llcppg.ModulePath(dep.Name)}
+
+	req.Version, err = mgr.LatestGoVerFromCVer(ctx, dep.Name, dep.Version)
+	if err == nil {
+		return req, nil
+	}
+	// other errors, e.g. failing to fetch llpkgstore.json, don't mean there's no llpkg
+	if !errors.Is(err, metadata.ErrNoVersionMapping) {
+		return Requirement{}, err
+	}
+
+	info, err := mgr.MetadataByName(ctx, dep.Name)
+	if err != nil {
+		return Requirement{}, err
+	}
+	comparator, err := versions.ComparatorOf(info.VersionScheme)
+	if err != nil {
+		return Requirement{}, wrapActionError(err)
+	}
+	if !comparator.IsValid(dep.Version) {
+		return Requirement{}, fmt.Errorf("%w: %s %s is required, but it doesn't follow the version scheme %s of llpkg %s",
+			ErrIncomparableVersion, dep.Name, dep.Version, cmp.Or(info.VersionScheme, versions.SchemeSemver), dep.Name)
+	}
+	var newer []string
+	for cversion := range info.Versions {
+		if comparator.IsValid(cversion) && comparator.Compare(cversion, dep.Version) > 0 {
+			newer = append(newer, cversion)
+		}
+	}
+	slices.SortFunc(newer, comparator.Compare)
+	for _, cversion := range newer {
+		req.Version, err = mgr.LatestGoVerFromCVer(ctx, dep.Name, cversion)
+		if err == nil {
+			return req, nil
+		}
+		// all the Go versions of the C version are yanked, try the next one
+		if !errors.Is(err, metadata.ErrNoVersionMapping) {
+			return Requirement{}, err
+		}
+	}
+	return Requirement{}, fmt.Errorf("%w: %s %s is required, but no llpkg %s maps C version %s or newer, please submit a PR mapping it first",
+		ErrNoLLPkg, dep.Name, dep.Version, dep.Name, dep.Version)
+}
+
+// ResolveRequirements resolves the C dependencies of the package to llpkg module requirements by the metadata manager,
+// unreleased maps module paths of llpkgs not in the metadata yet to their versions, e.g. updated by the same PR.
+// All the dependencies without llpkg are reported by an error wrapping ErrNoLLPkg.
+func ResolveRequirements(ctx context.Context, mgr metadata.Manager, pkg upstream.Package, deps []upstream.Package, unreleased map[string]string) ([]Requirement, error) {
+	var reqs []Requirement
+	var errs []error
+	for _, dep := range deps {
+		if dep.Name == pkg.Name {
+			continue
+		}
+		if version, ok := unreleased[llcppg.ModulePath(dep.Name)]; ok {
+			reqs = append(reqs, Requirement{Dependency: dep, Path: llcppg.ModulePath(dep.Name), Version: version})
+			continue
+		}
+		req, err := resolveRequirement(ctx, mgr, dep)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, errors.Join(errs...)
+}
+
+// WriteRequirements adds the requirements to go.mod in the directory,
+// the version of a module already required is replaced.
+func WriteRequirements(dir string, reqs []Requirement) error {
+	fileName := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return wrapActionError(err)
+	}
+	f, err := modfile.Parse(fileName, data, nil)
+	if err != nil {
+		return wrapActionError(err)
+	}
+	for _, req := range reqs {
+		if err := f.AddRequire(req.Path, req.Version); err != nil {
+			return wrapActionError(err)
+		}
+	}
+	f.Cleanup()
+	data, err = f.Format()
+	if err != nil {
+		return wrapActionError(err)
+	}
+	return wrapActionError(os.WriteFile(fileName, data, 0644))
+}
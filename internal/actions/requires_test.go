@@ -0,0 +1,120 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+func TestResolveRequirements(t *testing.T) {
+	mgr := metadata.NewMemoryManager(metadata.MetadataMap{
+		"zlib": {Versions: map[metadata.CVersion][]metadata.GoVersion{
+			"1.2.13": {"v1.0.0"},
+			"1.3.1":  {"v1.1.0", "v1.1.1"},
+		}},
+		"libiconv": {Versions: map[metadata.CVersion][]metadata.GoVersion{
+			"1.16": {"v0.1.0"},
+			"1.18": {"v0.2.0"},
+			"1.19": {"v0.3.0"},
+		}},
+		"sqlite3": {VersionScheme: "sqlite", Versions: map[metadata.CVersion][]metadata.GoVersion{
+			"3450100": {"v1.0.0"},
+			"3460000": {"v1.1.0"},
+			"3470000": {"v1.2.0"},
+		}},
+	})
+	pkg := upstream.Package{Name: "libxml2", Version: "2.13.6"}
+	reqs, err := ResolveRequirements(context.Background(), mgr, pkg, []upstream.Package{
+		{Name: "zlib", Version: "1.3.1"},
+		// 1.17 isn't mapped, the smallest newer one is used
+		{Name: "libiconv", Version: "1.17"},
+		// compared by the recorded version scheme
+		{Name: "sqlite3", Version: "3450200"},
+		{Name: "libxml2", Version: "2.13.6"},
+		// updated by the same PR
+		{Name: "xz", Version: "5.4.5"},
+	}, map[string]string{"github.com/goplus/llpkg/xz": "v0.1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Requirement{
+		{Dependency: upstream.Package{Name: "zlib", Version: "1.3.1"}, Path: "github.com/goplus/llpkg/zlib", Version: "v1.1.1"},
+		{Dependency: upstream.Package{Name: "libiconv", Version: "1.17"}, Path: "github.com/goplus/llpkg/libiconv", Version: "v0.2.0"},
+		{Dependency: upstream.Package{Name: "sqlite3", Version: "3450200"}, Path: "github.com/goplus/llpkg/sqlite3", Version: "v1.1.0"},
+		{Dependency: upstream.Package{Name: "xz", Version: "5.4.5"}, Path: "github.com/goplus/llpkg/xz", Version: "v0.1.0"},
+	}
+	if !reflect.DeepEqual(reqs, expected) {
+		t.Errorf("Expected %v, got %v", expected, reqs)
+	}
+
+	_, err = ResolveRequirements(context.Background(), mgr, pkg, []upstream.Package{
+		{Name: "zlib", Version: "1.4.0"},
+		{Name: "icu", Version: "74.1"},
+	}, nil)
+	if !errors.Is(err, ErrNoLLPkg) {
+		t.Fatalf("Expected ErrNoLLPkg, got %v", err)
+	}
+	for _, expected := range []string{"no llpkg zlib maps C version 1.4.0", "please create the llpkg icu first"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in %v", expected, err)
+		}
+	}
+
+	_, err = ResolveRequirements(context.Background(), mgr, pkg, []upstream.Package{{Name: "sqlite3", Version: "3.45.2"}}, nil)
+	if !errors.Is(err, ErrIncomparableVersion) || errors.Is(err, ErrNoLLPkg) {
+		t.Errorf("Expected ErrIncomparableVersion, got %v", err)
+	}
+}
+
+// failingManager is a metadata manager failing to fetch llpkgstore.json
+type failingManager struct {
+	metadata.Manager
+}
+
+func (failingManager) LatestGoVerFromCVer(context.Context, string, string) (string, error) {
+	return "", errors.New("error building cache: no mirror available")
+}
+
+func TestResolveRequirementsFetchError(t *testing.T) {
+	mgr := failingManager{metadata.NewMemoryManager(metadata.MetadataMap{
+		"zlib": {Versions: map[metadata.CVersion][]metadata.GoVersion{"1.3.1": {"v1.1.0"}}},
+	})}
+	pkg := upstream.Package{Name: "libxml2", Version: "2.13.6"}
+	_, err := ResolveRequirements(context.Background(), mgr, pkg, []upstream.Package{{Name: "zlib", Version: "1.3.1"}}, nil)
+	if err == nil || errors.Is(err, ErrNoLLPkg) {
+		t.Fatalf("Expected the fetch error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "no mirror available") {
+		t.Errorf("Expected the fetch error, got %v", err)
+	}
+}
+
+func TestWriteRequirements(t *testing.T) {
+	dir := t.TempDir()
+	gomod := "module github.com/goplus/llpkg/libxml2\n\ngo 1.20\n\nrequire github.com/goplus/llpkg/zlib v1.0.0\n"
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644)
+
+	err := WriteRequirements(dir, []Requirement{
+		{Path: "github.com/goplus/llpkg/zlib", Version: "v1.1.1"},
+		{Path: "github.com/goplus/llpkg/libiconv", Version: "v0.2.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "go.mod"))
+	for _, expected := range []string{"github.com/goplus/llpkg/zlib v1.1.1", "github.com/goplus/llpkg/libiconv v0.2.0"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, data)
+		}
+	}
+	if strings.Contains(string(data), "v1.0.0") {
+		t.Errorf("Expected the old version replaced:\n%s", data)
+	}
+}
@@ -0,0 +1,178 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxRetries is the max number of retries of a request
+	maxRetries = 5
+	// retryBaseDelay is the delay before the first retry on server errors, doubled for each retry
+	retryBaseDelay = time.Second
+	// maxRateLimitWait is the max time to wait for the rate limit to reset,
+	// the primary rate limit of GitHub resets every hour.
+	maxRateLimitWait = time.Hour
+	// secondaryRateLimitWait is the time to wait if a secondary rate limit is hit without Retry-After:
+	// https://docs.github.com/en/rest/using-the-rest-api/best-practices-for-using-the-rest-api#handle-rate-limit-errors-appropriately
+	secondaryRateLimitWait = time.Minute
+)
+
+// retryTransport is http.RoundTripper retrying requests on server errors with exponential backoff,
+// and waiting for the rate limit to reset when it's hit.
+// Each attempt is bound to apiTimeout, including reading the response body.
+//
+// Requests with a body are retried only if the body can be replayed by GetBody.
+// Server errors are only retried for idempotent methods, since a request such as POST may have been applied,
+// e.g. a release would be created twice, while a rate limited request is never applied.
+type retryTransport struct {
+	base http.RoundTripper
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// newRetryTransport returns retryTransport sending requests by base, http.DefaultTransport is used if base is nil.
+func newRetryTransport(base http.RoundTripper) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, now: time.Now, sleep: sleepContext}
+}
+
+// newRetryClient returns http.Client sending requests by retryTransport
+func newRetryClient() *http.Client {
+	return &http.Client{Transport: newRetryTransport(nil)}
+}
+
+// sleepContext sleeps for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelBody cancels the context of the attempt when the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.roundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		wait, retry := t.retryAfter(resp, attempt, isIdempotent(req.Method))
+		if !retry || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, nil
+		}
+		// drain the body to reuse the connection
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		log.Printf("%s %s: %s, retry in %s", req.Method, req.URL.Redacted(), resp.Status, wait)
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// roundTrip sends the request once with apiTimeout
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), apiTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// isIdempotent reports whether sending the request of method more than once has the same effect as once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAfter reports whether the response should be retried, and how long to wait before that.
+// Server errors are only retried if the request is idempotent.
+func (t *retryTransport) retryAfter(resp *http.Response, attempt int, idempotent bool) (time.Duration, bool) {
+	if attempt >= maxRetries {
+		return 0, false
+	}
+	switch {
+	case resp.StatusCode >= 500:
+		if !idempotent {
+			return 0, false
+		}
+		return retryBaseDelay << attempt, true
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		// secondary rate limit
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		// primary rate limit
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			// wait one more second for clock skew
+			wait := time.Unix(reset, 0).Sub(t.now()) + time.Second
+			if wait > maxRateLimitWait {
+				return 0, false
+			}
+			return max(wait, 0), true
+		}
+		if isSecondaryRateLimit(resp) {
+			return secondaryRateLimitWait, true
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return retryBaseDelay << attempt, true
+		}
+	}
+	return 0, false
+}
+
+// isSecondaryRateLimit reports whether the forbidden response is caused by secondary rate limits,
+// the body is peeked and restored.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body := resp.Body
+	peek, _ := io.ReadAll(io.LimitReader(body, 4096))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), body), body}
+	return strings.Contains(strings.ToLower(string(peek)), "secondary rate limit")
+}
@@ -0,0 +1,254 @@
+package actions
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRetryClient returns a client recording the waits instead of sleeping
+func newTestRetryClient(now time.Time) (*http.Client, *[]time.Duration) {
+	var waits []time.Duration
+	transport := newRetryTransport(nil)
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return &http.Client{Transport: transport}, &waits
+}
+
+// failingServer responds by fail for the first n requests, then responds OK with the request body
+func failingServer(t *testing.T, n int32, fail func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= n {
+			fail(w)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryServerError(t *testing.T) {
+	server, requests := failingServer(t, 3, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client, waits := newTestRetryClient(time.Now())
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("body"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "body" {
+		t.Errorf("Expected body replayed, got %s %s", resp.Status, body)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("Expected 4 requests, got %d", requests.Load())
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if len(*waits) != len(expected) {
+		t.Fatalf("Expected waits %v, got %v", expected, *waits)
+	}
+	for i := range expected {
+		if (*waits)[i] != expected[i] {
+			t.Errorf("Expected waits %v, got %v", expected, *waits)
+		}
+	}
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	server, requests := failingServer(t, 100, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client, _ := newTestRetryClient(time.Now())
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected the last response, got %s", resp.Status)
+	}
+	if requests.Load() != maxRetries+1 {
+		t.Errorf("Expected %d requests, got %d", maxRetries+1, requests.Load())
+	}
+}
+
+func TestRetryServerErrorPost(t *testing.T) {
+	server, requests := failingServer(t, 1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client, _ := newTestRetryClient(time.Now())
+
+	// the server may have created it before failing
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"tag_name":"cjson/v1.0.0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || requests.Load() != 1 {
+		t.Errorf("Expected no retry, got %s after %d requests", resp.Status, requests.Load())
+	}
+
+	// a rate limited POST isn't applied, so it's retried
+	server, requests = failingServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	resp, err = client.Post(server.URL, "application/json", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "body" || requests.Load() != 2 {
+		t.Errorf("Expected body replayed, got %s %s after %d requests", resp.Status, body, requests.Load())
+	}
+}
+
+func TestRetryUnreplayableBody(t *testing.T) {
+	server, requests := failingServer(t, 1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client, _ := newTestRetryClient(time.Now())
+
+	// the body of a pipe can't be replayed
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("body"))
+		pw.Close()
+	}()
+	req, _ := http.NewRequest(http.MethodPut, server.URL, pr)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || requests.Load() != 1 {
+		t.Errorf("Expected no retry, got %s after %d requests", resp.Status, requests.Load())
+	}
+}
+
+func TestRetryRateLimit(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name string
+		fail func(w http.ResponseWriter)
+		wait time.Duration
+	}{
+		{
+			name: "primary",
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Unix()+30, 10))
+				w.WriteHeader(http.StatusForbidden)
+			},
+			wait: 31 * time.Second,
+		},
+		{
+			name: "secondary with Retry-After",
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wait: 7 * time.Second,
+		},
+		{
+			name: "secondary without Retry-After",
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+			},
+			wait: secondaryRateLimitWait,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := failingServer(t, 1, tt.fail)
+			client, waits := newTestRetryClient(now)
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+				t.Errorf("Expected OK after 2 requests, got %s after %d requests", resp.Status, requests.Load())
+			}
+			if len(*waits) != 1 || (*waits)[0] != tt.wait {
+				t.Errorf("Expected waiting %s, got %v", tt.wait, *waits)
+			}
+		})
+	}
+}
+
+func TestRetryForbidden(t *testing.T) {
+	const message = `{"message": "Resource not accessible by integration"}`
+	server, requests := failingServer(t, 1, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(message))
+	})
+	client, _ := newTestRetryClient(time.Now())
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusForbidden || requests.Load() != 1 {
+		t.Errorf("Expected no retry, got %s after %d requests", resp.Status, requests.Load())
+	}
+	if string(body) != message {
+		t.Errorf("Expected body kept, got %s", body)
+	}
+}
+
+func TestRetryRateLimitTooLong(t *testing.T) {
+	now := time.Now()
+	server, requests := failingServer(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(2*maxRateLimitWait).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	})
+	client, _ := newTestRetryClient(now)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || requests.Load() != 1 {
+		t.Errorf("Expected giving up, got %s after %d requests", resp.Status, requests.Load())
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	server, _ := failingServer(t, 100, func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	transport := newRetryTransport(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return sleepContext(ctx, d)
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := (&http.Client{Transport: transport}).Do(req); err == nil {
+		t.Error("Expected error when canceled")
+	}
+}
@@ -0,0 +1,495 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PengPengPeng717/llpkgstore/internal/file"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+const (
+	// sbomNamespace prefixes the SPDX document namespace
+	sbomNamespace = "https://github.com/goplus/llpkg/sbom/"
+	// sbomSuffix is appended to the binary zip name for the zip of its SBOMs, e.g. cjson_linux_amd64_sbom.zip
+	sbomSuffix = "_sbom"
+)
+
+// SBOMTool is a tool taking part in generating the binary
+type SBOMTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// SBOMFile is a file in the binary zip
+type SBOMFile struct {
+	// Path is slash separated and relative to the root of the zip
+	Path   string `json:"path"`
+	SHA1   string `json:"sha1"`
+	SHA256 string `json:"sha256"`
+}
+
+// SBOM is the software bill of materials of a binary zip, which is rendered as SPDX JSON and CycloneDX JSON.
+// The documents only depend on the fields, so the same input always produces the same documents.
+type SBOM struct {
+	// Name is the binary zip name without .zip, e.g. cjson_linux_amd64
+	Name         string             `json:"name"`
+	Installer    string             `json:"installer"`
+	Package      upstream.Package   `json:"package"`
+	Dependencies []upstream.Package `json:"dependencies"`
+	Tools        []SBOMTool         `json:"tools"`
+	Files        []SBOMFile         `json:"files"`
+	// Created is SOURCE_DATE_EPOCH if set, otherwise the Unix epoch, rather than the time of building
+	Created time.Time `json:"created"`
+}
+
+// NewSBOM collects the SBOM of the binary zip name, whose content has been installed into dir.
+// Unlike release notes, the dependencies MUST be resolved, an incomplete SBOM isn't acceptable.
+func NewSBOM(uc *upstream.Upstream, name, dir string) (*SBOM, error) {
+	deps, err := uc.Installer.Dependency(uc.Pkg)
+	if err != nil {
+		return nil, fmt.Errorf("actions: cannot resolve dependencies of %s: %w", uc.Pkg.Name, err)
+	}
+	deps = slices.Clone(deps)
+	slices.SortFunc(deps, func(a, b upstream.Package) int {
+		return strings.Compare(a.Name+"/"+a.Version, b.Name+"/"+b.Version)
+	})
+	deps = slices.Compact(deps)
+
+	files, err := sbomFiles(dir)
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	created, err := sourceDateEpoch()
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	return &SBOM{
+		Name:         name,
+		Installer:    uc.Installer.Name(),
+		Package:      uc.Pkg,
+		Dependencies: deps,
+		Tools:        sbomTools(uc.Installer.Name()),
+		Files:        files,
+		Created:      created,
+	}, nil
+}
+
+// sourceDateEpoch returns the time of SOURCE_DATE_EPOCH, see https://reproducible-builds.org/specs/source-date-epoch/
+func sourceDateEpoch() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// sbomFiles hashes the regular files in dir, sorted by path
+func sbomFiles(dir string) ([]SBOMFile, error) {
+	var files []SBOMFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h1, h256 := sha1.New(), sha256.New()
+		if _, err := io.Copy(io.MultiWriter(h1, h256), f); err != nil {
+			return err
+		}
+		files = append(files, SBOMFile{
+			Path:   filepath.ToSlash(rel),
+			SHA1:   hex.EncodeToString(h1.Sum(nil)),
+			SHA256: hex.EncodeToString(h256.Sum(nil)),
+		})
+		return nil
+	})
+	slices.SortFunc(files, func(a, b SBOMFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return files, err
+}
+
+// sbomTools returns the versions of llpkgstore, the installer and the generator,
+// the version is empty if it's unknown, e.g. the tool isn't installed.
+func sbomTools(installer string) []SBOMTool {
+	self := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		self = info.Main.Version
+	}
+	generator := "llcppg"
+	if installer == "pip" {
+		generator = "llpyg"
+	}
+	return []SBOMTool{
+		{Name: "llpkgstore", Version: self},
+		{Name: installer, Version: commandVersion(installer)},
+		{Name: generator, Version: goToolVersion(generator)},
+	}
+}
+
+// commandVersion returns the first line printed by `name --version`, e.g. Conan version 2.12.1
+func commandVersion(name string) string {
+	out, err := exec.Command(name, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSpace(line)
+}
+
+// goToolVersion returns the module version of the Go program name in PATH, e.g. v0.5.1 of llcppg
+func goToolVersion(name string) string {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return info.Main.Version
+}
+
+// digest identifies the input of the SBOM, which derives the SPDX namespace and the CycloneDX serial number
+func (s *SBOM) digest() []byte {
+	b, _ := json.Marshal(s)
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+// purl returns the package URL of the package, see https://github.com/package-url/purl-spec
+func (s *SBOM) purl(pkg upstream.Package) string {
+	typ := "generic"
+	switch s.Installer {
+	case "conan":
+		typ = "conan"
+	case "pip":
+		typ = "pypi"
+	}
+	return fmt.Sprintf("pkg:%s/%s@%s", typ, url.PathEscape(pkg.Name), url.PathEscape(pkg.Version))
+}
+
+// String formats the tool as name-version, the version is omitted if unknown
+func (t SBOMTool) String() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + "-" + t.Version
+}
+
+var invalidSPDXIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+// spdxID makes an SPDX identifier, which only consists of letters, numbers, . and -
+func spdxID(kind, name string) string {
+	return "SPDXRef-" + kind + "-" + invalidSPDXIDChars.ReplaceAllString(name, "-")
+}
+
+// noAssertion fills the required SPDX field without information
+func noAssertion(s string) string {
+	if s == "" {
+		return "NOASSERTION"
+	}
+	return s
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	SPDXID                  string            `json:"SPDXID"`
+	Name                    string            `json:"name"`
+	VersionInfo             string            `json:"versionInfo"`
+	Supplier                string            `json:"supplier"`
+	DownloadLocation        string            `json:"downloadLocation"`
+	FilesAnalyzed           bool              `json:"filesAnalyzed"`
+	PackageVerificationCode map[string]string `json:"packageVerificationCode,omitempty"`
+	LicenseConcluded        string            `json:"licenseConcluded"`
+	LicenseDeclared         string            `json:"licenseDeclared"`
+	CopyrightText           string            `json:"copyrightText"`
+	ExternalRefs            []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxFile struct {
+	SPDXID           string         `json:"SPDXID"`
+	FileName         string         `json:"fileName"`
+	Checksums        []spdxChecksum `json:"checksums"`
+	LicenseConcluded string         `json:"licenseConcluded"`
+	CopyrightText    string         `json:"copyrightText"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []spdxPackage      `json:"packages"`
+	Files         []spdxFile         `json:"files"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+// spdxPackageOf describes a package without analyzing its files
+func (s *SBOM) spdxPackageOf(pkg upstream.Package) spdxPackage {
+	return spdxPackage{
+		SPDXID:           spdxID("Package", pkg.Name),
+		Name:             pkg.Name,
+		VersionInfo:      noAssertion(pkg.Version),
+		Supplier:         "NOASSERTION",
+		DownloadLocation: "NOASSERTION",
+		LicenseConcluded: "NOASSERTION",
+		LicenseDeclared:  "NOASSERTION",
+		CopyrightText:    "NOASSERTION",
+		ExternalRefs: []spdxExternalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  s.purl(pkg),
+		}},
+	}
+}
+
+// SPDX renders the SBOM as an SPDX 2.3 JSON document, see https://spdx.github.io/spdx-spec/v2.3/
+func (s *SBOM) SPDX() ([]byte, error) {
+	var doc spdxDocument
+	doc.SPDXVersion = "SPDX-2.3"
+	doc.DataLicense = "CC0-1.0"
+	doc.SPDXID = "SPDXRef-DOCUMENT"
+	doc.Name = s.Name
+	doc.DocumentNamespace = sbomNamespace + s.Name + "-" + hex.EncodeToString(s.digest())
+	doc.CreationInfo.Created = s.Created.UTC().Format(time.RFC3339)
+	for _, tool := range s.Tools {
+		doc.CreationInfo.Creators = append(doc.CreationInfo.Creators, "Tool: "+tool.String())
+	}
+
+	main := s.spdxPackageOf(s.Package)
+	doc.Files = []spdxFile{}
+	doc.Relationships = append(doc.Relationships, spdxRelationship{doc.SPDXID, "DESCRIBES", main.SPDXID})
+
+	// https://spdx.github.io/spdx-spec/v2.3/package-information/#79-package-verification-code-field
+	verification := sha1.New()
+	for i, f := range s.Files {
+		id := spdxID("File", strconv.Itoa(i))
+		doc.Files = append(doc.Files, spdxFile{
+			SPDXID:   id,
+			FileName: "./" + f.Path,
+			Checksums: []spdxChecksum{
+				{Algorithm: "SHA1", ChecksumValue: f.SHA1},
+				{Algorithm: "SHA256", ChecksumValue: f.SHA256},
+			},
+			LicenseConcluded: "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{main.SPDXID, "CONTAINS", id})
+	}
+	sha1s := make([]string, len(s.Files))
+	for i, f := range s.Files {
+		sha1s[i] = f.SHA1
+	}
+	slices.Sort(sha1s)
+	io.WriteString(verification, strings.Join(sha1s, ""))
+	main.FilesAnalyzed = len(s.Files) > 0
+	if main.FilesAnalyzed {
+		main.PackageVerificationCode = map[string]string{
+			"packageVerificationCodeValue": hex.EncodeToString(verification.Sum(nil)),
+		}
+	}
+	doc.Packages = append(doc.Packages, main)
+
+	for _, dep := range s.Dependencies {
+		pkg := s.spdxPackageOf(dep)
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{main.SPDXID, "DEPENDS_ON", pkg.SPDXID})
+	}
+	return marshalSBOM(doc)
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxComponent struct {
+	Type    string    `json:"type"`
+	BOMRef  string    `json:"bom-ref"`
+	Name    string    `json:"name"`
+	Version string    `json:"version,omitempty"`
+	PURL    string    `json:"purl,omitempty"`
+	Hashes  []cdxHash `json:"hashes,omitempty"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+type cdxDocument struct {
+	BOMFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string `json:"timestamp"`
+		Tools     struct {
+			Components []cdxComponent `json:"components"`
+		} `json:"tools"`
+		Component cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+// CycloneDX renders the SBOM as a CycloneDX 1.5 JSON document, see https://cyclonedx.org/docs/1.5/json/
+func (s *SBOM) CycloneDX() ([]byte, error) {
+	var doc cdxDocument
+	doc.BOMFormat = "CycloneDX"
+	doc.SpecVersion = "1.5"
+	doc.Version = 1
+
+	// a name based UUID (version 5 layout) derived from the input rather than a random one
+	id := s.digest()[:16]
+	id[6] = id[6]&0x0f | 0x50
+	id[8] = id[8]&0x3f | 0x80
+	doc.SerialNumber = fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+
+	doc.Metadata.Timestamp = s.Created.UTC().Format(time.RFC3339)
+	doc.Metadata.Tools.Components = []cdxComponent{}
+	for _, tool := range s.Tools {
+		doc.Metadata.Tools.Components = append(doc.Metadata.Tools.Components, cdxComponent{
+			Type:    "application",
+			BOMRef:  "tool:" + tool.Name,
+			Name:    tool.Name,
+			Version: tool.Version,
+		})
+	}
+	main := s.purl(s.Package)
+	doc.Metadata.Component = cdxComponent{
+		Type:    "library",
+		BOMRef:  main,
+		Name:    s.Package.Name,
+		Version: s.Package.Version,
+		PURL:    main,
+	}
+
+	doc.Components = []cdxComponent{}
+	dependsOn := []string{}
+	for _, dep := range s.Dependencies {
+		ref := s.purl(dep)
+		doc.Components = append(doc.Components, cdxComponent{
+			Type:    "library",
+			BOMRef:  ref,
+			Name:    dep.Name,
+			Version: dep.Version,
+			PURL:    ref,
+		})
+		dependsOn = append(dependsOn, ref)
+	}
+	for _, f := range s.Files {
+		doc.Components = append(doc.Components, cdxComponent{
+			Type:   "file",
+			BOMRef: "file:" + f.Path,
+			Name:   f.Path,
+			Hashes: []cdxHash{
+				{Alg: "SHA-1", Content: f.SHA1},
+				{Alg: "SHA-256", Content: f.SHA256},
+			},
+		})
+	}
+	doc.Dependencies = []cdxDependency{{Ref: main, DependsOn: dependsOn}}
+	return marshalSBOM(doc)
+}
+
+// marshalSBOM encodes the document with indentation and without escaping HTML
+func marshalSBOM(doc any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sbomZip returns the file name of the zip of SBOMs for the binary zip, e.g. cjson_linux_amd64_sbom.zip
+func sbomZip(binaryZipName string) string {
+	return strings.TrimSuffix(binaryZipName, ".zip") + sbomSuffix + ".zip"
+}
+
+// isSBOMZip reports whether the asset is a zip of SBOMs rather than a binary zip
+func isSBOMZip(fileName string) bool {
+	return strings.HasSuffix(filepath.Base(fileName), sbomSuffix+".zip")
+}
+
+// WriteSBOMZip writes {Name}.spdx.json and {Name}.cdx.json into zipFilePath
+func (s *SBOM) WriteSBOMZip(zipFilePath string) error {
+	spdx, err := s.SPDX()
+	if err != nil {
+		return wrapActionError(err)
+	}
+	cdx, err := s.CycloneDX()
+	if err != nil {
+		return wrapActionError(err)
+	}
+	tempDir, err := os.MkdirTemp("", "llpkg-sbom")
+	if err != nil {
+		return wrapActionError(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.WriteFile(filepath.Join(tempDir, s.Name+".spdx.json"), spdx, 0666); err != nil {
+		return wrapActionError(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, s.Name+".cdx.json"), cdx, 0666); err != nil {
+		return wrapActionError(err)
+	}
+	if err := file.Zip(tempDir, zipFilePath); err != nil {
+		return wrapActionError(err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package actions
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+func TestNewSBOM(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lib", "pkgconfig"), 0755)
+	os.WriteFile(filepath.Join(dir, "lib", "pkgconfig", "libcjson.pc"), []byte("Name: libcjson"), 0644)
+	os.WriteFile(filepath.Join(dir, "lib", "libcjson.a"), []byte("!<arch>"), 0644)
+
+	installer := &fakeInstaller{name: "conan", deps: map[string][]upstream.Package{
+		"cjson": {{Name: "zlib", Version: "1.3.1"}, {Name: "libiconv", Version: "1.17"}, {Name: "zlib", Version: "1.3.1"}},
+	}}
+	uc := &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: "cjson", Version: "1.7.18"}}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	sbom, err := NewSBOM(uc, "cjson_linux_amd64", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDeps := []upstream.Package{{Name: "libiconv", Version: "1.17"}, {Name: "zlib", Version: "1.3.1"}}
+	if !reflect.DeepEqual(sbom.Dependencies, expectedDeps) {
+		t.Errorf("Expected dependencies %v, got %v", expectedDeps, sbom.Dependencies)
+	}
+	var paths []string
+	for _, f := range sbom.Files {
+		paths = append(paths, f.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"lib/libcjson.a", "lib/pkgconfig/libcjson.pc"}) {
+		t.Errorf("unexpected files: %v", paths)
+	}
+	if sbom.Files[1].SHA256 != "5158681140a76b5bbec2a3954b33496454f02c619cf52476d76ca0d79c788e69" {
+		t.Errorf("unexpected sha256: %s", sbom.Files[1].SHA256)
+	}
+	if sbom.Created.Unix() != 1700000000 {
+		t.Errorf("Expected SOURCE_DATE_EPOCH, got %v", sbom.Created)
+	}
+
+	// the same input produces the same documents
+	again, err := NewSBOM(uc, "cjson_linux_amd64", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spdx1, _ := sbom.SPDX()
+	spdx2, _ := again.SPDX()
+	cdx1, _ := sbom.CycloneDX()
+	cdx2, _ := again.CycloneDX()
+	if !bytes.Equal(spdx1, spdx2) || !bytes.Equal(cdx1, cdx2) {
+		t.Error("Expected reproducible SBOMs")
+	}
+
+	uc.Pkg.Name = "unknown"
+	if _, err := NewSBOM(uc, "unknown_linux_amd64", dir); err == nil {
+		t.Error("Expected error for unresolved dependencies")
+	}
+}
+
+func testSBOM() *SBOM {
+	return &SBOM{
+		Name:         "cjson_linux_amd64",
+		Installer:    "conan",
+		Package:      upstream.Package{Name: "cjson", Version: "1.7.18"},
+		Dependencies: []upstream.Package{{Name: "zlib", Version: "1.3.1"}},
+		Tools:        []SBOMTool{{Name: "llpkgstore", Version: "v0.1.0"}, {Name: "conan", Version: "Conan version 2.12.1"}, {Name: "llcppg"}},
+		Files: []SBOMFile{{
+			Path:   "lib/libcjson.a",
+			SHA1:   "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+			SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		}},
+		Created: time.Unix(0, 0).UTC(),
+	}
+}
+
+func TestSBOMSPDX(t *testing.T) {
+	b, err := testSBOM().SPDX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.CreationInfo.Created != "1970-01-01T00:00:00Z" {
+		t.Errorf("unexpected document: %s", b)
+	}
+	if !strings.HasPrefix(doc.DocumentNamespace, sbomNamespace+"cjson_linux_amd64-") {
+		t.Errorf("unexpected namespace: %s", doc.DocumentNamespace)
+	}
+	expectedCreators := []string{"Tool: llpkgstore-v0.1.0", "Tool: conan-Conan version 2.12.1", "Tool: llcppg"}
+	if !reflect.DeepEqual(doc.CreationInfo.Creators, expectedCreators) {
+		t.Errorf("Expected creators %v, got %v", expectedCreators, doc.CreationInfo.Creators)
+	}
+	if len(doc.Packages) != 2 || doc.Packages[1].ExternalRefs[0].ReferenceLocator != "pkg:conan/zlib@1.3.1" {
+		t.Errorf("unexpected packages: %v", doc.Packages)
+	}
+	// sha1 of the only sha1
+	if code := doc.Packages[0].PackageVerificationCode["packageVerificationCodeValue"]; code != "10a34637ad661d98ba3344717656fcc76209c2f8" {
+		t.Errorf("unexpected verification code: %s", code)
+	}
+	expectedRelationships := []spdxRelationship{
+		{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Package-cjson"},
+		{"SPDXRef-Package-cjson", "CONTAINS", "SPDXRef-File-0"},
+		{"SPDXRef-Package-cjson", "DEPENDS_ON", "SPDXRef-Package-zlib"},
+	}
+	if !reflect.DeepEqual(doc.Relationships, expectedRelationships) {
+		t.Errorf("Expected relationships %v, got %v", expectedRelationships, doc.Relationships)
+	}
+}
+
+func TestSBOMCycloneDX(t *testing.T) {
+	s := testSBOM()
+	b, err := s.CycloneDX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc cdxDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" || doc.Metadata.Component.PURL != "pkg:conan/cjson@1.7.18" {
+		t.Errorf("unexpected document: %s", b)
+	}
+	if len(doc.SerialNumber) != len("urn:uuid:")+36 || doc.SerialNumber[len("urn:uuid:")+14] != '5' {
+		t.Errorf("unexpected serial number: %s", doc.SerialNumber)
+	}
+	if len(doc.Components) != 2 || doc.Components[1].Hashes[1].Content != s.Files[0].SHA256 {
+		t.Errorf("unexpected components: %v", doc.Components)
+	}
+	expectedDeps := []cdxDependency{{Ref: "pkg:conan/cjson@1.7.18", DependsOn: []string{"pkg:conan/zlib@1.3.1"}}}
+	if !reflect.DeepEqual(doc.Dependencies, expectedDeps) {
+		t.Errorf("Expected dependencies %v, got %v", expectedDeps, doc.Dependencies)
+	}
+
+	// a different input has a different serial number
+	s.Package.Version = "1.7.19"
+	other, _ := s.CycloneDX()
+	var otherDoc cdxDocument
+	json.Unmarshal(other, &otherDoc)
+	if otherDoc.SerialNumber == doc.SerialNumber {
+		t.Error("Expected a different serial number")
+	}
+}
+
+func TestWriteSBOMZip(t *testing.T) {
+	zipFilePath := filepath.Join(t.TempDir(), sbomZip("cjson_linux_amd64.zip"))
+	if filepath.Base(zipFilePath) != "cjson_linux_amd64_sbom.zip" || !isSBOMZip(zipFilePath) || isSBOMZip("cjson_linux_amd64.zip") {
+		t.Errorf("unexpected SBOM zip: %s", zipFilePath)
+	}
+	if err := testSBOM().WriteSBOMZip(zipFilePath); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"cjson_linux_amd64.cdx.json", "cjson_linux_amd64.spdx.json"}) {
+		t.Errorf("unexpected files: %v", names)
+	}
+}
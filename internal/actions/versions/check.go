@@ -0,0 +1,126 @@
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"golang.org/x/mod/semver"
+)
+
+var (
+	ErrUnorderedMapping = errors.New("versions: Go versions don't increase with C versions")
+	ErrBumpingRule      = errors.New("versions: Go version violates the bumping rules")
+)
+
+// Check reports all violations of the mapping rules, it's the same as what is enforced by
+// the Github Action when publishing:
+//  1. the mapping table is well-formed, see Validate.
+//  2. C versions follow the version scheme of the C library.
+//  3. Go versions increase with C versions, that is, every Go version of a C version
+//     is greater than all the Go versions of smaller C versions.
+func (v *Versions) Check() error {
+	errs := []error{v.Validate()}
+
+	clibs := make([]string, 0, len(v.MetadataMap))
+	for clib := range v.MetadataMap {
+		clibs = append(clibs, clib)
+	}
+	sort.Strings(clibs)
+
+	for _, clib := range clibs {
+		errs = append(errs, v.checkOrder(clib))
+	}
+	return errors.Join(errs...)
+}
+
+// checkOrder reports C versions don't follow the version scheme of the C library,
+// and Go versions don't increase with C versions.
+func (v *Versions) checkOrder(clib string) error {
+	comparator, err := v.Comparator(clib)
+	if err != nil {
+		return fmt.Errorf("%s: %w", clib, err)
+	}
+	var errs []error
+	for cversion := range v.cVersions(clib) {
+		if !comparator.IsValid(cversion) {
+			errs = append(errs, fmt.Errorf("%w: %s: %s doesn't follow the version scheme", ErrInvalidMapping, clib, cversion))
+		}
+	}
+
+	ascending := v.SortedCVersions(clib, comparator)
+	slices.Reverse(ascending)
+
+	// the greatest Go version of the smaller C versions
+	var previousCVersion, previousGoVersion string
+	for _, cversion := range ascending {
+		goVersions := slices.Clone(v.cVersions(clib)[cversion])
+		semver.Sort(goVersions)
+
+		for _, goversion := range goVersions {
+			if previousGoVersion != "" && semver.Compare(goversion, previousGoVersion) <= 0 {
+				errs = append(errs, fmt.Errorf("%w: %s: %s => %s is not greater than %s => %s",
+					ErrUnorderedMapping, clib, cversion, goversion, previousCVersion, previousGoVersion))
+			}
+		}
+		if len(goVersions) > 0 && semver.Compare(goVersions[len(goVersions)-1], previousGoVersion) > 0 {
+			previousCVersion, previousGoVersion = cversion, goVersions[len(goVersions)-1]
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckBumping reports Go versions violating the bumping rules in the design doc:
+//  1. the initial version is v1.0.0 for stable C libraries or v0.1.0 otherwise.
+//  2. pre-release Go versions and C versions are not accepted.
+//  3. Go versions of the same C version differ only in PATCH, which is for llpkg's self-updating.
+//
+// It's not enforced when publishing, since history versions may not follow the rules.
+func (v *Versions) CheckBumping() error {
+	clibs := make([]string, 0, len(v.MetadataMap))
+	for clib := range v.MetadataMap {
+		clibs = append(clibs, clib)
+	}
+	sort.Strings(clibs)
+
+	var errs []error
+	for _, clib := range clibs {
+		goVersions := v.GoVersions(clib)
+		if len(goVersions) == 0 {
+			continue
+		}
+		semver.Sort(goVersions)
+		if initial := goVersions[0]; initial != "v1.0.0" && initial != "v0.1.0" {
+			errs = append(errs, fmt.Errorf("%w: %s: initial version %s is neither v1.0.0 nor v0.1.0",
+				ErrBumpingRule, clib, initial))
+		}
+
+		comparator, err := v.Comparator(clib)
+		if err != nil {
+			// report the other C libraries too
+			errs = append(errs, fmt.Errorf("%s: %w", clib, err))
+			continue
+		}
+		cversions := v.SortedCVersions(clib, comparator)
+		slices.Reverse(cversions)
+
+		for _, cversion := range cversions {
+			if _, isSemver := comparator.(semverComparator); isSemver && semver.Prerelease(ToSemVer(cversion)) != "" {
+				errs = append(errs, fmt.Errorf("%w: %s: pre-release C version %s", ErrBumpingRule, clib, cversion))
+			}
+			goVersions := slices.Clone(v.cVersions(clib)[cversion])
+			semver.Sort(goVersions)
+			for _, goVersion := range goVersions {
+				if semver.Prerelease(goVersion) != "" || semver.Build(goVersion) != "" {
+					errs = append(errs, fmt.Errorf("%w: %s: pre-release Go version %s", ErrBumpingRule, clib, goVersion))
+				}
+				if semver.MajorMinor(goVersion) != semver.MajorMinor(goVersions[0]) {
+					errs = append(errs, fmt.Errorf("%w: %s: %s => %s is not a PATCH update of %s",
+						ErrBumpingRule, clib, cversion, goVersion, goVersions[0]))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package versions
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		unordered int
+		invalid   int
+	}{
+		{"valid", `{"cjson": {"versions": {"1.7.16": ["v0.1.0"], "1.7.18": ["v0.1.1", "v0.1.2"], "1.8.18": ["v0.2.0"]}}}`, 0, 0},
+		{"legacy patch", `{"cjson": {"versions": {"1.5.7": ["v0.1.0"], "1.5.8": ["v0.1.1"], "1.6.1": ["v0.2.0"]}}}`, 0, 0},
+		{"unordered", `{"cjson": {"versions": {"1.7.16": ["v0.2.0"], "1.7.18": ["v0.1.1", "v0.3.0"], "1.8.18": ["v0.1.0"]}}}`, 2, 0},
+		{"sqlite", `{"sqlite3": {"versionScheme": "sqlite", "versions": {"3450100": ["v0.2.0"], "3460000": ["v0.1.0"], "3.47": ["v0.3.0"]}}}`, 1, 1},
+	}
+	path := "ttt.json"
+	defer os.Remove(path)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			v, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var unordered, invalid int
+			if err := v.Check(); err != nil {
+				for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+					for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+						switch {
+						case errors.Is(err, ErrUnorderedMapping):
+							unordered++
+						case errors.Is(err, ErrInvalidMapping):
+							invalid++
+						default:
+							t.Errorf("unexpected error: %v", err)
+						}
+					}
+				}
+			}
+			if unordered != tc.unordered || invalid != tc.invalid {
+				t.Errorf("Expected %d unordered and %d invalid, got %d and %d: %v",
+					tc.unordered, tc.invalid, unordered, invalid, v.Check())
+			}
+		})
+	}
+}
+
+func TestCheckBumping(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		violations int
+	}{
+		{"valid", `{"cjson": {"versions": {"1.7.18": ["v1.0.0", "v1.0.1"], "1.7.19": ["v1.1.0"], "2.0.0": ["v2.0.0"]}}}`, 0},
+		{"unstable", `{"libass": {"versions": {"0.17.3": ["v0.1.0"]}}}`, 0},
+		{"initial version", `{"cjson": {"versions": {"1.7.18": ["v1.1.0"]}}}`, 1},
+		{"not patch", `{"cjson": {"versions": {"1.7.18": ["v1.0.0", "v1.1.0"]}}}`, 1},
+		{"pre-release", `{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.8.0-beta.2": ["v1.1.0-rc.1"]}}}`, 2},
+		{"unknown scheme", `{"cjson": {"versions": {"1.7.18": ["v1.1.0"]}}, "foo": {"versionScheme": "unknown", "versions": {"1.0": ["v1.0.0"]}}}`, 2},
+	}
+	path := "ttt.json"
+	defer os.Remove(path)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			v, err := Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var violations int
+			if err := v.CheckBumping(); err != nil {
+				if !errors.Is(err, ErrBumpingRule) {
+					t.Errorf("Expected ErrBumpingRule, got %v", err)
+				}
+				violations = len(err.(interface{ Unwrap() []error }).Unwrap())
+			}
+			if violations != tc.violations {
+				t.Errorf("Expected %d violations, got %d: %v", tc.violations, violations, v.CheckBumping())
+			}
+		})
+	}
+}
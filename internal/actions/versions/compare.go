@@ -0,0 +1,234 @@
+package versions
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Version schemes supported by C libraries, selected by upstream.package.versionScheme in llpkg.cfg.
+const (
+	// SchemeSemver is the default scheme, for example: 1.7.18
+	SchemeSemver = "semver"
+	// SchemeCalendar is for date-versioned libraries, for example: 2024.01.15, 2024-01-15 or 20240115
+	SchemeCalendar = "calendar"
+	// SchemeNumeric is for dotted numeric versions with a letter suffix, for example: 3.0.13a
+	SchemeNumeric = "numeric"
+	// SchemeSqlite is for sqlite-style integer versions, for example: 3450100 (3.45.1)
+	SchemeSqlite = "sqlite"
+)
+
+var ErrUnknownScheme = errors.New("versions: unknown version scheme")
+
+// Schemes lists the supported version schemes, each of them has a comparator.
+var Schemes = []string{SchemeSemver, SchemeCalendar, SchemeNumeric, SchemeSqlite}
+
+// Comparator orders C versions of a specific version scheme.
+type Comparator interface {
+	// IsValid reports whether the version follows the scheme.
+	IsValid(version string) bool
+	// Compare returns an integer comparing two valid versions,
+	// the result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+	Compare(a, b string) int
+	// Series returns the release series of a valid version,
+	// versions in the same series are patch releases of each other.
+	Series(version string) string
+}
+
+var comparators = map[string]Comparator{
+	SchemeSemver:   semverComparator{},
+	SchemeCalendar: calendarComparator{},
+	SchemeNumeric:  numericComparator{},
+	SchemeSqlite:   sqliteComparator{},
+}
+
+// ComparatorOf returns the comparator of the scheme, an empty scheme means SchemeSemver.
+func ComparatorOf(scheme string) (Comparator, error) {
+	if scheme == "" {
+		scheme = SchemeSemver
+	}
+	comparator, ok := comparators[scheme]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownScheme, scheme)
+	}
+	return comparator, nil
+}
+
+// compareInts compares two integer slices element by element, missing elements are treated as 0.
+func compareInts(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// parseInts converts numeric strings to integers, ok is false if any of them isn't numeric.
+func parseInts(parts []string) (ret []int, ok bool) {
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		ret = append(ret, n)
+	}
+	return ret, true
+}
+
+type semverComparator struct{}
+
+func (semverComparator) IsValid(version string) bool {
+	return version != "" && semver.IsValid(ToSemVer(version))
+}
+
+func (semverComparator) Compare(a, b string) int {
+	return semver.Compare(ToSemVer(a), ToSemVer(b))
+}
+
+func (semverComparator) Series(version string) string {
+	return semver.MajorMinor(ToSemVer(version))
+}
+
+type calendarComparator struct{}
+
+// parse splits a calendar version into year, month, day and the rest numeric parts.
+func (calendarComparator) parse(version string) ([]int, bool) {
+	var parts []string
+	if len(version) == 8 && !strings.ContainsAny(version, ".-") {
+		// compact form: YYYYMMDD
+		parts = []string{version[:4], version[4:6], version[6:]}
+	} else {
+		parts = strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' })
+	}
+	if len(parts) == 0 || len(parts[0]) != 4 {
+		return nil, false
+	}
+	nums, ok := parseInts(parts)
+	if !ok {
+		return nil, false
+	}
+	if len(nums) > 1 && (nums[1] < 1 || nums[1] > 12) {
+		return nil, false
+	}
+	if len(nums) > 2 && (nums[2] < 1 || nums[2] > 31) {
+		return nil, false
+	}
+	return nums, true
+}
+
+func (c calendarComparator) IsValid(version string) bool {
+	_, ok := c.parse(version)
+	return ok
+}
+
+func (c calendarComparator) Compare(a, b string) int {
+	x, _ := c.parse(a)
+	y, _ := c.parse(b)
+	return compareInts(x, y)
+}
+
+// Series of calendar versions is the year, releases within a year are considered as patches.
+func (c calendarComparator) Series(version string) string {
+	nums, ok := c.parse(version)
+	if !ok {
+		return ""
+	}
+	return strconv.Itoa(nums[0])
+}
+
+type numericComparator struct{}
+
+var numericVersionRegex = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)([a-z]*)$`)
+
+// parse splits a dotted numeric version into numeric parts and its letter suffix.
+func (numericComparator) parse(version string) ([]int, string, bool) {
+	matches := numericVersionRegex.FindStringSubmatch(version)
+	if matches == nil {
+		return nil, "", false
+	}
+	nums, ok := parseInts(strings.Split(matches[1], "."))
+	return nums, matches[2], ok
+}
+
+func (c numericComparator) IsValid(version string) bool {
+	_, _, ok := c.parse(version)
+	return ok
+}
+
+// Compare compares numeric parts first, then the suffix: no suffix < a < b < ... < z < za.
+func (c numericComparator) Compare(a, b string) int {
+	x, xSuffix, _ := c.parse(a)
+	y, ySuffix, _ := c.parse(b)
+	if cmp := compareInts(x, y); cmp != 0 {
+		return cmp
+	}
+	if len(xSuffix) != len(ySuffix) {
+		if len(xSuffix) > len(ySuffix) {
+			return 1
+		}
+		return -1
+	}
+	return strings.Compare(xSuffix, ySuffix)
+}
+
+// Series of numeric versions is all the numeric parts except the last one, 3.0.13a => 3.0
+func (c numericComparator) Series(version string) string {
+	nums, _, ok := c.parse(version)
+	if !ok {
+		return ""
+	}
+	series := make([]string, 0, len(nums))
+	for _, n := range nums[:max(len(nums)-1, 1)] {
+		series = append(series, strconv.Itoa(n))
+	}
+	return strings.Join(series, ".")
+}
+
+type sqliteComparator struct{}
+
+// parse decodes sqlite-style integer versions: XYYZZPP => X.YY.ZZ.PP
+func (sqliteComparator) parse(version string) (int, bool) {
+	if len(version) != 7 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(version)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func (c sqliteComparator) IsValid(version string) bool {
+	_, ok := c.parse(version)
+	return ok
+}
+
+func (c sqliteComparator) Compare(a, b string) int {
+	x, _ := c.parse(a)
+	y, _ := c.parse(b)
+	return compareInts([]int{x}, []int{y})
+}
+
+// Series of sqlite versions is the major and minor, 3450100 => 3.45
+func (c sqliteComparator) Series(version string) string {
+	n, ok := c.parse(version)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", n/1000000, n/10000%100)
+}
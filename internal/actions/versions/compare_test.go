@@ -0,0 +1,106 @@
+package versions
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+)
+
+func TestComparatorOf(t *testing.T) {
+	for _, scheme := range append(slices.Clone(Schemes), "") {
+		if _, err := ComparatorOf(scheme); err != nil {
+			t.Errorf("Expected comparator for %q, got %v", scheme, err)
+		}
+	}
+	if len(comparators) != len(Schemes) {
+		t.Errorf("Expected a comparator for each of %v, got %d", Schemes, len(comparators))
+	}
+	if _, err := ComparatorOf("unknown"); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("Expected ErrUnknownScheme, got %v", err)
+	}
+}
+
+func TestComparators(t *testing.T) {
+	tests := []struct {
+		scheme  string
+		sorted  []string // ascending
+		invalid []string
+		series  map[string]string
+	}{
+		{
+			scheme:  SchemeSemver,
+			sorted:  []string{"1.5.1", "1.5.3", "1.6", "v1.7.18", "2.0.0"},
+			invalid: []string{"", "abc", "3.0.13a"},
+			series:  map[string]string{"1.5.3": "v1.5", "v2.0.1": "v2.0"},
+		},
+		{
+			scheme:  SchemeCalendar,
+			sorted:  []string{"2023.12.31", "20240101", "2024-01-15", "2024.2", "2025.01.01.1"},
+			invalid: []string{"", "24.01", "2024.13.01", "2024.01.32", "2024a"},
+			series:  map[string]string{"2024.01.15": "2024", "20231231": "2023"},
+		},
+		{
+			scheme:  SchemeNumeric,
+			sorted:  []string{"1.1.1", "1.1.1a", "1.1.1w", "1.1.1za", "3.0.2", "3.0.13a", "3.1"},
+			invalid: []string{"", "3.0.13-rc1", "A1", "1..2"},
+			series:  map[string]string{"3.0.13a": "3.0", "1.1.1w": "1.1", "8": "8"},
+		},
+		{
+			scheme:  SchemeSqlite,
+			sorted:  []string{"3390400", "3450000", "3450100", "3460000"},
+			invalid: []string{"", "3.45.1", "345010"},
+			series:  map[string]string{"3450100": "3.45", "3390400": "3.39"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.scheme, func(t *testing.T) {
+			comparator, err := ComparatorOf(tc.scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, version := range tc.sorted {
+				if !comparator.IsValid(version) {
+					t.Errorf("Expected %s valid", version)
+				}
+			}
+			for _, version := range tc.invalid {
+				if comparator.IsValid(version) {
+					t.Errorf("Expected %s invalid", version)
+				}
+			}
+			shuffled := slices.Clone(tc.sorted)
+			slices.Reverse(shuffled)
+			slices.SortFunc(shuffled, comparator.Compare)
+			if !reflect.DeepEqual(shuffled, tc.sorted) {
+				t.Errorf("Expected %v, got %v", tc.sorted, shuffled)
+			}
+			for version, series := range tc.series {
+				if got := comparator.Series(version); got != series {
+					t.Errorf("Expected series of %s %s, got %s", version, series, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSortedCVersions(t *testing.T) {
+	v := &Versions{MetadataMap: metadata.MetadataMap{
+		"sqlite3": {
+			Versions: map[string][]string{
+				"3450100": {"v1.1.0"},
+				"3390400": {"v1.0.0"},
+				"3460000": {"v1.2.0"},
+				"latest":  {"v0.0.1"},
+			},
+		},
+	}}
+	comparator, _ := ComparatorOf(SchemeSqlite)
+	got := v.SortedCVersions("sqlite3", comparator)
+	want := []string{"3460000", "3450100", "3390400"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
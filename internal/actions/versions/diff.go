@@ -0,0 +1,79 @@
+package versions
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+)
+
+// Change is a mapping added or removed between two mapping tables.
+type Change struct {
+	Clib      string
+	CVersion  metadata.CVersion
+	GoVersion metadata.GoVersion
+	// Added is false if the mapping is removed
+	Added bool
+}
+
+// String returns the change in the form of "+ clib cversion => goversion"
+func (c Change) String() string {
+	op := "-"
+	if c.Added {
+		op = "+"
+	}
+	return fmt.Sprintf("%s %s %s => %s", op, c.Clib, c.CVersion, c.GoVersion)
+}
+
+// Diff returns mappings removed from old and added to new,
+// ordered by C library, C version, Go version, and removals come first.
+func Diff(old, new metadata.MetadataMap) []Change {
+	var changes []Change
+	collect := func(from, to metadata.MetadataMap, added bool) {
+		for clib, versions := range from {
+			if versions == nil {
+				continue
+			}
+			for cversion, goversions := range versions.Versions {
+				for _, goversion := range goversions {
+					if !hasMapping(to, clib, cversion, goversion) {
+						changes = append(changes, Change{clib, cversion, goversion, added})
+					}
+				}
+			}
+		}
+	}
+	collect(old, new, false)
+	collect(new, old, true)
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			cmp.Compare(a.Clib, b.Clib),
+			cmp.Compare(a.CVersion, b.CVersion),
+			cmp.Compare(a.GoVersion, b.GoVersion),
+			cmpBool(a.Added, b.Added),
+		)
+	})
+	return changes
+}
+
+// hasMapping reports whether the Go version is mapped to the C version in m
+func hasMapping(m metadata.MetadataMap, clib, cversion, goversion string) bool {
+	versions := m[clib]
+	if versions == nil {
+		return false
+	}
+	return slices.Contains(versions.Versions[cversion], goversion)
+}
+
+func cmpBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	default:
+		return 1
+	}
+}
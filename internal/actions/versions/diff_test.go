@@ -0,0 +1,35 @@
+package versions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+)
+
+func TestDiff(t *testing.T) {
+	old := metadata.MetadataMap{
+		"cjson": {Versions: map[string][]string{"1.7.18": {"v1.0.0", "v1.0.1"}}},
+		"zlib":  {Versions: map[string][]string{"1.3.1": {"v1.0.0"}}},
+	}
+	new := metadata.MetadataMap{
+		"cjson":  {Versions: map[string][]string{"1.7.18": {"v1.0.0"}, "1.7.19": {"v1.0.1"}}},
+		"libxml": {Versions: map[string][]string{"2.13.6": {"v1.0.0"}}},
+	}
+	got := Diff(old, new)
+	want := []Change{
+		{"cjson", "1.7.18", "v1.0.1", false},
+		{"cjson", "1.7.19", "v1.0.1", true},
+		{"libxml", "2.13.6", "v1.0.0", true},
+		{"zlib", "1.3.1", "v1.0.0", false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got[0].String() != "- cjson 1.7.18 => v1.0.1" {
+		t.Errorf("unexpected change string: %s", got[0])
+	}
+	if changes := Diff(old, old); len(changes) != 0 {
+		t.Errorf("Expected no change, got %v", changes)
+	}
+}
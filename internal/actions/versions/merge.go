@@ -0,0 +1,225 @@
+package versions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+	"golang.org/x/mod/semver"
+)
+
+var ErrMergeConflict = errors.New("versions: merge conflict")
+
+// keys of flattened metadata, the Go version is appended to the per-version ones.
+const (
+	versionsKey      = "versions/"
+	releasesKey      = "releases/"
+	yankedKey        = "yanked/"
+	versionSchemeKey = "versionScheme"
+	descriptionKey   = "description"
+	keywordsKey      = "keywords"
+	deprecatedKey    = "deprecated"
+)
+
+// Merge performs a three-way merge of mapping tables changed from base by ours and theirs.
+// Mappings are merged by Go version, so mappings added by both sides are kept,
+// and it conflicts only if both sides change the same thing differently,
+// for example, the same Go version is mapped to different C versions.
+//
+// On conflict, the merged mapping table is still returned with ours kept,
+// and the error wrapping ErrMergeConflict describes all the conflicts.
+func Merge(base, ours, theirs metadata.MetadataMap) (metadata.MetadataMap, error) {
+	clibs := map[string]struct{}{}
+	for _, m := range []metadata.MetadataMap{base, ours, theirs} {
+		for clib := range m {
+			clibs[clib] = struct{}{}
+		}
+	}
+	sortedClibs := make([]string, 0, len(clibs))
+	for clib := range clibs {
+		sortedClibs = append(sortedClibs, clib)
+	}
+	sort.Strings(sortedClibs)
+
+	merged := metadata.MetadataMap{}
+	var errs []error
+	for _, clib := range sortedClibs {
+		baseFields := flatten(base[clib])
+		oursFields := flatten(ours[clib])
+		theirsFields := flatten(theirs[clib])
+
+		keys := map[string]struct{}{}
+		for _, fields := range []map[string]string{baseFields, oursFields, theirsFields} {
+			for key := range fields {
+				keys[key] = struct{}{}
+			}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		fields := map[string]string{}
+		for _, key := range sortedKeys {
+			value, ok := merge3(baseFields[key], oursFields[key], theirsFields[key])
+			if !ok {
+				errs = append(errs, conflictError(clib, key, oursFields[key], theirsFields[key]))
+			}
+			if value != "" {
+				fields[key] = value
+			}
+		}
+		m, err := unflatten(fields)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			merged[clib] = m
+		}
+	}
+	return merged, errors.Join(errs...)
+}
+
+// merge3 merges a value changed from base by ours and theirs, an empty value means absent.
+// ok is false if both sides change it differently, and ours is returned.
+func merge3(base, ours, theirs string) (merged string, ok bool) {
+	switch {
+	case ours == theirs:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	case theirs == base:
+		return ours, true
+	}
+	return ours, false
+}
+
+func conflictError(clib, key, ours, theirs string) error {
+	describe := func(value string) string {
+		if value == "" {
+			return "removed"
+		}
+		return value
+	}
+	if goVersion, ok := strings.CutPrefix(key, versionsKey); ok {
+		return fmt.Errorf("%w: %s: %s is mapped to %s (ours) and %s (theirs)",
+			ErrMergeConflict, clib, goVersion, describe(ours), describe(theirs))
+	}
+	return fmt.Errorf("%w: %s: %s is changed to %s (ours) and %s (theirs)",
+		ErrMergeConflict, clib, key, describe(ours), describe(theirs))
+}
+
+// flatten converts metadata to a map from keys to JSON encoded values,
+// which makes every field can be merged separately.
+func flatten(m *metadata.Metadata) map[string]string {
+	fields := map[string]string{}
+	if m == nil {
+		return fields
+	}
+	set := func(key string, value any) {
+		// all the values are plain data, never fail
+		b, _ := json.Marshal(value)
+		fields[key] = string(b)
+	}
+	for cversion, goversions := range m.Versions {
+		for _, goversion := range goversions {
+			set(versionsKey+goversion, cversion)
+		}
+	}
+	for goversion, release := range m.Releases {
+		set(releasesKey+goversion, release)
+	}
+	for goversion, reason := range m.Yanked {
+		set(yankedKey+goversion, reason)
+	}
+	if m.VersionScheme != "" {
+		set(versionSchemeKey, m.VersionScheme)
+	}
+	if m.Description != "" {
+		set(descriptionKey, m.Description)
+	}
+	if len(m.Keywords) > 0 {
+		set(keywordsKey, m.Keywords)
+	}
+	if m.Deprecated != nil {
+		set(deprecatedKey, m.Deprecated)
+	}
+	return fields
+}
+
+// unflatten is the reverse of flatten, it returns nil if no version left.
+func unflatten(fields map[string]string) (*metadata.Metadata, error) {
+	m := &metadata.Metadata{
+		Versions: map[metadata.CVersion][]metadata.GoVersion{},
+	}
+	for key, value := range fields {
+		var err error
+		switch {
+		case strings.HasPrefix(key, versionsKey):
+			var cversion string
+			err = json.Unmarshal([]byte(value), &cversion)
+			m.Versions[cversion] = append(m.Versions[cversion], strings.TrimPrefix(key, versionsKey))
+		case strings.HasPrefix(key, releasesKey):
+			if m.Releases == nil {
+				m.Releases = map[metadata.GoVersion]*metadata.Release{}
+			}
+			release := &metadata.Release{}
+			err = json.Unmarshal([]byte(value), release)
+			m.Releases[strings.TrimPrefix(key, releasesKey)] = release
+		case strings.HasPrefix(key, yankedKey):
+			if m.Yanked == nil {
+				m.Yanked = map[metadata.GoVersion]string{}
+			}
+			var reason string
+			err = json.Unmarshal([]byte(value), &reason)
+			m.Yanked[strings.TrimPrefix(key, yankedKey)] = reason
+		case key == versionSchemeKey:
+			err = json.Unmarshal([]byte(value), &m.VersionScheme)
+		case key == descriptionKey:
+			err = json.Unmarshal([]byte(value), &m.Description)
+		case key == keywordsKey:
+			err = json.Unmarshal([]byte(value), &m.Keywords)
+		case key == deprecatedKey:
+			err = json.Unmarshal([]byte(value), &m.Deprecated)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(m.Versions) == 0 {
+		return nil, nil
+	}
+	for _, goversions := range m.Versions {
+		semver.Sort(goversions)
+	}
+	return m, nil
+}
+
+// MergeFiles merges mapping files like Merge, and writes the result to output atomically.
+// It's compatible with git merge driver, the result is written even on conflict.
+func MergeFiles(baseFile, oursFile, theirsFile, outputFile string) error {
+	var tables []metadata.MetadataMap
+	for _, fileName := range []string{baseFile, oursFile, theirsFile} {
+		v, err := Read(fileName)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, v.MetadataMap)
+	}
+	merged, mergeErr := Merge(tables[0], tables[1], tables[2])
+	if mergeErr != nil && !errors.Is(mergeErr, ErrMergeConflict) {
+		return mergeErr
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(outputFile, b, 0644); err != nil {
+		return err
+	}
+	return mergeErr
+}
@@ -0,0 +1,119 @@
+package versions
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PengPengPeng717/llpkgstore/metadata"
+)
+
+func parseMapping(t *testing.T, content string) metadata.MetadataMap {
+	t.Helper()
+	m := metadata.MetadataMap{}
+	if err := json.Unmarshal([]byte(content), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "union",
+			base:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+			ours:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.19": ["v1.1.0"]}}}`,
+			theirs: `{"cjson": {"versions": {"1.7.18": ["v1.0.0", "v1.0.1"]}}, "zlib": {"versions": {"1.3.1": ["v1.0.0"]}}}`,
+			want:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0", "v1.0.1"], "1.7.19": ["v1.1.0"]}}, "zlib": {"versions": {"1.3.1": ["v1.0.0"]}}}`,
+		},
+		{
+			name:   "same mapping added by both",
+			base:   `{}`,
+			ours:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+			theirs: `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+			want:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+		},
+		{
+			name:   "removed by one side",
+			base:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0", "v1.0.1"]}, "releases": {"v1.0.1": {"publishedAt": "2025-03-01T00:00:00Z"}}}}`,
+			ours:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+			theirs: `{"cjson": {"versions": {"1.7.18": ["v1.0.0", "v1.0.1"]}, "releases": {"v1.0.1": {"publishedAt": "2025-03-01T00:00:00Z"}}, "yanked": {"v1.0.0": "broken"}}}`,
+			want:   `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}, "yanked": {"v1.0.0": "broken"}}}`,
+		},
+		{
+			name:      "mapped to different C versions",
+			base:      `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+			ours:      `{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.19": ["v1.1.0"]}}}`,
+			theirs:    `{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.20": ["v1.1.0"]}, "deprecated": {"reason": "renamed"}}}`,
+			want:      `{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.19": ["v1.1.0"]}, "deprecated": {"reason": "renamed"}}}`,
+			conflicts: 1,
+		},
+		{
+			name:      "changed differently",
+			base:      `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`,
+			ours:      `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}, "yanked": {"v1.0.0": "broken"}}}`,
+			theirs:    `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}, "yanked": {"v1.0.0": "security"}}}`,
+			want:      `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}, "yanked": {"v1.0.0": "broken"}}}`,
+			conflicts: 1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			merged, err := Merge(parseMapping(t, tc.base), parseMapping(t, tc.ours), parseMapping(t, tc.theirs))
+			if tc.conflicts == 0 && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.conflicts > 0 {
+				if !errors.Is(err, ErrMergeConflict) {
+					t.Errorf("Expected ErrMergeConflict, got %v", err)
+				} else if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != tc.conflicts {
+					t.Errorf("Expected %d conflicts, got %d: %v", tc.conflicts, n, err)
+				}
+			}
+			got, _ := json.Marshal(merged)
+			want, _ := json.Marshal(parseMapping(t, tc.want))
+			if string(got) != string(want) {
+				t.Errorf("Expected %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("base.json", `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}}`)
+	ours := write("ours.json", `{"cjson": {"versions": {"1.7.18": ["v1.0.0"], "1.7.19": ["v1.1.0"]}}}`)
+	theirs := write("theirs.json", `{"cjson": {"versions": {"1.7.18": ["v1.0.0"]}}, "zlib": {"versions": {"1.3.1": ["v1.0.0"]}}}`)
+
+	if err := MergeFiles(base, ours, theirs, ours); err != nil {
+		t.Fatal(err)
+	}
+	v, err := Read(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.LatestGoVersion("cjson") != "v1.1.0" || v.LatestGoVersion("zlib") != "v1.0.0" {
+		t.Errorf("unexpected merge result: %s", v)
+	}
+
+	broken := write("broken.json", `{"cjson": `)
+	if err := MergeFiles(base, ours, broken, ours); !errors.Is(err, ErrInvalidMapping) {
+		t.Errorf("Expected ErrInvalidMapping, got %v", err)
+	}
+}
@@ -1,16 +1,24 @@
 package versions
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
-	"io"
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/PengPengPeng717/llpkgstore/metadata"
 	"golang.org/x/mod/semver"
 )
 
+var (
+	ErrDuplicateVersion = errors.New("versions: version has already existed")
+	ErrInvalidMapping   = errors.New("versions: invalid mapping file")
+)
+
 // Versions is a mapping table implement for Github Action only.
 // It's recommend to use another implement in llgo for common usage.
 type Versions struct {
@@ -20,46 +28,75 @@ type Versions struct {
 }
 
 // appendVersion adds a new version to the slice while preventing duplicates.
-// It panics if the element already exists in the array to enforce uniqueness constraints.
+// It returns ErrDuplicateVersion if the element already exists in the array to enforce uniqueness constraints.
 // Parameters:
 //
 //	arr: Slice of versions to modify
 //	elem: Version to append
-func appendVersion(arr []string, elem string) []string {
+func appendVersion(arr []string, elem string) ([]string, error) {
 	if slices.Contains(arr, elem) {
-		log.Fatalf("version %s has already existed", elem)
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateVersion, elem)
 	}
-	return append(arr, elem)
+	return append(arr, elem), nil
 }
 
 // Read initializes a Versions struct by reading version mappings from a file.
-// It creates the file if it doesn't exist and parses the JSON content into the MetadataMap.
+// It parses the JSON content into the MetadataMap and validates it,
+// an empty mapping table is returned if the file doesn't exist.
 // Parameters:
 //
 //	fileName: Path to the version mapping file
-func Read(fileName string) *Versions {
-	// read or create a file
-	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDONLY, 0644)
-	if err != nil {
-		panic(err)
-	}
-	defer f.Close()
-
-	b, err := io.ReadAll(f)
-	if err != nil {
-		panic(err)
+func Read(fileName string) (*Versions, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
 	}
 
 	m := metadata.MetadataMap{}
 
 	if len(b) > 0 {
-		json.Unmarshal(b, &m)
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidMapping, fileName, err)
+		}
 	}
 
-	return &Versions{
+	v := &Versions{
 		MetadataMap: m,
-		fileName:    f.Name(),
+		fileName:    fileName,
+	}
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Validate checks the mapping table is well-formed:
+// every Go version is a valid semver and is mapped to only one C version.
+func (v *Versions) Validate() error {
+	var errs []error
+	for clib, versions := range v.MetadataMap {
+		if versions == nil {
+			errs = append(errs, fmt.Errorf("%w: %s: no versions", ErrInvalidMapping, clib))
+			continue
+		}
+		seen := map[metadata.GoVersion]metadata.CVersion{}
+		for cversion, goversions := range versions.Versions {
+			if cversion == "" {
+				errs = append(errs, fmt.Errorf("%w: %s: empty C version", ErrInvalidMapping, clib))
+			}
+			for _, goversion := range goversions {
+				if !semver.IsValid(goversion) {
+					errs = append(errs, fmt.Errorf("%w: %s: %s is not a semver", ErrInvalidMapping, clib, goversion))
+				}
+				if previous, ok := seen[goversion]; ok {
+					errs = append(errs, fmt.Errorf("%w: %s: %s is mapped to both %s and %s",
+						ErrInvalidMapping, clib, goversion, previous, cversion))
+				}
+				seen[goversion] = cversion
+			}
+		}
 	}
+	return errors.Join(errs...)
 }
 
 // cVersions retrieves the version mappings for a specific C library.
@@ -97,6 +134,36 @@ func (v *Versions) GoVersions(clib string) (ret []string) {
 	return
 }
 
+// SortedCVersions returns C versions of the specified C library in descending order by the comparator.
+// Unlike CVersions, the original C versions are returned, and versions don't follow the scheme are skipped.
+func (v *Versions) SortedCVersions(clib string, comparator Comparator) (ret []string) {
+	for version := range v.cVersions(clib) {
+		if comparator.IsValid(version) {
+			ret = append(ret, version)
+		}
+	}
+	slices.SortFunc(ret, func(a, b string) int {
+		if cmp := comparator.Compare(b, a); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(b, a)
+	})
+	return
+}
+
+// Comparator returns the comparator of the version scheme recorded for the C library.
+func (v *Versions) Comparator(clib string) (Comparator, error) {
+	return ComparatorOf(v.VersionScheme(clib))
+}
+
+// VersionScheme returns the version scheme recorded for the C library, SchemeSemver if it's not recorded.
+func (v *Versions) VersionScheme(clib string) string {
+	if versions := v.MetadataMap[clib]; versions != nil && versions.VersionScheme != "" {
+		return versions.VersionScheme
+	}
+	return SchemeSemver
+}
+
 // LatestGoVersionForCVersion finds the latest Go version compatible with a specific C library version.
 func (v *Versions) LatestGoVersionForCVersion(clib, cver string) string {
 	version := v.MetadataMap[clib]
@@ -140,23 +207,353 @@ func (v *Versions) LatestGoVersion(clib string) string {
 //	mappedVersion: The Go version to map with the C library version.
 //
 // It appends the Go version to the existing list for the C library version and saves the updated metadata.
-func (v *Versions) Write(clib, clibVersion, mappedVersion string) {
+// Nothing is changed if any error occurs.
+func (v *Versions) Write(clib, clibVersion, mappedVersion string) error {
+	return v.WriteRelease(clib, clibVersion, mappedVersion, nil)
+}
+
+// WriteRelease is like Write, but also records the release details of the Go version.
+// The release details are skipped if release is nil.
+func (v *Versions) WriteRelease(clib, clibVersion, mappedVersion string, release *metadata.Release) error {
+	return v.transaction(func() error {
+		return v.write(clib, clibVersion, mappedVersion, release)
+	})
+}
+
+// Add is like Write, but also rejects the mapping if it breaks the order of the C library,
+// that is, Go versions must increase with C versions.
+// It's for editing the mapping table by hand, see Check.
+func (v *Versions) Add(clib, clibVersion, mappedVersion string) error {
+	return v.transaction(func() error {
+		if err := v.write(clib, clibVersion, mappedVersion, nil); err != nil {
+			return err
+		}
+		return v.checkOrder(clib)
+	})
+}
+
+// write adds a mapping to the mapping table in memory without persisting,
+// the mapping is validated before changing anything.
+func (v *Versions) write(clib, clibVersion, mappedVersion string, release *metadata.Release) error {
+	if clibVersion == "" {
+		return fmt.Errorf("versions: empty C version")
+	}
+	if !semver.IsValid(mappedVersion) {
+		return fmt.Errorf("versions: %s is not a semver", mappedVersion)
+	}
+	// a Go version can only be mapped to one C version
+	if slices.Contains(v.GoVersions(clib), mappedVersion) {
+		return fmt.Errorf("%w: %s/%s", ErrDuplicateVersion, clib, mappedVersion)
+	}
 	clibVersions := v.MetadataMap[clib]
 	if clibVersions == nil {
 		clibVersions = &metadata.Metadata{
 			Versions: map[metadata.CVersion][]metadata.GoVersion{},
 		}
-		v.MetadataMap[clib] = clibVersions
 	}
-	versions := clibVersions.Versions[clibVersion]
-
-	versions = appendVersion(versions, mappedVersion)
+	versions, err := appendVersion(clibVersions.Versions[clibVersion], mappedVersion)
+	if err != nil {
+		return err
+	}
+	v.MetadataMap[clib] = clibVersions
 
 	clibVersions.Versions[clibVersion] = versions
-	// sync to disk
-	b, _ := json.Marshal(&v.MetadataMap)
 
-	os.WriteFile(v.fileName, []byte(b), 0644)
+	if release != nil {
+		if clibVersions.Releases == nil {
+			clibVersions.Releases = map[metadata.GoVersion]*metadata.Release{}
+		}
+		clibVersions.Releases[mappedVersion] = release
+	}
+	return nil
+}
+
+// Remove deletes a mapped Go version of a C library version and persists to file.
+// If mappedVersion is empty, the C library version and all its Go versions are deleted.
+// The release details and yanked records of deleted Go versions are also deleted,
+// and the C library is deleted if there is no version left.
+func (v *Versions) Remove(clib, clibVersion, mappedVersion string) error {
+	clibVersions := v.MetadataMap[clib]
+	if clibVersions == nil {
+		return fmt.Errorf("versions: clib %s not found", clib)
+	}
+	goVersions, ok := clibVersions.Versions[clibVersion]
+	if !ok {
+		return fmt.Errorf("versions: %s/%s not found", clib, clibVersion)
+	}
+	removed := goVersions
+	if mappedVersion != "" {
+		if !slices.Contains(goVersions, mappedVersion) {
+			return fmt.Errorf("versions: %s is not mapped to %s/%s", mappedVersion, clib, clibVersion)
+		}
+		removed = []string{mappedVersion}
+	}
+	return v.transaction(func() error {
+		goVersions = slices.DeleteFunc(slices.Clone(goVersions), func(goVersion string) bool {
+			return slices.Contains(removed, goVersion)
+		})
+		if len(goVersions) == 0 {
+			delete(clibVersions.Versions, clibVersion)
+		} else {
+			clibVersions.Versions[clibVersion] = goVersions
+		}
+		for _, goVersion := range removed {
+			delete(clibVersions.Releases, goVersion)
+			delete(clibVersions.Yanked, goVersion)
+		}
+		if len(clibVersions.Versions) == 0 {
+			delete(v.MetadataMap, clib)
+		}
+		return nil
+	})
+}
+
+// SetVersionScheme records the version scheme of a C library and persists to file.
+// It's no-op if the scheme is unchanged.
+func (v *Versions) SetVersionScheme(clib, scheme string) error {
+	if clibVersions := v.MetadataMap[clib]; clibVersions != nil && clibVersions.VersionScheme == normalizeScheme(scheme) {
+		return nil
+	}
+	return v.transaction(func() error {
+		return v.setVersionScheme(clib, scheme)
+	})
+}
+
+// setVersionScheme records the version scheme in memory without persisting,
+// the scheme is validated before changing anything.
+func (v *Versions) setVersionScheme(clib, scheme string) error {
+	clibVersions := v.MetadataMap[clib]
+	if clibVersions == nil {
+		return fmt.Errorf("versions: clib %s not found", clib)
+	}
+	if _, err := ComparatorOf(scheme); err != nil {
+		return err
+	}
+	clibVersions.VersionScheme = normalizeScheme(scheme)
+	return nil
+}
+
+// normalizeScheme omits the default scheme in the mapping table
+func normalizeScheme(scheme string) string {
+	if scheme == SchemeSemver {
+		return ""
+	}
+	return scheme
+}
+
+// Yank marks a mapped Go version of a C library as yanked and persists to file.
+// A yanked version is excluded from latest and constraint resolution, but still resolvable by exact version.
+func (v *Versions) Yank(clib, mappedVersion, reason string) error {
+	clibVersions := v.MetadataMap[clib]
+	if clibVersions == nil {
+		return fmt.Errorf("versions: clib %s not found", clib)
+	}
+	if !slices.Contains(v.GoVersions(clib), mappedVersion) {
+		return fmt.Errorf("versions: %s/%s not found", clib, mappedVersion)
+	}
+	return v.transaction(func() error {
+		if clibVersions.Yanked == nil {
+			clibVersions.Yanked = map[metadata.GoVersion]string{}
+		}
+		clibVersions.Yanked[mappedVersion] = reason
+		return nil
+	})
+}
+
+// Deprecate marks a C library as deprecated and persists to file.
+// replacement is the name of the package replacing it, which is optional.
+func (v *Versions) Deprecate(clib, reason, replacement string) error {
+	clibVersions := v.MetadataMap[clib]
+	if clibVersions == nil {
+		return fmt.Errorf("versions: clib %s not found", clib)
+	}
+	if replacement != "" && v.MetadataMap[replacement] == nil {
+		return fmt.Errorf("versions: replacement %s not found", replacement)
+	}
+	return v.transaction(func() error {
+		clibVersions.Deprecated = &metadata.Deprecation{
+			Reason:      reason,
+			Replacement: replacement,
+		}
+		return nil
+	})
+}
+
+// Tx stages changes to the mapping table in memory, which are validated as they are made,
+// and persisted together by Commit. The mapping table is untouched until Commit succeeds.
+type Tx struct {
+	v      *Versions
+	staged *Versions
+}
+
+// Begin starts staging changes to the mapping table.
+func (v *Versions) Begin() (*Tx, error) {
+	staged, err := v.clone()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{v: v, staged: staged}, nil
+}
+
+// clone deep copies the mapping table
+func (v *Versions) clone() (*Versions, error) {
+	b, err := json.Marshal(&v.MetadataMap)
+	if err != nil {
+		return nil, err
+	}
+	m := metadata.MetadataMap{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &Versions{MetadataMap: m, fileName: v.fileName}, nil
+}
+
+// WriteRelease stages a mapping with its release details, see Versions.WriteRelease.
+// Nothing is staged if the mapping is invalid.
+// The release is persisted as it is when committing, so it may be completed after staging, e.g. checksums.
+func (tx *Tx) WriteRelease(clib, clibVersion, mappedVersion string, release *metadata.Release) error {
+	return tx.staged.write(clib, clibVersion, mappedVersion, release)
+}
+
+// SetVersionScheme stages the version scheme of a C library, see Versions.SetVersionScheme.
+func (tx *Tx) SetVersionScheme(clib, scheme string) error {
+	return tx.staged.setVersionScheme(clib, scheme)
+}
+
+// Commit persists the staged changes to file and applies them to the mapping table.
+// If signingKey isn't nil, the file is signed again, and the signature is written to
+// the file name + metadata.SignatureSuffix together with the file.
+func (tx *Tx) Commit(signingKey ed25519.PrivateKey) error {
+	b, err := json.Marshal(&tx.staged.MetadataMap)
+	if err != nil {
+		return err
+	}
+	files := []atomicFile{{tx.v.fileName, b}}
+	if signingKey != nil {
+		files = append(files, atomicFile{tx.v.fileName + metadata.SignatureSuffix, metadata.Sign(signingKey, b)})
+	}
+	if err := writeFilesAtomic(files, 0644); err != nil {
+		return err
+	}
+	tx.v.MetadataMap = tx.staged.MetadataMap
+	return nil
+}
+
+// transaction applies fn to the mapping table and persists it to file.
+// If either fn or persisting fails, the mapping table in memory is rolled back,
+// and the file is left untouched.
+func (v *Versions) transaction(fn func() error) error {
+	backup, err := json.Marshal(&v.MetadataMap)
+	if err != nil {
+		return err
+	}
+	rollback := func() {
+		m := metadata.MetadataMap{}
+		// it's marshaled by ourselves, should never fail
+		json.Unmarshal(backup, &m)
+		v.MetadataMap = m
+	}
+	if err := fn(); err != nil {
+		rollback()
+		return err
+	}
+	if err := v.sync(); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// sync writes the mapping table to disk atomically,
+// it writes to a temporary file in the same directory and renames it,
+// so the file is never half-written.
+func (v *Versions) sync() error {
+	b, err := json.Marshal(&v.MetadataMap)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(v.fileName, b, 0644)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to fileName
+func writeFileAtomic(fileName string, data []byte, perm os.FileMode) error {
+	return writeFilesAtomic([]atomicFile{{fileName, data}}, perm)
+}
+
+// atomicFile is a file written by writeFilesAtomic
+type atomicFile struct {
+	name string
+	data []byte
+}
+
+// writeFilesAtomic writes all the files to temporary files before renaming any of them,
+// so nothing is changed if writing fails, e.g. the disk is full.
+// If renaming fails, the files renamed are restored as best effort.
+func writeFilesAtomic(files []atomicFile, perm os.FileMode) error {
+	tempNames := make([]string, 0, len(files))
+	// clean up on failure, it's no-op after renaming
+	defer func() {
+		for _, tempName := range tempNames {
+			os.Remove(tempName)
+		}
+	}()
+	for _, file := range files {
+		tempName, err := writeTemp(file.name, file.data, perm)
+		if err != nil {
+			return err
+		}
+		tempNames = append(tempNames, tempName)
+	}
+
+	// nil if the file doesn't exist
+	originals := make([][]byte, len(files))
+	for i, file := range files {
+		original, err := os.ReadFile(file.name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		originals[i] = original
+	}
+	for i, file := range files {
+		if err := os.Rename(tempNames[i], file.name); err != nil {
+			for j := range i {
+				if originals[j] == nil {
+					os.Remove(files[j].name)
+				} else {
+					os.WriteFile(files[j].name, originals[j], perm)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTemp writes data to a temporary file in the directory of fileName, and returns its name
+func writeTemp(fileName string, data []byte, perm os.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
 }
 
 // String returns the JSON representation of the Versions metadata.
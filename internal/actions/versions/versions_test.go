@@ -2,10 +2,17 @@ package versions
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/PengPengPeng717/llpkgstore/metadata"
 	"golang.org/x/mod/semver"
 )
 
@@ -26,7 +33,10 @@ func TestCVersions(t *testing.T) {
 	}
 	defer os.Remove(path)
 
-	v := Read(path)
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
 	goVersion := v.GoVersions("cgood")
 	semver.Sort(goVersion)
 
@@ -71,7 +81,10 @@ func TestLatestVersion(t *testing.T) {
 	}
 	defer os.Remove(path)
 
-	v := Read(path)
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if v.LatestGoVersion("cgood") != "v1.1.0" {
 		t.Errorf("unexpected latest version: want: v1.1.0 got: %s", v.LatestGoVersion("cgood"))
@@ -79,20 +92,39 @@ func TestLatestVersion(t *testing.T) {
 }
 
 func TestAppend(t *testing.T) {
-	v := Read("llpkgstore.json")
+	v, err := Read("llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer os.Remove("llpkgstore.json")
 
-	v.Write("cjson", "1.7.18", "v1.0.0")
-	v.Write("cjson", "1.7.19", "v1.0.2")
+	if err := v.Write("cjson", "1.7.18", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("cjson", "1.7.19", "v1.0.2"); err != nil {
+		t.Fatal(err)
+	}
 
-	v = Read("llpkgstore.json")
+	v, err = Read("llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 	//defer os.Remove("llpkgstore.json")
 
-	v.Write("cjson", "1.7.18", "v1.0.1")
-	v.Write("libxml", "1.45.1.4", "v1.0.0")
+	if err := v.Write("cjson", "1.7.18", "v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("libxml", "1.45.1.4", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
 
-	v = Read("llpkgstore.json")
-	v.Write("libxml", "1.45.1.5", "v1.0.1")
+	v, err = Read("llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("libxml", "1.45.1.5", "v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
 
 	b, _ := os.ReadFile("llpkgstore.json")
 
@@ -100,3 +132,333 @@ func TestAppend(t *testing.T) {
 		t.Error("unexpected append result")
 	}
 }
+
+func TestWriteRelease(t *testing.T) {
+	v, err := Read("llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("llpkgstore.json")
+
+	if err := v.Write("cjson", "1.7.18", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	err = v.WriteRelease("cjson", "1.7.18", "v1.0.1", &metadata.Release{
+		PublishedAt: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Commit:      "0123456789abcdef",
+		Installer:   "conan",
+		Checksums:   map[string]string{"linux_amd64": "aaa"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile("llpkgstore.json")
+
+	if !bytes.Equal(b, []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0","v1.0.1"]},"releases":{"v1.0.1":{"publishedAt":"2025-03-01T00:00:00Z","commit":"0123456789abcdef","installer":"conan","checksums":{"linux_amd64":"aaa"}}}}}`)) {
+		t.Errorf("unexpected write result: %s", string(b))
+	}
+
+	v, err = Read("llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := v.MetadataMap["cjson"].Releases["v1.0.1"]
+	if release == nil || release.Commit != "0123456789abcdef" {
+		t.Errorf("unexpected release: %v", release)
+	}
+	if _, ok := v.MetadataMap["cjson"].Releases["v1.0.0"]; ok {
+		t.Error("unexpected release for v1.0.0")
+	}
+}
+
+func TestYankAndDeprecate(t *testing.T) {
+	v, err := Read("llpkgstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("llpkgstore.json")
+
+	if err := v.Write("cjson", "1.7.18", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("cjson", "1.7.18", "v1.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("zlib", "1.3.1", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Yank("cjson", "v1.0.1", "broken"); err != nil {
+		t.Error(err)
+	}
+	if err := v.Yank("cjson", "v1.0.2", "broken"); err == nil {
+		t.Error("unexpected yank of non-existent version")
+	}
+	if err := v.Yank("libxml", "v1.0.0", "broken"); err == nil {
+		t.Error("unexpected yank of non-existent clib")
+	}
+	if err := v.Deprecate("zlib", "renamed", "cjson"); err != nil {
+		t.Error(err)
+	}
+	if err := v.Deprecate("zlib", "renamed", "libxml"); err == nil {
+		t.Error("unexpected deprecation with non-existent replacement")
+	}
+
+	b, _ := os.ReadFile("llpkgstore.json")
+
+	if !bytes.Equal(b, []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0","v1.0.1"]},"yanked":{"v1.0.1":"broken"}},"zlib":{"versions":{"1.3.1":["v1.0.0"]},"deprecated":{"reason":"renamed","replacement":"cjson"}}}`)) {
+		t.Errorf("unexpected result: %s", string(b))
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	path := "ttt.json"
+	defer os.Remove(path)
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"broken json", `{"cjson": {"versions": {"1.7.16: ["v1.1.0"]}}}`},
+		{"not semver", `{"cjson": {"versions": {"1.7.16": ["1.1.0"]}}}`},
+		{"duplicate", `{"cjson": {"versions": {"1.7.16": ["v1.1.0"], "1.7.18": ["v1.1.0"]}}}`},
+		{"empty c version", `{"cjson": {"versions": {"": ["v1.1.0"]}}}`},
+		{"null", `{"cjson": null}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Read(path)
+			if !errors.Is(err, ErrInvalidMapping) {
+				t.Errorf("Expected ErrInvalidMapping, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadNotExist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.MetadataMap) != 0 {
+		t.Errorf("Expected empty mapping, got %v", v.MetadataMap)
+	}
+	// reading never creates the file
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected file not created, got %v", err)
+	}
+}
+
+func TestWriteRollback(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "llpkgstore.json")
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("cjson", "1.7.18", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	if err := v.Write("cjson", "1.7.19", "v1.0.0"); !errors.Is(err, ErrDuplicateVersion) {
+		t.Errorf("Expected ErrDuplicateVersion, got %v", err)
+	}
+	if err := v.Write("cjson", "1.7.19", "1.0.1"); err == nil {
+		t.Error("Expected error for non-semver Go version")
+	}
+
+	// make persisting fail
+	v.fileName = filepath.Join(dir, "not-exist", "llpkgstore.json")
+	if err := v.Write("zlib", "1.3.1", "v1.0.0"); err == nil {
+		t.Error("Expected error when persisting fails")
+	}
+	if _, ok := v.MetadataMap["zlib"]; ok {
+		t.Error("Expected in-memory mapping rolled back")
+	}
+
+	after, _ := os.ReadFile(path)
+	if !bytes.Equal(before, after) {
+		t.Errorf("Expected file untouched, got %s", after)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary file left, got %d entries", len(entries))
+	}
+}
+
+func TestTx(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Write("cjson", "1.7.18", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	tx, err := v.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.WriteRelease("cjson", "1.7.19", "v1.0.0", nil); !errors.Is(err, ErrDuplicateVersion) {
+		t.Errorf("Expected ErrDuplicateVersion, got %v", err)
+	}
+	if err := tx.WriteRelease("zlib", "1.3.1", "1.0.0", nil); err == nil {
+		t.Error("Expected error for non-semver Go version")
+	}
+	release := &metadata.Release{Commit: "abc"}
+	if err := tx.WriteRelease("cjson", "1.7.19", "v1.1.0", release); err != nil {
+		t.Fatal(err)
+	}
+	// staged changes are neither visible nor persisted before committing
+	if slices.Contains(v.GoVersions("cjson"), "v1.1.0") {
+		t.Error("Expected staged mapping invisible before committing")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Errorf("Expected file untouched before committing, got %s", after)
+	}
+	if _, ok := tx.staged.MetadataMap["zlib"]; ok {
+		t.Error("Expected invalid mapping not staged")
+	}
+
+	// the release may be completed after staging
+	release.Checksums = map[string]string{"linux_amd64": "aaa"}
+	if err := tx.Commit(nil); err != nil {
+		t.Fatal(err)
+	}
+	v, err = Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.MetadataMap["cjson"].Releases["v1.1.0"]; got == nil || got.Checksums["linux_amd64"] != "aaa" {
+		t.Errorf("Expected committed release with checksums, got %v", got)
+	}
+}
+
+func TestTxCommitSigned(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, _ := v.Begin()
+	if err := tx.SetVersionScheme("sqlite3", SchemeSqlite); err == nil {
+		t.Error("Expected error for non-existent clib")
+	}
+	tx.WriteRelease("sqlite3", "3450100", "v1.0.0", nil)
+	if err := tx.SetVersionScheme("sqlite3", "unknown"); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("Expected ErrUnknownScheme, got %v", err)
+	}
+	if err := tx.SetVersionScheme("sqlite3", SchemeSqlite); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(privateKey); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	signature, _ := os.ReadFile(path + metadata.SignatureSuffix)
+	if err := metadata.Verify([]ed25519.PublicKey{publicKey}, data, signature); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if v.MetadataMap["sqlite3"].VersionScheme != SchemeSqlite {
+		t.Errorf("Expected committed scheme, got %q", v.MetadataMap["sqlite3"].VersionScheme)
+	}
+
+	// the signature can't be written, neither file is changed
+	os.Remove(path + metadata.SignatureSuffix)
+	os.Mkdir(path+metadata.SignatureSuffix, 0755)
+	os.WriteFile(filepath.Join(path+metadata.SignatureSuffix, "file"), nil, 0644)
+	tx, _ = v.Begin()
+	tx.WriteRelease("sqlite3", "3460000", "v1.1.0", nil)
+	if err := tx.Commit(privateKey); err == nil {
+		t.Error("Expected error when writing the signature fails")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(data, after) {
+		t.Errorf("Expected file restored, got %s", after)
+	}
+	if slices.Contains(v.GoVersions("sqlite3"), "v1.1.0") {
+		t.Error("Expected mapping table unchanged")
+	}
+}
+
+func TestSetVersionScheme(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetVersionScheme("sqlite3", SchemeSqlite); err == nil {
+		t.Error("Expected error for non-existent clib")
+	}
+	if err := v.Write("sqlite3", "3450100", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetVersionScheme("sqlite3", "unknown"); !errors.Is(err, ErrUnknownScheme) {
+		t.Errorf("Expected ErrUnknownScheme, got %v", err)
+	}
+	if err := v.SetVersionScheme("sqlite3", SchemeSqlite); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comparator, err := v.Comparator("sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !comparator.IsValid("3450100") || comparator.IsValid("3.45.1") {
+		t.Errorf("Expected sqlite comparator, got %T", comparator)
+	}
+}
+
+func TestAddAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llpkgstore.json")
+	v, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Add("cjson", "1.7.18", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Add("cjson", "1.7.19", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Add("cjson", "1.7.17", "v1.2.0"); !errors.Is(err, ErrUnorderedMapping) {
+		t.Errorf("Expected ErrUnorderedMapping, got %v", err)
+	}
+	if slices.Contains(v.GoVersions("cjson"), "v1.2.0") {
+		t.Error("Expected unordered mapping rolled back")
+	}
+	if err := v.Yank("cjson", "v1.1.0", "broken"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Remove("cjson", "1.7.19", "v1.0.0"); err == nil {
+		t.Error("Expected error for Go version not mapped to the C version")
+	}
+	if err := v.Remove("cjson", "1.7.19", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.MetadataMap["cjson"].Yanked["v1.1.0"]; ok {
+		t.Error("Expected yanked record removed")
+	}
+	if err := v.Remove("cjson", "1.7.18", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(path)
+	if string(b) != "{}" {
+		t.Errorf("Expected empty mapping, got %s", b)
+	}
+}
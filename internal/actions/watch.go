@@ -0,0 +1,341 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PengPengPeng717/llpkgstore/config"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+	"golang.org/x/mod/semver"
+)
+
+// UpdateBranchPrefix is the prefix of branches pushed by Watch, e.g. llpkgstore/update/cjson/1.7.19
+const UpdateBranchPrefix = "llpkgstore/update/"
+
+var (
+	ErrNotNewer  = errors.New("actions: C version is not newer than the mapped ones")
+	ErrDirtyRepo = errors.New("actions: the repository has uncommitted changes, commit or stash them first")
+)
+
+// generatedFiles are the files of a package committed by Watch besides Go sources,
+// other files, e.g. .pc files copied for debugging, are left out.
+var generatedFiles = []string{"llpkg.cfg", "llcppg.cfg", "llcppg.pub", "llcppg.symb.json", "llpyg.cfg", "go.mod", "go.sum"}
+
+// pipSearchResult matches a result of pip search, e.g. numpy (2.2.3) - Fundamental package
+var pipSearchResult = regexp.MustCompile(`^(\S+) \(([^)]+)\)`)
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	// RepoDir is the path to the local clone of the repository, containing package directories and llpkgstore.json
+	RepoDir string
+	// Remote is the git remote to push update branches to, origin by default
+	Remote string
+	// Base is the branch updates are based on and merged into, main by default
+	Base string
+	// NewUpstream creates the upstream of the package, config.NewUpstreamFromConfig by default
+	NewUpstream func(config.UpstreamConfig) (*upstream.Upstream, error)
+	// Generate regenerates the bindings in the package directory after llpkg.cfg is updated
+	Generate func(dir string) error
+}
+
+// Update is a newer upstream version of a package found by Watch
+type Update struct {
+	Package string
+	// Version is the current C version, NewVersion is the newer one
+	Version    string
+	NewVersion string
+	// MappedVersion is the next Go version for NewVersion
+	MappedVersion string
+	// Branch is the branch pushed, PullRequest is the number of the pull request opened
+	Branch      string
+	PullRequest int
+	// Skipped reports the branch exists, since the pull request has been opened by a previous run
+	Skipped bool
+}
+
+// upstreamVersions parses the versions of the package in the results of Installer.Search,
+// e.g. cjson/1.7.18 or cjson/1.7.18#revision for conan, and cjson (1.7.18) for pip.
+func upstreamVersions(name string, results []string) []string {
+	var ret []string
+	for _, result := range results {
+		if match := pipSearchResult.FindStringSubmatch(strings.TrimSpace(result)); match != nil {
+			if match[1] == name {
+				ret = append(ret, match[2])
+			}
+			continue
+		}
+		for _, field := range strings.Fields(result) {
+			version, ok := strings.CutPrefix(field, name+"/")
+			if !ok {
+				continue
+			}
+			if i := strings.IndexAny(version, "#@"); i >= 0 {
+				version = version[:i]
+			}
+			ret = append(ret, version)
+		}
+	}
+	return ret
+}
+
+// newestVersion returns the newest valid version newer than current, or "" if there isn't any.
+// Pre-releases of semver C libraries are skipped, since they're not stable to be mapped.
+func newestVersion(candidates []string, current, scheme string, comparator versions.Comparator) string {
+	newest := current
+	for _, version := range candidates {
+		if !comparator.IsValid(version) {
+			continue
+		}
+		if (scheme == "" || scheme == versions.SchemeSemver) && semver.Prerelease(versions.ToSemVer(version)) != "" {
+			continue
+		}
+		if comparator.Compare(version, newest) > 0 {
+			newest = version
+		}
+	}
+	if newest == current {
+		return ""
+	}
+	return newest
+}
+
+// majorMinor parses MAJOR and MINOR of the Go version
+func majorMinor(goVersion string) (major, minor int, err error) {
+	parts := strings.Split(strings.TrimPrefix(semver.MajorMinor(goVersion), "v"), ".")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("actions: invalid Go version %s", goVersion)
+	}
+	if major, err = strconv.Atoi(parts[0]); err == nil {
+		minor, err = strconv.Atoi(parts[1])
+	}
+	return
+}
+
+// NextMappedVersion returns the mapped version of a newer C version of the C library following bumping rules:
+//  1. the initial version is v1.0.0 for stable C libraries or v0.1.0 otherwise.
+//  2. MAJOR is increased for a new MAJOR version of semver C libraries, since it's a breaking change.
+//  3. MINOR is increased otherwise.
+//
+// Updates of history series are maintained in release branches, so cversion must be newer than the mapped ones.
+func NextMappedVersion(ver *versions.Versions, clib, cversion string, comparator versions.Comparator) (string, error) {
+	semverC := versions.ToSemVer(cversion)
+	cversions := ver.SortedCVersions(clib, comparator)
+	latestGo := ver.LatestGoVersion(clib)
+	if len(cversions) == 0 || latestGo == "" {
+		if semver.IsValid(semverC) && semver.Major(semverC) == "v0" {
+			return "v0.1.0", nil
+		}
+		return "v1.0.0", nil
+	}
+	latestC := cversions[0]
+	if comparator.Compare(cversion, latestC) <= 0 {
+		return "", fmt.Errorf("%w: %s %s is not newer than %s", ErrNotNewer, clib, cversion, latestC)
+	}
+
+	major, minor, err := majorMinor(latestGo)
+	if err != nil {
+		return "", err
+	}
+	latestSemverC := versions.ToSemVer(latestC)
+	if semver.IsValid(semverC) && semver.IsValid(latestSemverC) && semver.Major(semverC) != semver.Major(latestSemverC) {
+		return fmt.Sprintf("v%d.0.0", major+1), nil
+	}
+	return fmt.Sprintf("v%d.%d.0", major, minor+1), nil
+}
+
+// packageVersionField matches the version field of the package in llpkg.cfg
+var packageVersionField = regexp.MustCompile(`("package"\s*:\s*\{[^{}]*"version"\s*:\s*)"[^"]*"`)
+
+// setPackageVersion replaces the version of the package in llpkg.cfg, the formatting is kept.
+func setPackageVersion(cfgFile, version string) error {
+	data, err := os.ReadFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	if !packageVersionField.Match(data) {
+		return fmt.Errorf("actions: no package version found in %s", cfgFile)
+	}
+	replaced := false
+	data = packageVersionField.ReplaceAllFunc(data, func(match []byte) []byte {
+		if replaced {
+			return match
+		}
+		replaced = true
+		return packageVersionField.ReplaceAll(match, []byte(`${1}"`+version+`"`))
+	})
+	return os.WriteFile(cfgFile, data, 0644)
+}
+
+// updateBranch returns the branch of the update
+func updateBranch(clib, cversion string) string {
+	return UpdateBranchPrefix + clib + "/" + cversion
+}
+
+// Watch finds newer upstream versions of packages in the repository by Installer.Search,
+// and opens a pull request for each of them, which updates llpkg.cfg and regenerates the bindings
+// in a branch, with "Release-as" of the next mapped version in the commit message.
+// An update is skipped if its branch exists, which means the pull request has been opened.
+// Failures of a package don't stop the others.
+func (d *DefaultClient) Watch(opts WatchOptions) ([]Update, error) {
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	if opts.Base == "" {
+		opts.Base = defaultReleaseBranch
+	}
+	if opts.NewUpstream == nil {
+		opts.NewUpstream = config.NewUpstreamFromConfig
+	}
+	ver, err := versions.Read(filepath.Join(opts.RepoDir, "llpkgstore.json"))
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+	entries, err := os.ReadDir(opts.RepoDir)
+	if err != nil {
+		return nil, wrapActionError(err)
+	}
+
+	var updates []Update
+	var errs []error
+	for _, entry := range entries {
+		cfgFile := filepath.Join(opts.RepoDir, entry.Name(), "llpkg.cfg")
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if _, err := os.Stat(cfgFile); err != nil {
+			continue
+		}
+		update, err := d.watchPackage(opts, ver, entry.Name())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		if update != nil {
+			updates = append(updates, *update)
+		}
+	}
+	return updates, errors.Join(errs...)
+}
+
+// watchPackage opens a pull request for the newest upstream version of the package in the directory,
+// nil is returned if the package is up to date.
+func (d *DefaultClient) watchPackage(opts WatchOptions, ver *versions.Versions, dir string) (*Update, error) {
+	cfgFile := filepath.Join(opts.RepoDir, dir, "llpkg.cfg")
+	cfg, err := config.ParseLLPkgConfig(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	uc, err := opts.NewUpstream(cfg.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	clib := cfg.Upstream.Package.Name
+	comparator, err := versions.ComparatorOf(cfg.Upstream.Package.VersionScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := uc.Installer.Search(uc.Pkg)
+	if err != nil {
+		return nil, err
+	}
+	current := cfg.Upstream.Package.Version
+	if cversions := ver.SortedCVersions(clib, comparator); len(cversions) > 0 && comparator.Compare(cversions[0], current) > 0 {
+		current = cversions[0]
+	}
+	newest := newestVersion(upstreamVersions(clib, results), current, cfg.Upstream.Package.VersionScheme, comparator)
+	if newest == "" {
+		return nil, nil
+	}
+	mappedVersion, err := NextMappedVersion(ver, clib, newest, comparator)
+	if err != nil {
+		return nil, err
+	}
+	update := &Update{
+		Package:       clib,
+		Version:       cfg.Upstream.Package.Version,
+		NewVersion:    newest,
+		MappedVersion: mappedVersion,
+		Branch:        updateBranch(clib, newest),
+	}
+	if d.hasBranch(update.Branch) {
+		update.Skipped = true
+		return update, nil
+	}
+
+	message := fmt.Sprintf("%s: update to %s\n\n%s%s/%s", clib, newest, MappedVersionPrefix, clib, mappedVersion)
+	if d.plan != nil {
+		// the repository is kept untouched in a dry run
+		d.plan.add("push branch %s regenerating %s:\n%s", update.Branch, dir, message)
+	} else if err := pushUpdate(opts, dir, update, message); err != nil {
+		return nil, err
+	}
+	pull, err := d.host.CreatePullRequest(context.TODO(), PullRequestOptions{
+		Title: fmt.Sprintf("%s: update to %s", clib, newest),
+		Body: fmt.Sprintf("Update %s from %s to %s, which is released as %s.\n\nThis pull request is opened by `llpkgstore watch`.",
+			clib, update.Version, newest, mappedVersion),
+		Head: update.Branch,
+		Base: opts.Base,
+	})
+	if err != nil {
+		return nil, err
+	}
+	update.PullRequest = pull.Number
+	return update, nil
+}
+
+// pushUpdate commits the update of the package in the directory to the branch of the update with the message,
+// and pushes it to the remote. The base branch is checked out again finally.
+// It refuses to run on a repository with uncommitted changes, so that everything left by
+// the generation can be discarded without losing changes of others.
+func pushUpdate(opts WatchOptions, dir string, update *Update, message string) (err error) {
+	repo := LocalOptions{RepoDir: opts.RepoDir}
+	status, err := repo.git("status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("%w:\n%s", ErrDirtyRepo, strings.TrimRight(status, "\n"))
+	}
+	if _, err := repo.git("checkout", "-q", "-B", update.Branch, opts.Base); err != nil {
+		return err
+	}
+	defer func() {
+		// the tree was clean, so the changes are all made by the generation
+		_, resetErr := repo.git("reset", "-q", "--hard")
+		_, cleanErr := repo.git("clean", "-q", "-f", "-d", "--", dir)
+		_, checkoutErr := repo.git("checkout", "-q", opts.Base)
+		err = errors.Join(err, resetErr, cleanErr, checkoutErr)
+	}()
+
+	pkgDir := filepath.Join(opts.RepoDir, dir)
+	if err := setPackageVersion(filepath.Join(pkgDir, "llpkg.cfg"), update.NewVersion); err != nil {
+		return wrapActionError(err)
+	}
+	if opts.Generate != nil {
+		if err := opts.Generate(pkgDir); err != nil {
+			return err
+		}
+	}
+	pathspecs := []string{filepath.ToSlash(filepath.Join(dir, "*.go"))}
+	for _, name := range generatedFiles {
+		if _, err := os.Stat(filepath.Join(pkgDir, name)); err == nil {
+			pathspecs = append(pathspecs, filepath.ToSlash(filepath.Join(dir, name)))
+		}
+	}
+	if _, err := repo.git(append([]string{"add", "-A", "--"}, pathspecs...)...); err != nil {
+		return err
+	}
+	if _, err := repo.git("commit", "-q", "-m", message); err != nil {
+		return err
+	}
+	_, err = repo.git("push", "-q", opts.Remote, update.Branch)
+	return err
+}
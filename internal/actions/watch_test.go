@@ -0,0 +1,232 @@
+package actions
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PengPengPeng717/llpkgstore/config"
+	"github.com/PengPengPeng717/llpkgstore/internal/actions/versions"
+	"github.com/PengPengPeng717/llpkgstore/upstream"
+)
+
+func TestUpstreamVersions(t *testing.T) {
+	results := []string{"cjson/1.7.17", "cjson/1.7.18#5e5b", "cjson-ext/2.0.0", "cjson (1.7.19) - Ultralightweight JSON parser"}
+	expected := []string{"1.7.17", "1.7.18", "1.7.19"}
+	if got := upstreamVersions("cjson", results); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestNewestVersion(t *testing.T) {
+	semver, _ := versions.ComparatorOf("")
+	if got := newestVersion([]string{"1.7.17", "1.7.19", "2.0.0-rc1", "invalid"}, "1.7.18", "", semver); got != "1.7.19" {
+		t.Errorf("Expected 1.7.19, got %s", got)
+	}
+	if got := newestVersion([]string{"1.7.17", "1.7.18"}, "1.7.18", "", semver); got != "" {
+		t.Errorf("Expected up to date, got %s", got)
+	}
+	sqlite, _ := versions.ComparatorOf(versions.SchemeSqlite)
+	if got := newestVersion([]string{"3450100", "3460000"}, "3450100", versions.SchemeSqlite, sqlite); got != "3460000" {
+		t.Errorf("Expected 3460000, got %s", got)
+	}
+}
+
+func TestNextMappedVersion(t *testing.T) {
+	ver, err := versions.Read(filepath.Join(t.TempDir(), "llpkgstore.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ver.Add("cjson", "1.7.18", "v1.0.0")
+	ver.Add("cjson", "1.7.18", "v1.0.1")
+	ver.Add("zlib", "0.9.0", "v0.1.0")
+	comparator, _ := versions.ComparatorOf("")
+
+	tests := []struct {
+		clib, cversion, expected string
+	}{
+		{"cjson", "1.7.19", "v1.1.0"},
+		{"cjson", "2.0.0", "v2.0.0"},
+		{"zlib", "0.9.1", "v0.2.0"},
+		{"zlib", "1.0.0", "v1.0.0"},
+		{"libxml2", "2.13.6", "v1.0.0"},
+		{"libfoo", "0.3.0", "v0.1.0"},
+	}
+	for _, tt := range tests {
+		got, err := NextMappedVersion(ver, tt.clib, tt.cversion, comparator)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.clib, tt.cversion, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Expected %s %s mapped to %s, got %s", tt.clib, tt.cversion, tt.expected, got)
+		}
+	}
+	if _, err := NextMappedVersion(ver, "cjson", "1.7.17", comparator); !errors.Is(err, ErrNotNewer) {
+		t.Errorf("Expected ErrNotNewer, got %v", err)
+	}
+}
+
+func TestSetPackageVersion(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "llpkg.cfg")
+	cfg := `{
+  "upstream": {
+    "installer": {"name": "conan", "config": {"version": "2"}},
+    "package": {
+      "name": "cjson",
+      "version": "1.7.18"
+    }
+  }
+}`
+	os.WriteFile(cfgFile, []byte(cfg), 0644)
+	if err := setPackageVersion(cfgFile, "1.7.19"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(cfgFile)
+	if expected := strings.Replace(cfg, `"1.7.18"`, `"1.7.19"`, 1); string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+// newWatchRepo creates a repository cloned from a bare remote, containing the packages at the versions,
+// and llpkgstore.json mapping them to v1.0.0.
+func newWatchRepo(t *testing.T, packages map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	repoDir, remoteDir := filepath.Join(dir, "repo"), filepath.Join(dir, "remote.git")
+	if ret, err := exec.Command("git", "init", "-q", "--bare", remoteDir).CombinedOutput(); err != nil {
+		t.Skipf("git is not available: %s", ret)
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+	git := func(args ...string) {
+		if ret, err := exec.Command("git", append([]string{"-C", repoDir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, ret)
+		}
+	}
+	if ret, err := exec.Command("git", "init", "-q", "-b", "main", repoDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %s", ret)
+	}
+	git("remote", "add", "origin", remoteDir)
+
+	ver, _ := versions.Read(filepath.Join(repoDir, "llpkgstore.json"))
+	for name, version := range packages {
+		os.MkdirAll(filepath.Join(repoDir, name), 0755)
+		cfg := `{"upstream": {"installer": {"name": "conan"}, "package": {"name": "` + name + `", "version": "` + version + `"}}}`
+		os.WriteFile(filepath.Join(repoDir, name, "llpkg.cfg"), []byte(cfg), 0644)
+		if err := ver.Write(name, version, "v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial commit")
+	git("push", "-q", "origin", "main")
+	return repoDir
+}
+
+func TestWatch(t *testing.T) {
+	repoDir := newWatchRepo(t, map[string]string{"cjson": "1.7.18", "libxml2": "2.13.5", "zlib": "1.3.1", "broken": "1.0.0"})
+	installer := &fakeInstaller{search: map[string][]string{
+		"cjson":   {"cjson/1.7.17", "cjson/1.7.18", "cjson/1.7.19", "cjson/2.0.0-rc1"},
+		"libxml2": {"libxml2/2.13.6"},
+		"zlib":    {"zlib/1.3.1"},
+	}}
+	fake := &fakeGitHub{now: time.Now(), branches: []string{"llpkgstore/update/libxml2/2.13.6"}}
+	client := NewClient(newGitHubTestHost(t, fake), nil)
+
+	var generated []string
+	updates, err := client.Watch(WatchOptions{
+		RepoDir: repoDir,
+		NewUpstream: func(cfg config.UpstreamConfig) (*upstream.Upstream, error) {
+			return &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: cfg.Package.Name, Version: cfg.Package.Version}}, nil
+		},
+		Generate: func(dir string) error {
+			generated = append(generated, filepath.Base(dir))
+			// copied for debugging
+			os.WriteFile(filepath.Join(dir, "libcjson.pc"), []byte("Name: cjson\n"), 0644)
+			return os.WriteFile(filepath.Join(dir, "cjson.go"), []byte("package cjson\n"), 0644)
+		},
+	})
+	// broken has no search results, which doesn't stop the others
+	if err == nil || !strings.Contains(err.Error(), "broken: package not found") {
+		t.Errorf("Expected error of broken, got %v", err)
+	}
+	expected := []Update{
+		{Package: "cjson", Version: "1.7.18", NewVersion: "1.7.19", MappedVersion: "v1.1.0",
+			Branch: "llpkgstore/update/cjson/1.7.19", PullRequest: 1},
+		{Package: "libxml2", Version: "2.13.5", NewVersion: "2.13.6", MappedVersion: "v1.1.0",
+			Branch: "llpkgstore/update/libxml2/2.13.6", Skipped: true},
+	}
+	if !reflect.DeepEqual(updates, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, updates)
+	}
+	if !reflect.DeepEqual(generated, []string{"cjson"}) {
+		t.Errorf("Expected cjson generated, got %v", generated)
+	}
+
+	if len(fake.created) != 1 {
+		t.Fatalf("Expected 1 pull request opened, got %v", fake.created)
+	}
+	pull := fake.created[0]
+	if pull["head"] != "llpkgstore/update/cjson/1.7.19" || pull["base"] != "main" || pull["title"] != "cjson: update to 1.7.19" {
+		t.Errorf("unexpected pull request %v", pull)
+	}
+
+	repo := LocalOptions{RepoDir: repoDir}
+	if branch, _ := repo.git("branch", "--show-current"); strings.TrimSpace(branch) != "main" {
+		t.Errorf("Expected main checked out, got %s", branch)
+	}
+	message, err := repo.git("log", "-1", "--format=%B", "origin/llpkgstore/update/cjson/1.7.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(message, "Release-as: cjson/v1.1.0") {
+		t.Errorf("Expected Release-as: cjson/v1.1.0, got %s", message)
+	}
+	cfg, err := repo.git("show", "origin/llpkgstore/update/cjson/1.7.19:cjson/llpkg.cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cfg, `"version": "1.7.19"`) {
+		t.Errorf("Expected llpkg.cfg updated, got %s", cfg)
+	}
+	files, _ := repo.git("ls-tree", "--name-only", "origin/llpkgstore/update/cjson/1.7.19", "cjson/")
+	if !strings.Contains(files, "cjson/cjson.go") || strings.Contains(files, ".pc") {
+		t.Errorf("Expected only bindings committed, got %s", files)
+	}
+	if status, _ := repo.git("status", "--porcelain"); status != "" {
+		t.Errorf("Expected the repository cleaned, got %s", status)
+	}
+}
+
+func TestWatchDirtyRepo(t *testing.T) {
+	repoDir := newWatchRepo(t, map[string]string{"cjson": "1.7.18"})
+	installer := &fakeInstaller{search: map[string][]string{"cjson": {"cjson/1.7.19"}}}
+	client := NewClient(newGitHubTestHost(t, &fakeGitHub{now: time.Now()}), nil)
+	// a local change which mustn't be discarded
+	os.WriteFile(filepath.Join(repoDir, "notes.txt"), []byte("local change"), 0644)
+	_, err := client.Watch(WatchOptions{
+		RepoDir: repoDir,
+		NewUpstream: func(cfg config.UpstreamConfig) (*upstream.Upstream, error) {
+			return &upstream.Upstream{Installer: installer, Pkg: upstream.Package{Name: cfg.Package.Name, Version: cfg.Package.Version}}, nil
+		},
+	})
+	if !errors.Is(err, ErrDirtyRepo) {
+		t.Errorf("Expected ErrDirtyRepo, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repoDir, "notes.txt")); string(data) != "local change" {
+		t.Errorf("Expected the local change kept, got %q", data)
+	}
+	if branch, _ := (LocalOptions{RepoDir: repoDir}).git("branch", "--show-current"); strings.TrimSpace(branch) != "main" {
+		t.Errorf("Expected main checked out, got %s", branch)
+	}
+}
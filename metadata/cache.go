@@ -1,44 +1,72 @@
 package metadata
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 var (
 	ErrCacheFileNotFound = errors.New("cache file not found")
+	ErrNoMirror          = errors.New("no remote mirror configured")
+	ErrUnverifiedCache   = errors.New("cache file isn't signed by trusted keys")
+
+	mirrorTimeout = 10 * time.Second // timeout for each mirror, change only for testing
 )
 
 // Cache represents a local cache for storing and retrieving data.
-// It binds a local file path and a remote data source URL
+// It binds a local file path and a list of remote data source URLs,
+// which are tried in order until one of them succeeds.
+// Cache is safe for concurrent use, and updates are serialized.
 type Cache[T any] struct {
-	data T // stores cached data
+	mu       sync.RWMutex // guards data and source
+	updateMu sync.Mutex   // serializes Update
+
+	data      T      // stores cached data
+	raw       []byte // data as served by the mirror, which is saved to disk as it is
+	signature []byte // detached signature of raw if verified
 
-	cacheFilePath string // local file path for cache storage
-	remoteUrl     string // URL of the remote data source
+	cacheFilePath string   // local file path for cache storage
+	remoteUrls    []string // URLs of the remote data source, in priority order
+	source        string   // URL of the mirror which served the current data
+	verify        Verifier // verifies remote data with its detached signature, optional
 
 	modTime time.Time // last modified time of the cached data
 }
 
 // NewCache initializes and loads the cache from disk or remote source
-func NewCache[T any](cacheFilePath, remoteUrl string) (*Cache[T], error) {
+func NewCache[T any](cacheFilePath string, remoteUrls ...string) (*Cache[T], error) {
+	return NewVerifiedCache[T](cacheFilePath, nil, remoteUrls...)
+}
+
+// NewVerifiedCache is like NewCache, but remote data is accepted only if
+// its detached signature, located at the remote URL + SignatureSuffix, passes verify.
+// Data without a signature is accepted until signatures are required, see RequireSignatureEnv.
+// The signature is cached alongside the data at cacheFilePath + SignatureSuffix,
+// and the cache file is verified again when it's loaded.
+// A nil verify disables verification.
+func NewVerifiedCache[T any](cacheFilePath string, verify Verifier, remoteUrls ...string) (*Cache[T], error) {
 	cache := &Cache[T]{
 		cacheFilePath: cacheFilePath,
-		remoteUrl:     remoteUrl,
+		remoteUrls:    remoteUrls,
+		verify:        verify,
 	}
 
 	err := cache.loadFromDisk()
 	if err != nil {
-		// local cache missing or invalid, fetch from remote
-		err = cache.Update()
+		// local cache missing, invalid or unverified, fetch from remote.
+		// An unverified cache file may be tampered, so it's never used and is replaced by the fetched data.
+		err = cache.Update(context.Background())
 		if err != nil {
-			return nil, fmt.Errorf("error building cache: %v", err)
+			return nil, fmt.Errorf("error building cache: %w", err)
 		}
 	}
 
@@ -46,8 +74,11 @@ func NewCache[T any](cacheFilePath, remoteUrl string) (*Cache[T], error) {
 }
 
 // Update refreshes the cache by fetching remote data and saving to disk
-func (c *Cache[T]) Update() error {
-	err := c.fetch()
+func (c *Cache[T]) Update(ctx context.Context) error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	err := c.fetch(ctx)
 	if err != nil {
 		return err
 	}
@@ -60,10 +91,80 @@ func (c *Cache[T]) Update() error {
 	return nil
 }
 
-// fetch retrieves the latest data from the remote source using conditional requests
-func (c *Cache[T]) fetch() error {
+// fetch retrieves the latest data from the first available mirror.
+// Mirrors are tried in order, and the errors of all failed mirrors are
+// returned only if none of them succeeds.
+func (c *Cache[T]) fetch(ctx context.Context) error {
+	if len(c.remoteUrls) == 0 {
+		return ErrNoMirror
+	}
+	var errs []error
+	for _, remoteUrl := range c.remoteUrls {
+		err := c.fetchFrom(ctx, remoteUrl)
+		if err == nil {
+			c.mu.Lock()
+			c.source = remoteUrl
+			c.mu.Unlock()
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("mirror %s: %w", remoteUrl, err))
+	}
+	return errors.Join(errs...)
+}
+
+// fetchFrom retrieves the latest data from a single mirror
+func (c *Cache[T]) fetchFrom(ctx context.Context, remoteUrl string) error {
+	u, err := url.Parse(remoteUrl)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "file" {
+		return c.fetchFile(u.Path)
+	}
+	return c.fetchHTTP(ctx, remoteUrl)
+}
+
+// fetchFile loads data from a local registry file, which is useful for fully local registries
+func (c *Cache[T]) fetchFile(path string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	// same as HTTP 304 Not Modified
+	if !c.modTime.IsZero() && !fileInfo.ModTime().After(c.modTime) {
+		return nil
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var signature []byte
+	if c.verify != nil {
+		signature, err = os.ReadFile(path + SignatureSuffix)
+		if err != nil {
+			return err
+		}
+		if err := c.verify(body, signature); err != nil {
+			return err
+		}
+	}
+	var bodyData T
+	err = json.Unmarshal(body, &bodyData)
+	if err != nil {
+		return err
+	}
+	c.setData(bodyData, body, signature)
+	c.modTime = fileInfo.ModTime()
+	return nil
+}
+
+// fetchHTTP retrieves the latest data from the remote source using conditional requests
+func (c *Cache[T]) fetchHTTP(ctx context.Context, remoteUrl string) error {
+	ctx, cancel := context.WithTimeout(ctx, mirrorTimeout)
+	defer cancel()
+
 	// Create HTTP request with If-Modified-Since header to reduce unnecessary downloads
-	req, err := http.NewRequest("GET", c.remoteUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", remoteUrl, nil)
 	if err != nil {
 		return err
 	}
@@ -85,13 +186,19 @@ func (c *Cache[T]) fetch() error {
 		if err != nil {
 			return err
 		}
+		signature, err := c.verifySignature(body, func() ([]byte, error) {
+			return fetchSignature(ctx, remoteUrl+SignatureSuffix)
+		})
+		if err != nil {
+			return err
+		}
 
 		var bodyData T
 		err = json.Unmarshal(body, &bodyData)
 		if err != nil {
 			return err
 		}
-		c.data = bodyData
+		c.setData(bodyData, body, signature)
 
 		// Update last modified time from response headers
 		lastModified := resp.Header.Get("Last-Modified")
@@ -107,10 +214,79 @@ func (c *Cache[T]) fetch() error {
 	}
 }
 
+// verifySignature verifies data with the detached signature returned by getSignature,
+// and returns the signature to be cached, which is nil if nothing is verified.
+// A missing signature is accepted as long as signatures aren't required,
+// but a published one must always be valid.
+func (c *Cache[T]) verifySignature(data []byte, getSignature func() ([]byte, error)) ([]byte, error) {
+	if c.verify == nil {
+		return nil, nil
+	}
+	signature, err := getSignature()
+	if errors.Is(err, ErrNoSignature) && !signatureRequired() {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verify(data, signature); err != nil {
+		return nil, err
+	}
+	return signature, nil
+}
+
+// readSignature reads the detached signature from a local file
+func readSignature(fileName string) ([]byte, error) {
+	signature, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoSignature, fileName)
+	}
+	return signature, err
+}
+
+// fetchSignature downloads the detached signature
+func fetchSignature(ctx context.Context, signatureUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", signatureUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNoSignature, signatureUrl)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signature HTTP error %d: %s", resp.StatusCode, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
 func (c *Cache[T]) Data() T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.data
 }
 
+func (c *Cache[T]) setData(data T, raw, signature []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = data
+	c.raw = raw
+	c.signature = signature
+}
+
+// Source returns the URL of the mirror which served the current data.
+// It's empty if the data is loaded from the local cache file.
+func (c *Cache[T]) Source() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.source
+}
+
 // saveToDisk persists the current cache data to the local file system
 func (c *Cache[T]) saveToDisk() error {
 	// Create directory structure if needed
@@ -119,19 +295,47 @@ func (c *Cache[T]) saveToDisk() error {
 		return err
 	}
 
-	// Serialize data to JSON
-	file, err := json.Marshal(c.data)
-	if err != nil {
-		return err
+	c.mu.RLock()
+	raw, signature := c.raw, c.signature
+	c.mu.RUnlock()
+
+	// the signature is written first, a data file without matching signature is refetched
+	if c.verify != nil {
+		if signature != nil {
+			err = writeFileAtomic(c.cacheFilePath+SignatureSuffix, signature)
+		} else {
+			// unsigned data, a stale signature would reject it when it's loaded
+			err = os.Remove(c.cacheFilePath + SignatureSuffix)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return writeFileAtomic(c.cacheFilePath, raw)
+}
 
-	// Write to file with proper permissions
-	err = os.WriteFile(c.cacheFilePath, file, 0644)
+// writeFileAtomic replaces fileName with data by renaming a temporary file,
+// so readers never see a partially written file.
+func writeFileAtomic(fileName string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
 	if err != nil {
 		return err
 	}
-
-	return nil
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fileName)
 }
 
 func (c *Cache[T]) loadFromDisk() error {
@@ -142,13 +346,21 @@ func (c *Cache[T]) loadFromDisk() error {
 			return fmt.Errorf("error read file from cache: %v", err)
 		}
 
+		// The cache file may be tampered, verify it as remote data.
+		signature, err := c.verifySignature(file, func() ([]byte, error) {
+			return readSignature(c.cacheFilePath + SignatureSuffix)
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnverifiedCache, err)
+		}
+
 		// Unmarshal the cache file.
 		var fileData T
 		err = json.Unmarshal(file, &fileData)
 		if err != nil {
 			return fmt.Errorf("error json unmarshal from cache: %v", err)
 		}
-		c.data = fileData
+		c.setData(fileData, file, signature)
 
 		// Get the last modified time of the cache.
 		fileInfo, err := os.Stat(c.cacheFilePath)
@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -89,7 +90,7 @@ func TestCache_Fetch304(t *testing.T) {
 	cache.modTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
 
 	// Second fetch should return 304
-	err := cache.fetch()
+	err := cache.fetch(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to fetch cache: %v", err)
 	}
@@ -114,3 +115,101 @@ func TestCache_InvalidJSON(t *testing.T) {
 		t.Errorf("Expected error for invalid JSON response, but got nil")
 	}
 }
+
+// Test failover to the next mirror when the first one is unavailable
+func TestCache_MirrorFailover(t *testing.T) {
+	badServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer badServer.Close()
+
+	goodServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(testCacheData)
+	}))
+	defer goodServer.Close()
+
+	tmpDir := t.TempDir()
+	cache, err := NewCache[MetadataMap](filepath.Join(tmpDir, "cache.json"), badServer.URL, goodServer.URL)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	if !reflect.DeepEqual(cache.Data(), testCacheData) {
+		t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+	}
+	if cache.Source() != goodServer.URL {
+		t.Errorf("Unexpected source. Expected: %s, Got: %s", goodServer.URL, cache.Source())
+	}
+}
+
+// Test a mirror which doesn't respond in time is skipped
+func TestCache_MirrorTimeout(t *testing.T) {
+	done := make(chan struct{})
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slowServer.Close()
+	defer close(done)
+
+	goodServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(testCacheData)
+	}))
+	defer goodServer.Close()
+
+	originalTimeout := mirrorTimeout
+	defer func() { mirrorTimeout = originalTimeout }()
+	mirrorTimeout = 100 * time.Millisecond
+
+	tmpDir := t.TempDir()
+	cache, err := NewCache[MetadataMap](filepath.Join(tmpDir, "cache.json"), slowServer.URL, goodServer.URL)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if cache.Source() != goodServer.URL {
+		t.Errorf("Unexpected source. Expected: %s, Got: %s", goodServer.URL, cache.Source())
+	}
+}
+
+// Test all mirrors fail
+func TestCache_AllMirrorsFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	_, err := NewCache[MetadataMap](filepath.Join(tmpDir, "cache.json"), server.URL, "file:///nonexistent/llpkgstore.json")
+	if err == nil {
+		t.Fatal("Expected error when all mirrors fail, but got nil")
+	}
+
+	_, err = NewCache[MetadataMap](filepath.Join(tmpDir, "cache.json"))
+	if err == nil {
+		t.Fatal("Expected error when no mirror is configured, but got nil")
+	}
+}
+
+// Test loading data from a file:// mirror
+func TestCache_FileMirror(t *testing.T) {
+	tmpDir := t.TempDir()
+	registry := filepath.Join(tmpDir, "registry.json")
+	err := os.WriteFile(registry, []byte(`{"example-module":{"versions":{"1.7.18":["v1.2.0"]}}}`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write registry file: %v", err)
+	}
+	fileURL := "file://" + filepath.ToSlash(registry)
+
+	cache, err := NewCache[MetadataMap](filepath.Join(tmpDir, "cache.json"), fileURL)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	if !reflect.DeepEqual(cache.Data(), testCacheData) {
+		t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+	}
+	if cache.Source() != fileURL {
+		t.Errorf("Unexpected source. Expected: %s, Got: %s", fileURL, cache.Source())
+	}
+}
@@ -0,0 +1,337 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+var ErrInvalidConstraint = errors.New("invalid version constraint")
+
+// Constraint is a parsed semver constraint, such as ">=1.7 <1.8", "^1.2", "~1.7" or "v1.x".
+//
+// Supported syntax:
+//   - comparison operators: =, ==, !=, >, >=, <, <=
+//   - caret ranges: ^1.2.3 := >=1.2.3 <2.0.0, ^0.2.3 := >=0.2.3 <0.3.0
+//   - tilde ranges: ~1.2.3 := >=1.2.3 <1.3.0, ~1 := >=1.0.0 <2.0.0
+//   - hyphen ranges: 1.2 - 1.4 := >=1.2.0 <1.5.0
+//   - wildcards and partial versions: 1.x, 1.2.*, 1.7 := >=1.7.0 <1.8.0
+//   - space or comma separated terms are ANDed, and "||" ORs the groups.
+//
+// A prerelease version like 1.3.0-beta.1 matches only the groups naming a prerelease
+// of the same major.minor.patch, so ">=1.2" excludes it but ">=1.3.0-alpha" doesn't.
+//
+// Versions may have an optional "v" prefix, so it applies to both C versions and Go versions.
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+// comparator compares a canonical semver with version by op
+type comparator struct {
+	op      string
+	version string
+}
+
+func (c comparator) check(version string) bool {
+	cmp := semver.Compare(version, c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// partialVersion is a version which may omit its minor or patch part.
+type partialVersion struct {
+	major, minor, patch int
+	// specified is the count of specified numeric parts, 0 means any version.
+	specified  int
+	prerelease string
+}
+
+// lower returns the smallest version matching p
+func (p partialVersion) lower() string {
+	return fmt.Sprintf("v%d.%d.%d%s", p.major, p.minor, p.patch, p.prerelease)
+}
+
+// next returns the smallest version which is larger than all versions matching p,
+// it's only valid for 0 < specified < 3.
+func (p partialVersion) next() string {
+	if p.specified == 1 {
+		return fmt.Sprintf("v%d.0.0", p.major+1)
+	}
+	return fmt.Sprintf("v%d.%d.0", p.major, p.minor+1)
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
+
+// parsePartialVersion parses version like 1, 1.2, 1.2.3, v1.2.x, 1.2.3-beta.1
+func parsePartialVersion(s string) (p partialVersion, err error) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" || isWildcard(s) {
+		return
+	}
+	core, suffix := s, ""
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		core, suffix = s[:i], s[i:]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return p, fmt.Errorf("%w: %s", ErrInvalidConstraint, s)
+	}
+	nums := [3]*int{&p.major, &p.minor, &p.patch}
+	for i, part := range parts {
+		if isWildcard(part) {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("%w: %s", ErrInvalidConstraint, s)
+		}
+		*nums[i] = n
+		p.specified++
+	}
+	if suffix != "" {
+		if p.specified != 3 {
+			return p, fmt.Errorf("%w: prerelease requires a full version: %s", ErrInvalidConstraint, s)
+		}
+		if !semver.IsValid("v" + s) {
+			return p, fmt.Errorf("%w: %s", ErrInvalidConstraint, s)
+		}
+		// build metadata doesn't take part in comparison
+		p.prerelease = semver.Prerelease("v" + s)
+	}
+	return
+}
+
+// operators ordered by length, so the longest operator is matched first
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "=", "^", "~"}
+
+func splitOperator(term string) (op, version string) {
+	for _, op := range operators {
+		if strings.HasPrefix(term, op) {
+			return op, strings.TrimSpace(term[len(op):])
+		}
+	}
+	return "", term
+}
+
+// comparators converts a term to comparators
+func comparators(op string, p partialVersion) ([]comparator, error) {
+	full := p.specified == 3
+	anyVersion := p.specified == 0
+	switch op {
+	case "", "=", "==":
+		if full {
+			return []comparator{{"=", p.lower()}}, nil
+		}
+		if anyVersion {
+			return nil, nil
+		}
+		return []comparator{{">=", p.lower()}, {"<", p.next()}}, nil
+	case "!=":
+		if !full {
+			return nil, fmt.Errorf("%w: != requires a full version", ErrInvalidConstraint)
+		}
+		return []comparator{{"!=", p.lower()}}, nil
+	case ">":
+		if full {
+			return []comparator{{">", p.lower()}}, nil
+		}
+		if anyVersion {
+			// nothing is larger than any version
+			return []comparator{{"<", "v0.0.0-0"}}, nil
+		}
+		return []comparator{{">=", p.next()}}, nil
+	case ">=":
+		return []comparator{{">=", p.lower()}}, nil
+	case "<":
+		if anyVersion {
+			return []comparator{{"<", "v0.0.0-0"}}, nil
+		}
+		return []comparator{{"<", p.lower()}}, nil
+	case "<=":
+		if full {
+			return []comparator{{"<=", p.lower()}}, nil
+		}
+		if anyVersion {
+			return nil, nil
+		}
+		return []comparator{{"<", p.next()}}, nil
+	case "^":
+		if anyVersion {
+			return nil, nil
+		}
+		var upper string
+		switch {
+		case p.major > 0 || p.specified == 1:
+			upper = fmt.Sprintf("v%d.0.0", p.major+1)
+		case p.minor > 0 || p.specified == 2:
+			upper = fmt.Sprintf("v0.%d.0", p.minor+1)
+		default:
+			upper = fmt.Sprintf("v0.0.%d", p.patch+1)
+		}
+		return []comparator{{">=", p.lower()}, {"<", upper}}, nil
+	case "~":
+		if anyVersion {
+			return nil, nil
+		}
+		upper := fmt.Sprintf("v%d.%d.0", p.major, p.minor+1)
+		if p.specified == 1 {
+			upper = fmt.Sprintf("v%d.0.0", p.major+1)
+		}
+		return []comparator{{">=", p.lower()}, {"<", upper}}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown operator %s", ErrInvalidConstraint, op)
+}
+
+// hyphenRange converts "from - to" to comparators
+func hyphenRange(from, to partialVersion) []comparator {
+	var ret []comparator
+	if from.specified > 0 {
+		ret = append(ret, comparator{">=", from.lower()})
+	}
+	switch {
+	case to.specified == 3:
+		ret = append(ret, comparator{"<=", to.lower()})
+	case to.specified > 0:
+		ret = append(ret, comparator{"<", to.next()})
+	}
+	return ret
+}
+
+// tokenize splits a group into terms, joining operators separated from its version by spaces.
+func tokenize(group string) []string {
+	fields := strings.Fields(strings.ReplaceAll(group, ",", " "))
+	var terms []string
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if op, version := splitOperator(field); op != "" && version == "" && i+1 < len(fields) {
+			field += fields[i+1]
+			i++
+		}
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// ParseConstraint parses a semver constraint, see Constraint for the syntax.
+func ParseConstraint(constraint string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(constraint)}
+	if c.raw == "" {
+		return nil, fmt.Errorf("%w: empty constraint", ErrInvalidConstraint)
+	}
+	for _, group := range strings.Split(c.raw, "||") {
+		terms := tokenize(group)
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("%w: empty range in %q", ErrInvalidConstraint, c.raw)
+		}
+		var set []comparator
+		for i := 0; i < len(terms); i++ {
+			// hyphen range: from - to
+			if i+2 < len(terms) && terms[i+1] == "-" {
+				from, err := parsePartialVersion(terms[i])
+				if err != nil {
+					return nil, err
+				}
+				to, err := parsePartialVersion(terms[i+2])
+				if err != nil {
+					return nil, err
+				}
+				set = append(set, hyphenRange(from, to)...)
+				i += 2
+				continue
+			}
+			op, version := splitOperator(terms[i])
+			p, err := parsePartialVersion(version)
+			if err != nil {
+				return nil, err
+			}
+			cmps, err := comparators(op, p)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, cmps...)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// Check reports whether version satisfies the constraint.
+// version may omit the "v" prefix or minor/patch part like C versions,
+// it never matches if it can't be converted to a semver.
+func (c *Constraint) Check(version string) bool {
+	v := toSemVer(version)
+	if !semver.IsValid(v) {
+		return false
+	}
+	for _, set := range c.sets {
+		matched := true
+		for _, cmp := range set {
+			if !cmp.check(v) {
+				matched = false
+				break
+			}
+		}
+		if matched && allowsPrerelease(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsPrerelease reports whether version is allowed by set in terms of its prerelease:
+// a release version is always allowed, and a prerelease one only if set has a
+// prerelease comparator with the same major.minor.patch.
+func allowsPrerelease(set []comparator, version string) bool {
+	prerelease := semver.Prerelease(version)
+	if prerelease == "" {
+		return true
+	}
+	core := strings.TrimSuffix(version, prerelease)
+	for _, cmp := range set {
+		if cmpPrerelease := semver.Prerelease(cmp.version); cmpPrerelease != "" &&
+			strings.TrimSuffix(cmp.version, cmpPrerelease) == core {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// toSemVer converts a version string to canonical semantic version format,
+// it keeps the same behavior with versions.ToSemVer, which cannot be imported
+// here due to import cycle.
+func toSemVer(version string) string {
+	if version == "" {
+		return version
+	}
+	v := version
+	if v[0] != 'v' {
+		v = "v" + v
+	}
+	if canonical := semver.Canonical(v); canonical != "" {
+		return canonical
+	}
+	return version
+}
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		matches    []string
+		mismatches []string
+	}{
+		{">=1.7 <1.8", []string{"1.7", "1.7.0", "1.7.19", "v1.7.5"}, []string{"1.6.9", "1.8", "1.8.0", "2.0.0"}},
+		{">= 1.7, < 1.8", []string{"1.7.18"}, []string{"1.8.0"}},
+		{"~1.7", []string{"1.7.0", "1.7.18"}, []string{"1.8.0", "1.6.0"}},
+		{"~1.7.18", []string{"1.7.18", "1.7.19"}, []string{"1.7.17", "1.8.0"}},
+		{"~1", []string{"1.0.0", "1.9.9"}, []string{"2.0.0"}},
+		{"^1.2", []string{"1.2.0", "1.9.0"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0", []string{"0.0.1", "0.9.0"}, []string{"1.0.0"}},
+		{"v1.x", []string{"v1.0.0", "v1.2.3"}, []string{"v2.0.0", "v0.9.0"}},
+		{"1.2.*", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, nil},
+		{"1.7", []string{"1.7.0", "1.7.18"}, []string{"1.8.0"}},
+		{"=1.7.18", []string{"1.7.18", "v1.7.18"}, []string{"1.7.19"}},
+		{"!=1.7.18", []string{"1.7.19"}, []string{"1.7.18"}},
+		{">1.7", []string{"1.8.0"}, []string{"1.7.99"}},
+		{">1.7.18", []string{"1.7.19"}, []string{"1.7.18"}},
+		{"<=1.7", []string{"1.7.99"}, []string{"1.8.0"}},
+		{"<=1.7.18", []string{"1.7.18"}, []string{"1.7.19"}},
+		{"1.2 - 1.4", []string{"1.2.0", "1.4.9"}, []string{"1.1.9", "1.5.0"}},
+		{"1.2.3 - 1.4.5", []string{"1.2.3", "1.4.5"}, []string{"1.2.2", "1.4.6"}},
+		{"<1.0 || >=2.0", []string{"0.9.0", "2.1.0"}, []string{"1.5.0"}},
+		{">=1.0.0-beta <1.0.0", []string{"1.0.0-rc.1"}, []string{"1.0.0", "0.9.0"}},
+		// prerelease versions match only ranges naming a prerelease of the same version
+		{">=1.2", []string{"1.3.0"}, []string{"1.3.0-beta.1", "2.0.0-rc.1"}},
+		{"^1.2.3-beta.1", []string{"1.2.3-beta.2", "1.2.3", "1.5.0"}, []string{"1.2.3-alpha", "1.5.0-rc.1"}},
+		{"~1.7 || >=2.0.0-rc.1", []string{"1.7.2", "2.0.0-rc.2", "2.1.0"}, []string{"1.7.3-beta", "2.1.0-rc.1"}},
+		{"*", nil, []string{"1.0.0-beta"}},
+		// non-semver versions never match
+		{"*", nil, []string{"3450100a", "abc"}},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.constraint, err)
+			continue
+		}
+		for _, v := range tt.matches {
+			if !c.Check(v) {
+				t.Errorf("%q: expected %s to match", tt.constraint, v)
+			}
+		}
+		for _, v := range tt.mismatches {
+			if c.Check(v) {
+				t.Errorf("%q: expected %s to mismatch", tt.constraint, v)
+			}
+		}
+	}
+}
+
+func TestParseConstraint_Invalid(t *testing.T) {
+	for _, constraint := range []string{"", "   ", ">=abc", "1.2.3.4", "!=1.7", "1.2-beta", ">=1.0 ||", "~>1.0"} {
+		if _, err := ParseConstraint(constraint); !errors.Is(err, ErrInvalidConstraint) {
+			t.Errorf("%q: expected ErrInvalidConstraint, got %v", constraint, err)
+		}
+	}
+}
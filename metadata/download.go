@@ -0,0 +1,156 @@
+package metadata
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultReleaseURL is where binary zips are published, as assets of the release {name}/{goVersion}
+const DefaultReleaseURL = "https://github.com/goplus/llpkg/releases/download"
+
+// ChecksumsFileName is the release asset listing sha256 of the other assets in the format of sha256sum
+const ChecksumsFileName = "SHA256SUMS"
+
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrNoBinary         = errors.New("no binary found")
+)
+
+// BinaryFileName returns the file name of the binary zip for GOOS_GOARCH, e.g. cjson_linux_amd64.zip
+func BinaryFileName(name, platform string) string {
+	return name + "_" + platform + ".zip"
+}
+
+// BinaryURL returns the URL of the binary zip of the Go version for GOOS_GOARCH published under releaseURL
+func BinaryURL(releaseURL, name, goVer, platform string) string {
+	return strings.TrimSuffix(releaseURL, "/") + "/" + name + "/" + goVer + "/" + BinaryFileName(name, platform)
+}
+
+// VerifyChecksum checks the file against the hex encoded sha256
+func VerifyChecksum(fileName, checksum string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, checksum) {
+		return fmt.Errorf("%w: %s: expected sha256 %s, got %s", ErrChecksumMismatch, fileName, checksum, actual)
+	}
+	return nil
+}
+
+// download saves the response of url into the file
+func download(ctx context.Context, url string, f *os.File) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: HTTP error %d: %s", url, resp.StatusCode, resp.Status)
+	}
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
+
+// DownloadBinary downloads the binary zip of the Go version for GOOS_GOARCH from releaseURL,
+// and extracts it into dir after verifying its sha256 against the release recorded in the metadata.
+// Nothing is extracted if the checksum mismatches or isn't recorded.
+func DownloadBinary(ctx context.Context, mgr Manager, releaseURL, name, goVer, platform, dir string) error {
+	release, err := mgr.ReleaseInfo(ctx, name, goVer)
+	if err != nil {
+		return err
+	}
+	checksum, ok := release.Checksums[platform]
+	if !ok {
+		return fmt.Errorf("%w: %s %s for %s, available: %v", ErrNoBinary, name, goVer, platform, release.Platforms())
+	}
+
+	f, err := os.CreateTemp("", "llpkg-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = download(ctx, BinaryURL(releaseURL, name, goVer, platform), f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := VerifyChecksum(f.Name(), checksum); err != nil {
+		return err
+	}
+	return Unzip(f.Name(), dir)
+}
+
+// Unzip extracts the zip file into the directory dir, creating dir if necessary.
+// Entries escaping dir, e.g. ../evil or absolute paths, are rejected before anything is extracted.
+func Unzip(fileName, dir string) error {
+	r, err := zip.OpenReader(fileName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Zip names entries with forward slashes, backslashes are normalized
+	// for archives made by broken Windows tools.
+	names := make([]string, len(r.File))
+	for i, f := range r.File {
+		names[i] = filepath.FromSlash(strings.ReplaceAll(f.Name, `\`, "/"))
+		if !filepath.IsLocal(names[i]) {
+			return fmt.Errorf("unzip %s: invalid file name %s", fileName, f.Name)
+		}
+	}
+	for i, f := range r.File {
+		path := filepath.Join(dir, names[i])
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0777); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return err
+		}
+		if err := extractFile(f, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the content of the zip entry to path
+func extractFile(f *zip.File, path string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0666|f.Mode()&0777)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return &os.PathError{Op: "Unzip", Path: path, Err: err}
+	}
+	return w.Close()
+}
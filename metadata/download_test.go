@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryURL(t *testing.T) {
+	url := BinaryURL(DefaultReleaseURL+"/", "cjson", "v1.0.0", "linux_amd64")
+	if url != "https://github.com/goplus/llpkg/releases/download/cjson/v1.0.0/cjson_linux_amd64.zip" {
+		t.Errorf("unexpected URL: %s", url)
+	}
+}
+
+func TestDownloadBinary(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	zw, _ := w.Create("lib/pkgconfig/libcjson.pc")
+	zw.Write([]byte("Name: libcjson"))
+	w.Close()
+	binary := buf.Bytes()
+	digest := sha256.Sum256(binary)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cjson/v1.0.0/cjson_linux_amd64.zip":
+			w.Write(binary)
+		case "/cjson/v1.0.0/cjson_darwin_arm64.zip":
+			w.Write(append(binary, "tampered"...))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	mgr := NewMemoryManager(MetadataMap{
+		"cjson": {
+			Versions: map[CVersion][]GoVersion{"1.7.18": {"v1.0.0"}},
+			Releases: map[GoVersion]*Release{"v1.0.0": {Checksums: map[string]string{
+				"linux_amd64":  hex.EncodeToString(digest[:]),
+				"darwin_arm64": hex.EncodeToString(digest[:]),
+			}}},
+		},
+	})
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := DownloadBinary(ctx, mgr, server.URL, "cjson", "v1.0.0", "linux_amd64", dir); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "lib", "pkgconfig", "libcjson.pc")); err != nil || string(content) != "Name: libcjson" {
+		t.Errorf("unexpected content: %s %v", content, err)
+	}
+
+	dir = t.TempDir()
+	err := DownloadBinary(ctx, mgr, server.URL, "cjson", "v1.0.0", "darwin_arm64", dir)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing extracted, got %v", entries)
+	}
+
+	err = DownloadBinary(ctx, mgr, server.URL, "cjson", "v1.0.0", "windows_amd64", t.TempDir())
+	if !errors.Is(err, ErrNoBinary) {
+		t.Errorf("Expected ErrNoBinary, got %v", err)
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	zipFile := filepath.Join(t.TempDir(), "test.zip")
+	f, _ := os.Create(zipFile)
+	w := zip.NewWriter(f)
+	zw, _ := w.Create("lib/pkgconfig/libcjson.pc")
+	zw.Write([]byte("Name: libcjson"))
+	w.Close()
+	f.Close()
+	dir := t.TempDir()
+	if err := Unzip(zipFile, dir); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "lib", "pkgconfig", "libcjson.pc"))
+	if err != nil || string(content) != "Name: libcjson" {
+		t.Errorf("unexpected content: %s %v", content, err)
+	}
+
+	// nothing is extracted if any entry escapes the directory
+	evil := filepath.Join(t.TempDir(), "evil.zip")
+	f, _ = os.Create(evil)
+	w = zip.NewWriter(f)
+	for _, name := range []string{"ok.txt", "../evil.txt"} {
+		zw, _ := w.Create(name)
+		zw.Write([]byte(name))
+	}
+	w.Close()
+	f.Close()
+	dir = t.TempDir()
+	if err := Unzip(evil, dir); err == nil {
+		t.Error("Expected error of ../evil.txt")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ok.txt")); err == nil {
+		t.Error("Expected nothing extracted")
+	}
+}
@@ -0,0 +1,29 @@
+package metadata
+
+import "context"
+
+// memoryStore is a metadataStore which never refreshes its data
+type memoryStore struct {
+	data MetadataMap
+}
+
+func (s *memoryStore) Data() MetadataMap {
+	return s.data
+}
+
+func (s *memoryStore) Update(_ context.Context) error {
+	return nil
+}
+
+// NewMemoryManager returns a Manager serving the given metadata from memory,
+// which is useful for testing and fully offline usage.
+// The metadata is deep copied, so modifying data later doesn't affect the Manager.
+func NewMemoryManager(data MetadataMap) Manager {
+	copied := make(MetadataMap, len(data))
+	for name, metadata := range data {
+		copied[name] = metadata.clone()
+	}
+	// building flat maps from memory never fails
+	mgr, _ := newMetadataMgrFromStore(&memoryStore{data: copied})
+	return mgr
+}
@@ -0,0 +1,119 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMemoryManager(t *testing.T) {
+	data := MetadataMap{
+		"test-module": &Metadata{
+			Versions: map[CVersion][]GoVersion{
+				"1.7.18": {"v1.2.0", "v1.2.1"},
+				"1.8.0":  {"v1.4.0"},
+			},
+		},
+	}
+	var mgr Manager = NewMemoryManager(data)
+	ctx := context.Background()
+
+	// modifying the original data doesn't affect the manager
+	data["test-module"].Versions["1.7.18"][0] = "v9.9.9"
+	data["other-module"] = &Metadata{}
+
+	exists, err := mgr.ModuleExists(ctx, "test-module")
+	if err != nil || !exists {
+		t.Fatalf("Expected module to exist: %v", err)
+	}
+	exists, err = mgr.ModuleExists(ctx, "other-module")
+	if err != nil || exists {
+		t.Fatalf("Expected module to not exist: %v", err)
+	}
+
+	latestGoVer, err := mgr.LatestGoVer(ctx, "test-module")
+	if err != nil || latestGoVer != "v1.4.0" {
+		t.Errorf("Expected latest Go version v1.4.0, got %s: %v", latestGoVer, err)
+	}
+
+	latestCVer, err := mgr.LatestCVer(ctx, "test-module")
+	if err != nil || latestCVer != "1.8.0" {
+		t.Errorf("Expected latest C version 1.8.0, got %s: %v", latestCVer, err)
+	}
+
+	goVers, err := mgr.GoVersFromCVer(ctx, "test-module", "1.7.18")
+	if err != nil || !reflect.DeepEqual(goVers, []string{"v1.2.0", "v1.2.1"}) {
+		t.Errorf("Unexpected Go versions %v: %v", goVers, err)
+	}
+
+	cVer, err := mgr.CVerFromGoVer(ctx, "test-module", "v1.2.1")
+	if err != nil || cVer != "1.7.18" {
+		t.Errorf("Expected C version 1.7.18, got %s: %v", cVer, err)
+	}
+
+	cVers, err := mgr.AllCVersFromName(ctx, "test-module")
+	slices.Sort(cVers)
+	if err != nil || !reflect.DeepEqual(cVers, []string{"1.7.18", "1.8.0"}) {
+		t.Errorf("Unexpected C versions %v: %v", cVers, err)
+	}
+
+	if _, err := mgr.CVerFromGoVer(ctx, "test-module", "v0.0.1"); err == nil {
+		t.Error("Expected error for unknown Go version")
+	}
+
+	all, err := mgr.AllMetadata(ctx)
+	if err != nil || len(all) != 1 {
+		t.Errorf("Unexpected metadata %v: %v", all, err)
+	}
+}
+
+func TestMetadataByName_Copy(t *testing.T) {
+	mgr := NewMemoryManager(MetadataMap{
+		"test-module": &Metadata{
+			Versions: map[CVersion][]GoVersion{"1.7.18": {"v1.2.0"}},
+			Keywords: []string{"json"},
+			Releases: map[GoVersion]*Release{
+				"v1.2.0": {Checksums: map[string]string{"linux_amd64": "abc"}},
+			},
+			Yanked:     map[GoVersion]string{"v1.1.0": "broken"},
+			Deprecated: &Deprecation{Reason: "unmaintained"},
+		},
+	})
+	ctx := context.Background()
+
+	// modifying the returned metadata doesn't affect the manager
+	metadata, err := mgr.MetadataByName(ctx, "test-module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata.Versions["1.7.18"][0] = "v9.9.9"
+	metadata.Versions["1.8.0"] = []GoVersion{"v1.4.0"}
+	metadata.Keywords[0] = "xml"
+	metadata.Releases["v1.2.0"].Checksums["linux_amd64"] = "def"
+	metadata.Yanked["v1.2.0"] = "broken"
+	metadata.Deprecated.Reason = "changed"
+
+	release, err := mgr.ReleaseInfo(ctx, "test-module", "v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release.Checksums["darwin_arm64"] = "def"
+
+	want := Metadata{
+		Versions: map[CVersion][]GoVersion{"1.7.18": {"v1.2.0"}},
+		Keywords: []string{"json"},
+		Releases: map[GoVersion]*Release{
+			"v1.2.0": {Checksums: map[string]string{"linux_amd64": "abc"}},
+		},
+		Yanked:     map[GoVersion]string{"v1.1.0": "broken"},
+		Deprecated: &Deprecation{Reason: "unmaintained"},
+	}
+	got, err := mgr.MetadataByName(ctx, "test-module")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected metadata %+v, got %+v", want, got)
+	}
+}
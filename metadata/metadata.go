@@ -1,14 +1,29 @@
 package metadata
 
 import (
+	"context"
 	"errors"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
+// MirrorsEnv is the environment variable holding a comma or space separated list
+// of mirrors of llpkgstore.json, which is used when no mirror is passed to NewMetadataMgr.
+const MirrorsEnv = "LLPKGSTORE_MIRRORS"
+
 var (
 	remoteMetadataURL      = "https://goplus.github.io/llpkg/llpkgstore.json" // change only for testing
 	cachedMetadataFileName = "llpkgstore.json"
 	ErrMetadataNotInCache  = errors.New("metadata not in cache")
+	ErrReleaseNotFound     = errors.New("release info not found")
+	ErrNoVersionMapping    = errors.New("no version mappings")
 )
 
 type CVersion = string
@@ -19,31 +34,212 @@ type MetadataMap map[string]*Metadata
 
 type Metadata struct {
 	Versions map[CVersion][]GoVersion `json:"versions"`
+	// VersionScheme is how C versions are ordered, empty means semver.
+	// It's the same as upstream.package.versionScheme in llpkg.cfg.
+	VersionScheme string `json:"versionScheme,omitempty"`
+
+	// Optional descriptive fields for searching
+	Description string   `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+
+	// Releases records the details of each Go version.
+	// It's optional, Go versions published before it's introduced have no record.
+	Releases map[GoVersion]*Release `json:"releases,omitempty"`
+
+	// Yanked maps yanked Go versions to the reason.
+	// Yanked Go versions are excluded from latest and constraint resolution,
+	// but still resolvable by exact version.
+	Yanked map[GoVersion]string `json:"yanked,omitempty"`
+	// Deprecated is set if the whole package is deprecated
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+// Deprecation describes why a package is deprecated and what to use instead
+type Deprecation struct {
+	Reason string `json:"reason,omitempty"`
+	// Replacement is the name of the package replacing this one, optional
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// IsYanked returns true if the Go version is yanked
+func (m *Metadata) IsYanked(goVersion GoVersion) bool {
+	_, ok := m.Yanked[goVersion]
+	return ok
+}
+
+// clone returns a deep copy of m, so the copy can be modified without affecting m
+func (m *Metadata) clone() *Metadata {
+	copied := *m
+	copied.Versions = make(map[CVersion][]GoVersion, len(m.Versions))
+	for cVersion, goVersions := range m.Versions {
+		copied.Versions[cVersion] = slices.Clone(goVersions)
+	}
+	copied.Keywords = slices.Clone(m.Keywords)
+	copied.Yanked = maps.Clone(m.Yanked)
+	if m.Deprecated != nil {
+		deprecated := *m.Deprecated
+		copied.Deprecated = &deprecated
+	}
+	if m.Releases != nil {
+		copied.Releases = make(map[GoVersion]*Release, len(m.Releases))
+		for goVersion, release := range m.Releases {
+			if release != nil {
+				release = release.clone()
+			}
+			copied.Releases[goVersion] = release
+		}
+	}
+	return &copied
+}
+
+// Release records the details of a published Go version
+type Release struct {
+	// PublishedAt is the time when the Go version is published
+	PublishedAt time.Time `json:"publishedAt"`
+	// Commit is the SHA of the tagged commit
+	Commit string `json:"commit,omitempty"`
+	// Installer is the name of the upstream installer, e.g. conan
+	Installer string `json:"installer,omitempty"`
+	// Checksums maps GOOS_GOARCH to the hex encoded sha256 of the binary zip
+	Checksums map[string]string `json:"checksums,omitempty"`
 }
 
+// Platforms returns all GOOS_GOARCH having binaries, sorted
+func (r *Release) Platforms() []string {
+	platforms := make([]string, 0, len(r.Checksums))
+	for platform := range r.Checksums {
+		platforms = append(platforms, platform)
+	}
+	slices.Sort(platforms)
+	return platforms
+}
+
+// clone returns a deep copy of r
+func (r *Release) clone() *Release {
+	copied := *r
+	copied.Checksums = maps.Clone(r.Checksums)
+	return &copied
+}
+
+// Manager queries the version mappings of llpkgs.
+// All methods may refresh the underlying metadata, which is cancelled by ctx.
+type Manager interface {
+	// AllMetadata returns all up-to-date metadata
+	AllMetadata(ctx context.Context) (MetadataMap, error)
+	// MetadataByName returns the up-to-date module metadata by name
+	MetadataByName(ctx context.Context, name string) (Metadata, error)
+	// ModuleExists returns true if the name is an exist module name
+	ModuleExists(ctx context.Context, name string) (bool, error)
+
+	// LatestCVer returns the latest C version associated with the latest Go version
+	LatestCVer(ctx context.Context, name string) (string, error)
+	// LatestGoVer returns the latest Go version for the given module name
+	LatestGoVer(ctx context.Context, name string) (string, error)
+	// LatestGoVerFromCVer returns the latest Go version based on the module name and C version
+	LatestGoVerFromCVer(ctx context.Context, name, cVer string) (string, error)
+	// GoVersFromCVer returns Go versions based on the module name and C version
+	GoVersFromCVer(ctx context.Context, name, cVer string) ([]string, error)
+	// CVerFromGoVer returns the C version based on the module name and Go version
+	CVerFromGoVer(ctx context.Context, name, goVer string) (string, error)
+	// AllGoVersFromName returns all Go versions for the given module name
+	AllGoVersFromName(ctx context.Context, name string) ([]string, error)
+	// AllCVersFromName returns all C versions for the given module name
+	AllCVersFromName(ctx context.Context, name string) ([]string, error)
+
+	// GoVersFromConstraint returns Go versions satisfying the constraint, sorted descending
+	GoVersFromConstraint(ctx context.Context, name, constraint string) ([]string, error)
+	// GoVersFromCConstraint returns Go versions whose C version satisfies the constraint, sorted descending
+	GoVersFromCConstraint(ctx context.Context, name, constraint string) ([]string, error)
+
+	// Search returns modules matching term, ordered by relevance
+	Search(ctx context.Context, term string) ([]SearchResult, error)
+	// ModulesWithPrefix returns names of modules starting with prefix, sorted
+	ModulesWithPrefix(ctx context.Context, prefix string) ([]string, error)
+
+	// ReleaseInfo returns the release details of the Go version
+	ReleaseInfo(ctx context.Context, name, goVer string) (Release, error)
+
+	// Yanked reports whether the Go version is yanked and why
+	Yanked(ctx context.Context, name, goVer string) (yanked bool, reason string, err error)
+	// Deprecation returns the deprecation of the module, or nil if it's not deprecated
+	Deprecation(ctx context.Context, name string) (*Deprecation, error)
+}
+
+// metadataStore provides the metadata for metadataMgr
+type metadataStore interface {
+	Data() MetadataMap
+	Update(ctx context.Context) error
+}
+
+// make sure metadataMgr implements Manager
+var _ Manager = (*metadataMgr)(nil)
+
+// metadataMgr is safe for concurrent use.
+// Readers work on an immutable snapshot, which is replaced as a whole after updating,
+// and concurrent updates are merged into one.
 type metadataMgr struct {
-	cache *Cache[MetadataMap]
+	cache metadataStore
+
+	snapshot atomic.Pointer[versionSnapshot]
+	updates  singleflight.Group
+}
+
+// versionSnapshot is an immutable view of the metadata,
+// it MUST not be modified after building.
+type versionSnapshot struct {
+	metadata MetadataMap
 
 	// Add flat hash for optimization
 	flatCToGo map[flatKey][]string // "name/cversion" -> []goversion
 	flatGoToC map[flatKey]string   // "name/goversion" -> cversion
 }
 
-// NewMetadataMgr returns a new metadata manager
-func NewMetadataMgr(cacheDir string) (*metadataMgr, error) {
-	cachePath := filepath.Join(cacheDir, cachedMetadataFileName)
-	cache, err := NewCache[MetadataMap](cachePath, remoteMetadataURL)
+// Mirrors returns the mirrors of llpkgstore.json in priority order.
+// Mirrors from LLPKGSTORE_MIRRORS take precedence, and the default remote is used
+// if the environment variable is not set.
+func Mirrors() []string {
+	mirrors := strings.FieldsFunc(os.Getenv(MirrorsEnv), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(mirrors) == 0 {
+		return []string{remoteMetadataURL}
+	}
+	return mirrors
+}
+
+// NewMetadataMgr returns a new HTTP-backed metadata manager.
+// mirrors are tried in order when fetching llpkgstore.json,
+// if it's empty, the result of Mirrors is used.
+// A file:// URL is accepted for fully local registries.
+// Both remote data and the local cache are verified against the pinned public keys,
+// data with an invalid signature is never used, and an unverified cache is fetched again.
+// Unsigned data is accepted until signatures are required, see RequireSignatureEnv.
+func NewMetadataMgr(cacheDir string, mirrors ...string) (Manager, error) {
+	verify, err := pinnedVerifier()
 	if err != nil {
 		return nil, err
 	}
+	return newMetadataMgr(cacheDir, verify, mirrors...)
+}
 
-	mgr := &metadataMgr{
-		cache:     cache,
-		flatCToGo: make(map[flatKey][]string),
-		flatGoToC: make(map[flatKey]string),
+// newMetadataMgr is NewMetadataMgr verifying data by verify, a nil verify disables verification for testing
+func newMetadataMgr(cacheDir string, verify Verifier, mirrors ...string) (*metadataMgr, error) {
+	if len(mirrors) == 0 {
+		mirrors = Mirrors()
+	}
+	cachePath := filepath.Join(cacheDir, cachedMetadataFileName)
+	cache, err := NewVerifiedCache[MetadataMap](cachePath, verify, mirrors...)
+	if err != nil {
+		return nil, err
 	}
 
-	err = mgr.buildFlatVersionMaps()
+	return newMetadataMgrFromStore(cache)
+}
+
+func newMetadataMgrFromStore(store metadataStore) (*metadataMgr, error) {
+	mgr := &metadataMgr{cache: store}
+
+	err := mgr.buildFlatVersionMaps()
 	if err != nil {
 		return nil, err
 	}
@@ -51,9 +247,10 @@ func NewMetadataMgr(cacheDir string) (*metadataMgr, error) {
 	return mgr, nil
 }
 
-// Returns all up-to-date metadata
-func (m *metadataMgr) AllMetadata() (MetadataMap, error) {
-	err := m.update()
+// Returns all up-to-date metadata.
+// The result is shared with other readers and MUST not be modified.
+func (m *metadataMgr) AllMetadata(ctx context.Context) (MetadataMap, error) {
+	err := m.update(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -61,12 +258,12 @@ func (m *metadataMgr) AllMetadata() (MetadataMap, error) {
 }
 
 // Returns the up-to-date module metadata by name
-func (m *metadataMgr) MetadataByName(name string) (Metadata, error) {
+func (m *metadataMgr) MetadataByName(ctx context.Context, name string) (Metadata, error) {
 	// First try to find the module metadata in the cache
 	metadata, err := m.cachedMetadataByName(name)
 	if errors.Is(err, ErrMetadataNotInCache) || errors.Is(err, ErrCacheFileNotFound) {
 		// If the module metadata is not in the cache, update the cache
-		err := m.update()
+		err := m.update(ctx)
 		if err != nil {
 			return Metadata{}, err
 		}
@@ -82,8 +279,8 @@ func (m *metadataMgr) MetadataByName(name string) (Metadata, error) {
 }
 
 // Returns true if the name is an exist module name
-func (m *metadataMgr) ModuleExists(name string) (bool, error) {
-	_, err := m.MetadataByName(name)
+func (m *metadataMgr) ModuleExists(ctx context.Context, name string) (bool, error) {
+	_, err := m.MetadataByName(ctx, name)
 	if errors.Is(err, ErrMetadataNotInCache) {
 		return false, nil
 	} else if err != nil {
@@ -92,13 +289,42 @@ func (m *metadataMgr) ModuleExists(name string) (bool, error) {
 	return true, nil
 }
 
+// Returns the URL of the mirror which served the current metadata,
+// or empty if the metadata is not loaded from a remote mirror.
+func (m *metadataMgr) Source() string {
+	if cache, ok := m.cache.(*Cache[MetadataMap]); ok {
+		return cache.Source()
+	}
+	return ""
+}
+
+// Returns the current snapshot
+func (m *metadataMgr) current() *versionSnapshot {
+	return m.snapshot.Load()
+}
+
 // Returns the module metadata in the cache
 func (m *metadataMgr) allCachedMetadata() MetadataMap {
-	cache := m.cache.Data()
-	return cache
+	return m.current().metadata
 }
 
-// Returns the module metadata in the cache by name
+// Returns versions excluding the yanked ones of the module
+func (m *metadataMgr) withoutYanked(name string, goVersions []string) []string {
+	metadata, ok := m.allCachedMetadata()[name]
+	if !ok || len(metadata.Yanked) == 0 {
+		return goVersions
+	}
+	ret := make([]string, 0, len(goVersions))
+	for _, goVersion := range goVersions {
+		if !metadata.IsYanked(goVersion) {
+			ret = append(ret, goVersion)
+		}
+	}
+	return ret
+}
+
+// Returns a copy of the module metadata in the cache by name,
+// so the caller may modify it without affecting other readers.
 func (m *metadataMgr) cachedMetadataByName(name string) (Metadata, error) {
 	allMetadata := m.allCachedMetadata()
 
@@ -107,43 +333,51 @@ func (m *metadataMgr) cachedMetadataByName(name string) (Metadata, error) {
 		return Metadata{}, ErrMetadataNotInCache
 	}
 
-	return *metadata, nil
+	return *metadata.clone(), nil
 }
 
-func (m *metadataMgr) update() error {
-	err := m.cache.Update()
-	if err != nil {
-		return err
-	}
-
-	err = m.buildFlatVersionMaps()
-	if err != nil {
-		return err
+// update refreshes the metadata, concurrent calls share the same update.
+func (m *metadataMgr) update(ctx context.Context) error {
+	ch := m.updates.DoChan("update", func() (any, error) {
+		// don't let one caller cancel the update shared with others
+		err := m.cache.Update(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return nil, m.buildFlatVersionMaps()
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case ret := <-ch:
+		return ret.Err
 	}
-
-	return nil
 }
 
+// buildFlatVersionMaps builds a new snapshot from the store and publishes it
 func (m *metadataMgr) buildFlatVersionMaps() error {
-	// Reset flat hash
-	m.flatCToGo = make(map[flatKey][]string)
-	m.flatGoToC = make(map[flatKey]string)
+	allCachedMetadata := m.cache.Data()
 
-	allCachedMetadata := m.allCachedMetadata()
+	snapshot := &versionSnapshot{
+		metadata:  allCachedMetadata,
+		flatCToGo: make(map[flatKey][]string),
+		flatGoToC: make(map[flatKey]string),
+	}
 
 	for name, metadata := range allCachedMetadata {
 		versions := metadata.Versions
 		for cVersion, goVersions := range versions {
 			// Build flat hash
 			cKey := flatKey{name, cVersion}
-			m.flatCToGo[cKey] = goVersions
+			snapshot.flatCToGo[cKey] = goVersions
 
 			for _, goVersion := range goVersions {
 				goKey := flatKey{name, goVersion}
-				m.flatGoToC[goKey] = cVersion
+				snapshot.flatGoToC[goKey] = cVersion
 			}
 		}
 	}
 
+	m.snapshot.Store(snapshot)
 	return nil
 }
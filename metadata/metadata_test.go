@@ -1,14 +1,19 @@
 package metadata
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var testMetadata = MetadataMap{
@@ -37,7 +42,7 @@ func TestNewMetadataMgr(t *testing.T) {
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
 	tmpDir := t.TempDir()
-	mgr, err := NewMetadataMgr(tmpDir)
+	mgr, err := newMetadataMgr(tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create metadata manager: %v", err)
 	}
@@ -67,16 +72,16 @@ func TestMetadataMgr_AllMetadata(t *testing.T) {
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
 	tmpDir := t.TempDir()
-	mgr, _ := NewMetadataMgr(tmpDir)
+	mgr, _ := newMetadataMgr(tmpDir, nil)
 
 	// Force metadata update from mock server
-	err := mgr.update()
+	err := mgr.update(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to update metadata: %v", err)
 	}
 
 	// Retrieve and validate all metadata
-	data, err := mgr.AllMetadata()
+	data, err := mgr.AllMetadata(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to retrieve metadata: %v", err)
 	}
@@ -104,7 +109,7 @@ func TestNewMetadataMgr_LocalAndRemoteFailure(t *testing.T) {
 	defer func() { remoteMetadataURL = originalURL }()
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
-	_, err = NewMetadataMgr(tmpDir)
+	_, err = newMetadataMgr(tmpDir, nil)
 	if err == nil {
 		t.Fatal("Expected error, but got nil")
 	}
@@ -126,9 +131,9 @@ func TestMetadataMgr_MetadataByName_Existing(t *testing.T) {
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
 	tmpDir := t.TempDir()
-	mgr, _ := NewMetadataMgr(tmpDir)
+	mgr, _ := newMetadataMgr(tmpDir, nil)
 
-	metadata, err := mgr.MetadataByName("example-module")
+	metadata, err := mgr.MetadataByName(context.Background(), "example-module")
 	if err != nil {
 		t.Fatalf("Failed to retrieve metadata: %v", err)
 	}
@@ -154,9 +159,9 @@ func TestMetadataMgr_MetadataByName_NotFound(t *testing.T) {
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
 	tmpDir := t.TempDir()
-	mgr, _ := NewMetadataMgr(tmpDir)
+	mgr, _ := newMetadataMgr(tmpDir, nil)
 
-	_, err := mgr.MetadataByName("nonexistent-module")
+	_, err := mgr.MetadataByName(context.Background(), "nonexistent-module")
 	if !errors.Is(err, ErrMetadataNotInCache) {
 		t.Errorf("Expected ErrMetadataNotInCache, but got: %v", err)
 	}
@@ -178,9 +183,9 @@ func TestMetadataMgr_ModuleExists(t *testing.T) {
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
 	tmpDir := t.TempDir()
-	mgr, _ := NewMetadataMgr(tmpDir)
+	mgr, _ := newMetadataMgr(tmpDir, nil)
 
-	exists, err := mgr.ModuleExists("example-module")
+	exists, err := mgr.ModuleExists(context.Background(), "example-module")
 	if err != nil {
 		t.Fatalf("Failed to check module existence: %v", err)
 	}
@@ -188,7 +193,7 @@ func TestMetadataMgr_ModuleExists(t *testing.T) {
 		t.Fatal("Expected module to exist, but it doesn't")
 	}
 
-	exists, err = mgr.ModuleExists("nonexistent-module")
+	exists, err = mgr.ModuleExists(context.Background(), "nonexistent-module")
 	if err != nil {
 		t.Fatalf("Failed to check module existence: %v", err)
 	}
@@ -220,12 +225,12 @@ func TestMetadataMgr_UpdateError(t *testing.T) {
 	tmpDir := t.TempDir()
 	// ensure metadataMgr can be created
 	os.WriteFile(filepath.Join(tmpDir, "llpkgstore.json"), testMetadataJSON, 0644)
-	mgr, err := NewMetadataMgr(tmpDir)
+	mgr, err := newMetadataMgr(tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create metadata manager: %v", err)
 	}
 
-	err = mgr.update()
+	err = mgr.update(context.Background())
 	if err == nil {
 		t.Fatal("Expected error, but got nil")
 	}
@@ -249,7 +254,7 @@ func TestNewMetadataMgr_InvalidRemoteData(t *testing.T) {
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
 	tmpDir := t.TempDir()
-	_, err := NewMetadataMgr(tmpDir)
+	_, err := newMetadataMgr(tmpDir, nil)
 	if err == nil {
 		t.Fatal("Expected error, but got nil")
 	}
@@ -278,12 +283,12 @@ func TestNewMetadataMgr_BadCacheFile(t *testing.T) {
 	defer func() { remoteMetadataURL = originalURL }()
 	remoteMetadataURL = server.URL + "/llpkgstore.json"
 
-	mgr, err := NewMetadataMgr(tmpDir)
+	mgr, err := newMetadataMgr(tmpDir, nil)
 	if err != nil {
 		t.Fatalf("Failed to create metadata manager: %v", err)
 	}
 
-	data, err := mgr.AllMetadata()
+	data, err := mgr.AllMetadata(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to retrieve metadata: %v", err)
 	}
@@ -291,3 +296,153 @@ func TestNewMetadataMgr_BadCacheFile(t *testing.T) {
 		t.Errorf("Metadata mismatch. Expected: %v, Got: %v", testMetadata, data)
 	}
 }
+
+// TestNewMetadataMgr_Mirrors verifies mirrors from arguments and LLPKGSTORE_MIRRORS are used in order
+func TestNewMetadataMgr_Mirrors(t *testing.T) {
+	badServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer badServer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(testMetadata)
+	}))
+	defer server.Close()
+
+	mgr, err := newMetadataMgr(t.TempDir(), nil, badServer.URL, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+	if mgr.Source() != server.URL {
+		t.Errorf("Unexpected source. Expected: %s, Got: %s", server.URL, mgr.Source())
+	}
+
+	t.Setenv(MirrorsEnv, badServer.URL+", "+server.URL)
+	if mirrors := Mirrors(); !reflect.DeepEqual(mirrors, []string{badServer.URL, server.URL}) {
+		t.Errorf("Unexpected mirrors: %v", mirrors)
+	}
+
+	mgr, err = newMetadataMgr(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+	if mgr.Source() != server.URL {
+		t.Errorf("Unexpected source. Expected: %s, Got: %s", server.URL, mgr.Source())
+	}
+}
+
+// TestMetadataMgr_ConcurrentLookups hammers lookups during updates, run it with -race
+func TestMetadataMgr_ConcurrentLookups(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// serve a growing mapping table, so every update replaces the snapshot
+		n := requests.Add(1)
+		data := MetadataMap{
+			"example-module": &Metadata{
+				Versions: map[CVersion][]GoVersion{
+					"1.7.18": {"v1.2.1", "v1.2.0", fmt.Sprintf("v1.2.%d", n+1)},
+					"1.7.19": {"v1.3.0"},
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(data)
+	}))
+	defer server.Close()
+
+	mgr, err := newMetadataMgr(t.TempDir(), nil, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := mgr.update(ctx); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := mgr.LatestGoVerFromCVer(ctx, "example-module", "1.7.18"); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := mgr.GoVersFromCVer(ctx, "example-module", "1.7.18"); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := mgr.LatestGoVer(ctx, "example-module"); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := mgr.CVerFromGoVer(ctx, "example-module", "v1.3.0"); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := mgr.AllCVersFromName(ctx, "example-module"); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestMetadataMgr_SingleflightUpdate verifies concurrent updates share one request
+func TestMetadataMgr_SingleflightUpdate(t *testing.T) {
+	var requests atomic.Int64
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first request is for creating the manager
+		if requests.Add(1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(testMetadata)
+	}))
+	defer server.Close()
+
+	mgr, err := newMetadataMgr(t.TempDir(), nil, server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := mgr.update(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// wait for the shared update to reach the server
+	for requests.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Expected concurrent updates to share one request, got %d requests", n-1)
+	}
+}
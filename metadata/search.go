@@ -0,0 +1,150 @@
+package metadata
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Relevance of search results, the larger the better.
+const (
+	ScoreFuzzy = iota + 1
+	ScoreDescription
+	ScoreSubstring
+	ScorePrefix
+	ScoreExact
+)
+
+// SearchResult is a module matching the search term
+type SearchResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	LatestCVer  string `json:"latestCVersion,omitempty"`
+	LatestGoVer string `json:"latestGoVersion,omitempty"`
+	// Deprecated is set if the module is deprecated
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+	// Score is the relevance of the result, see Score* constants
+	Score int `json:"score"`
+}
+
+// Search returns modules whose name or descriptive fields match term, ordered by relevance.
+// A module matches if its name equals, starts with or contains term, its description
+// or keywords contain term, or its name is within a small edit distance of term.
+// An empty term matches all modules.
+func (m *metadataMgr) Search(ctx context.Context, term string) ([]SearchResult, error) {
+	allMetadata, err := m.AllMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	term = strings.ToLower(strings.TrimSpace(term))
+
+	var results []SearchResult
+	for name, metadata := range allMetadata {
+		score := matchScore(term, name, metadata)
+		if score == 0 {
+			continue
+		}
+		result := SearchResult{
+			Name:        name,
+			Description: metadata.Description,
+			Deprecated:  metadata.Deprecated,
+			Score:       score,
+		}
+		result.LatestCVer, result.LatestGoVer = latestVersions(metadata)
+		results = append(results, result)
+	}
+
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return results, nil
+}
+
+// ModulesWithPrefix returns names of modules starting with prefix, sorted
+func (m *metadataMgr) ModulesWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	allMetadata, err := m.AllMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range allMetadata {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// matchScore returns the relevance of the module for term, 0 means mismatched.
+func matchScore(term, name string, metadata *Metadata) int {
+	lowerName := strings.ToLower(name)
+	switch {
+	case term == "" || lowerName == term:
+		return ScoreExact
+	case strings.HasPrefix(lowerName, term):
+		return ScorePrefix
+	case strings.Contains(lowerName, term):
+		return ScoreSubstring
+	}
+	if strings.Contains(strings.ToLower(metadata.Description), term) {
+		return ScoreDescription
+	}
+	for _, keyword := range metadata.Keywords {
+		if strings.Contains(strings.ToLower(keyword), term) {
+			return ScoreDescription
+		}
+	}
+	// allow one typo for every three characters, e.g. cjosn => cjson
+	if editDistance(term, lowerName) <= max(1, len(term)/3) {
+		return ScoreFuzzy
+	}
+	return 0
+}
+
+// latestVersions returns the latest Go version and its C version of the module, yanked versions are excluded
+func latestVersions(metadata *Metadata) (latestCVer, latestGoVer string) {
+	for cVersion, goVersions := range metadata.Versions {
+		for _, goVersion := range goVersions {
+			if metadata.IsYanked(goVersion) {
+				continue
+			}
+			if latestGoVer == "" || semver.Compare(goVersion, latestGoVer) > 0 {
+				latestCVer, latestGoVer = cVersion, goVersion
+			}
+		}
+	}
+	return
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// which is the Levenshtein distance counting a transposition of two adjacent characters as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
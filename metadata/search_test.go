@@ -0,0 +1,120 @@
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var searchTestData = MetadataMap{
+	"cjson": &Metadata{
+		Versions: map[CVersion][]GoVersion{
+			"1.7.18": {"v1.0.0", "v1.0.1"},
+			"1.7.19": {"v1.1.0"},
+		},
+		Description: "Ultralightweight JSON parser in ANSI C",
+	},
+	"json-c": &Metadata{
+		Versions: map[CVersion][]GoVersion{"0.17": {"v0.1.0"}},
+	},
+	"libxml2": &Metadata{
+		Versions: map[CVersion][]GoVersion{"2.13.6": {"v1.0.0"}},
+		Keywords: []string{"xml", "parser"},
+	},
+	"libxslt": &Metadata{
+		Versions: map[CVersion][]GoVersion{"1.1.42": {"v1.0.0"}},
+	},
+	"zlib": &Metadata{
+		Versions: map[CVersion][]GoVersion{},
+	},
+}
+
+func searchNames(results []SearchResult) (names []string) {
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return
+}
+
+func TestSearch(t *testing.T) {
+	mgr := NewMemoryManager(searchTestData)
+	ctx := context.Background()
+
+	tests := []struct {
+		term  string
+		names []string
+	}{
+		{"cjson", []string{"cjson"}},
+		{"CJSON", []string{"cjson"}},
+		{"json", []string{"json-c", "cjson"}},
+		{"lib", []string{"libxml2", "libxslt", "zlib"}},
+		{"parser", []string{"cjson", "libxml2"}},
+		{"cjosn", []string{"cjson"}},
+		{"zlbi", []string{"zlib"}},
+		{"sqlite", nil},
+		{"", []string{"cjson", "json-c", "libxml2", "libxslt", "zlib"}},
+	}
+	for _, tt := range tests {
+		results, err := mgr.Search(ctx, tt.term)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := searchNames(results); !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("%q: expected %v, got %v", tt.term, tt.names, names)
+		}
+	}
+
+	results, err := mgr.Search(ctx, "cjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := SearchResult{
+		Name:        "cjson",
+		Description: "Ultralightweight JSON parser in ANSI C",
+		LatestCVer:  "1.7.19",
+		LatestGoVer: "v1.1.0",
+		Score:       ScoreExact,
+	}
+	if !reflect.DeepEqual(results[0], expected) {
+		t.Errorf("Expected %v, got %v", expected, results[0])
+	}
+}
+
+func TestModulesWithPrefix(t *testing.T) {
+	mgr := NewMemoryManager(searchTestData)
+
+	names, err := mgr.ModulesWithPrefix(context.Background(), "lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"libxml2", "libxslt"}) {
+		t.Errorf("Unexpected modules: %v", names)
+	}
+
+	names, err = mgr.ModulesWithPrefix(context.Background(), "rust")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected no modules, got %v", names)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"cjson", "cjson", 0},
+		{"cjosn", "cjson", 1},
+		{"abc", "ca", 3},
+		{"zlb", "zlib", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if d := editDistance(tt.a, tt.b); d != tt.distance {
+			t.Errorf("editDistance(%q, %q): expected %d, got %d", tt.a, tt.b, tt.distance, d)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package metadata
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignatureSuffix is the suffix of the detached signature file published alongside llpkgstore.json
+const SignatureSuffix = ".sig"
+
+// RequireSignatureEnv is the environment variable which, set to 1, makes a missing signature
+// of llpkgstore.json an error before signatures are required by default.
+const RequireSignatureEnv = "LLPKGSTORE_REQUIRE_SIGNATURE"
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrInvalidKey       = errors.New("invalid ed25519 key")
+	ErrNoPinnedKey      = errors.New("no public key pinned for llpkgstore.json")
+	ErrNoSignature      = errors.New("no signature published")
+
+	// base64 encoded ed25519 public keys trusted for llpkgstore.json,
+	// the private key of any of them is LLPKGSTORE_SIGNING_KEY of post-processing.
+	// Append the new key before rotating LLPKGSTORE_SIGNING_KEY, and remove the old one after that.
+	// change only for testing
+	pinnedPublicKeys = []string{
+		"8B3BvXjlMyWnmZVZfTqnF43OVl4VpMS1PHDk7E7/pW8=",
+	}
+
+	// requireSignature rejects llpkgstore.json published without a signature.
+	// Signatures are rolled out in stages: post-processing publishes llpkgstore.json.sig first,
+	// and this is turned on once the signature is served by the default remote.
+	// Until then unsigned data is accepted, but a published signature is always verified.
+	requireSignature = false
+)
+
+// Verifier verifies data with its detached signature
+type Verifier func(data, signature []byte) error
+
+// ParsePublicKey parses a base64 encoded ed25519 public key
+func ParsePublicKey(key string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParsePrivateKey parses a base64 encoded ed25519 private key,
+// both the 32 bytes seed and the 64 bytes private key are accepted.
+func ParsePrivateKey(key string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	default:
+		return nil, ErrInvalidKey
+	}
+}
+
+// Sign returns the base64 encoded detached signature of data
+func Sign(key ed25519.PrivateKey, data []byte) []byte {
+	sig := ed25519.Sign(key, data)
+	return []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+// SignFile signs fileName and writes the signature to fileName + SignatureSuffix
+func SignFile(key ed25519.PrivateKey, fileName string) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName+SignatureSuffix, Sign(key, data), 0644)
+}
+
+// Verify checks the base64 encoded signature of data against all the keys,
+// it succeeds if any of the keys matches.
+func Verify(keys []ed25519.PublicKey, data, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// NewVerifier returns a Verifier accepting signatures from any of the keys
+func NewVerifier(keys []ed25519.PublicKey) Verifier {
+	return func(data, signature []byte) error {
+		return Verify(keys, data, signature)
+	}
+}
+
+// signatureRequired reports whether data without a signature must be rejected
+func signatureRequired() bool {
+	return requireSignature || os.Getenv(RequireSignatureEnv) == "1"
+}
+
+// pinnedVerifier returns the Verifier for pinned public keys,
+// it fails instead of skipping verification if no key is pinned.
+func pinnedVerifier() (Verifier, error) {
+	if len(pinnedPublicKeys) == 0 {
+		return nil, ErrNoPinnedKey
+	}
+	keys := make([]ed25519.PublicKey, 0, len(pinnedPublicKeys))
+	for _, pinned := range pinnedPublicKeys {
+		key, err := ParsePublicKey(pinned)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewVerifier(keys), nil
+}
@@ -0,0 +1,310 @@
+package metadata
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestKey generates a throwaway ed25519 key pair
+func newTestKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return publicKey, privateKey
+}
+
+// newSignedServer serves data at /llpkgstore.json and its signature at /llpkgstore.json.sig
+func newSignedServer(t *testing.T, data, signature []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/llpkgstore.json":
+			w.Write(data)
+		case "/llpkgstore.json" + SignatureSuffix:
+			w.Write(signature)
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	otherPublicKey, _ := newTestKey(t)
+
+	data := []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0"]}}}`)
+	signature := Sign(privateKey, data)
+
+	if err := Verify([]ed25519.PublicKey{publicKey}, data, signature); err != nil {
+		t.Errorf("Expected valid signature, got: %v", err)
+	}
+	if err := Verify([]ed25519.PublicKey{otherPublicKey, publicKey}, data, signature); err != nil {
+		t.Errorf("Expected valid signature with multiple keys, got: %v", err)
+	}
+	if err := Verify([]ed25519.PublicKey{otherPublicKey}, data, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for wrong key, got: %v", err)
+	}
+	if err := Verify([]ed25519.PublicKey{publicKey}, []byte(`{}`), signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered data, got: %v", err)
+	}
+	if err := Verify([]ed25519.PublicKey{publicKey}, data, []byte("!!!")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for malformed signature, got: %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+
+	parsedPublicKey, err := ParsePublicKey(base64.StdEncoding.EncodeToString(publicKey))
+	if err != nil || !parsedPublicKey.Equal(publicKey) {
+		t.Errorf("Failed to parse public key: %v", err)
+	}
+
+	parsedPrivateKey, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey))
+	if err != nil || !parsedPrivateKey.Equal(privateKey) {
+		t.Errorf("Failed to parse private key: %v", err)
+	}
+
+	parsedPrivateKey, err = ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	if err != nil || !parsedPrivateKey.Equal(privateKey) {
+		t.Errorf("Failed to parse private key from seed: %v", err)
+	}
+
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString([]byte("short"))); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got: %v", err)
+	}
+	if _, err := ParsePrivateKey("not base64"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got: %v", err)
+	}
+}
+
+func TestSignFile(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+
+	fileName := filepath.Join(t.TempDir(), "llpkgstore.json")
+	data := []byte(`{"cjson":{"versions":{"1.7.18":["v1.0.0"]}}}`)
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := SignFile(privateKey, fileName); err != nil {
+		t.Fatalf("Failed to sign file: %v", err)
+	}
+	signature, err := os.ReadFile(fileName + SignatureSuffix)
+	if err != nil {
+		t.Fatalf("Failed to read signature: %v", err)
+	}
+	if err := Verify([]ed25519.PublicKey{publicKey}, data, signature); err != nil {
+		t.Errorf("Expected valid signature, got: %v", err)
+	}
+}
+
+func TestVerifiedCache(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	_, otherPrivateKey := newTestKey(t)
+
+	data, _ := json.Marshal(testCacheData)
+	verify := NewVerifier([]ed25519.PublicKey{publicKey})
+
+	t.Run("valid", func(t *testing.T) {
+		server := newSignedServer(t, data, Sign(privateKey, data))
+		defer server.Close()
+
+		cache, err := NewVerifiedCache[MetadataMap](filepath.Join(t.TempDir(), "cache.json"), verify, server.URL+"/llpkgstore.json")
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		if !reflect.DeepEqual(cache.Data(), testCacheData) {
+			t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+		}
+	})
+
+	t.Run("untrusted key", func(t *testing.T) {
+		server := newSignedServer(t, data, Sign(otherPrivateKey, data))
+		defer server.Close()
+
+		_, err := NewVerifiedCache[MetadataMap](filepath.Join(t.TempDir(), "cache.json"), verify, server.URL+"/llpkgstore.json")
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature, got: %v", err)
+		}
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/llpkgstore.json" {
+				w.Write(data)
+				return
+			}
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		// accepted until signatures are required
+		cachePath := filepath.Join(t.TempDir(), "cache.json")
+		os.WriteFile(cachePath+SignatureSuffix, []byte("stale"), 0644)
+		cache, err := NewVerifiedCache[MetadataMap](cachePath, verify, server.URL+"/llpkgstore.json")
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		if !reflect.DeepEqual(cache.Data(), testCacheData) {
+			t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+		}
+		if _, err := os.Stat(cachePath + SignatureSuffix); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected stale signature to be removed, got: %v", err)
+		}
+
+		t.Setenv(RequireSignatureEnv, "1")
+		_, err = NewVerifiedCache[MetadataMap](filepath.Join(t.TempDir(), "cache.json"), verify, server.URL+"/llpkgstore.json")
+		if !errors.Is(err, ErrNoSignature) {
+			t.Errorf("Expected ErrNoSignature, got: %v", err)
+		}
+	})
+
+	t.Run("failover to trusted mirror", func(t *testing.T) {
+		tampered := newSignedServer(t, []byte(`{}`), Sign(privateKey, data))
+		defer tampered.Close()
+
+		tmpDir := t.TempDir()
+		registry := filepath.Join(tmpDir, "llpkgstore.json")
+		os.WriteFile(registry, data, 0644)
+		SignFile(privateKey, registry)
+
+		cache, err := NewVerifiedCache[MetadataMap](filepath.Join(tmpDir, "cache.json"), verify,
+			tampered.URL+"/llpkgstore.json", "file://"+filepath.ToSlash(registry))
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		if !reflect.DeepEqual(cache.Data(), testCacheData) {
+			t.Errorf("Cache data mismatch. Expected: %v, Got: %v", testCacheData, cache.Data())
+		}
+	})
+}
+
+// TestNewMetadataMgr_PinnedKeys verifies the manager rejects data which isn't signed by pinned keys
+func TestNewMetadataMgr_PinnedKeys(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+	_, otherPrivateKey := newTestKey(t)
+
+	originalKeys := pinnedPublicKeys
+	defer func() { pinnedPublicKeys = originalKeys }()
+	pinnedPublicKeys = []string{base64.StdEncoding.EncodeToString(publicKey)}
+
+	data, _ := json.Marshal(testMetadata)
+
+	server := newSignedServer(t, data, Sign(privateKey, data))
+	defer server.Close()
+	if _, err := NewMetadataMgr(t.TempDir(), server.URL+"/llpkgstore.json"); err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+
+	untrusted := newSignedServer(t, data, Sign(otherPrivateKey, data))
+	defer untrusted.Close()
+	if _, err := NewMetadataMgr(t.TempDir(), untrusted.URL+"/llpkgstore.json"); err == nil {
+		t.Fatal("Expected error for untrusted signature, but got nil")
+	}
+}
+
+// TestNewMetadataMgr_ProductionKeys verifies the pinned keys reject tampered data,
+// and unsigned data only when signatures are required
+func TestNewMetadataMgr_ProductionKeys(t *testing.T) {
+	if _, err := pinnedVerifier(); err != nil {
+		t.Fatalf("Failed to parse pinned keys: %v", err)
+	}
+	_, privateKey := newTestKey(t)
+	data, _ := json.Marshal(testMetadata)
+
+	tampered := newSignedServer(t, []byte(`{"cjson":{"versions":{"1.7.18":["v9.9.9"]}}}`), Sign(privateKey, data))
+	defer tampered.Close()
+	if _, err := NewMetadataMgr(t.TempDir(), tampered.URL+"/llpkgstore.json"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered data, got: %v", err)
+	}
+
+	unsigned := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/llpkgstore.json" {
+			w.Write(data)
+			return
+		}
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}))
+	defer unsigned.Close()
+	if _, err := NewMetadataMgr(t.TempDir(), unsigned.URL+"/llpkgstore.json"); err != nil {
+		t.Errorf("Expected unsigned data to be accepted before signatures are required, got: %v", err)
+	}
+
+	originalRequire := requireSignature
+	defer func() { requireSignature = originalRequire }()
+	requireSignature = true
+	if _, err := NewMetadataMgr(t.TempDir(), unsigned.URL+"/llpkgstore.json"); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("Expected ErrNoSignature for missing signature, got: %v", err)
+	}
+
+	originalKeys := pinnedPublicKeys
+	defer func() { pinnedPublicKeys = originalKeys }()
+	pinnedPublicKeys = nil
+	if _, err := NewMetadataMgr(t.TempDir(), unsigned.URL+"/llpkgstore.json"); !errors.Is(err, ErrNoPinnedKey) {
+		t.Errorf("Expected ErrNoPinnedKey, got: %v", err)
+	}
+}
+
+// TestNewMetadataMgr_VerifiedCacheFile verifies the cache file is verified again when it's loaded,
+// and an unverified one is fetched again from the remote
+func TestNewMetadataMgr_VerifiedCacheFile(t *testing.T) {
+	publicKey, privateKey := newTestKey(t)
+
+	originalKeys := pinnedPublicKeys
+	defer func() { pinnedPublicKeys = originalKeys }()
+	pinnedPublicKeys = []string{base64.StdEncoding.EncodeToString(publicKey)}
+
+	data, _ := json.Marshal(testMetadata)
+	server := newSignedServer(t, data, Sign(privateKey, data))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	if _, err := NewMetadataMgr(cacheDir, server.URL+"/llpkgstore.json"); err != nil {
+		t.Fatalf("Failed to create metadata manager: %v", err)
+	}
+	cachePath := filepath.Join(cacheDir, cachedMetadataFileName)
+	if _, err := os.Stat(cachePath + SignatureSuffix); err != nil {
+		t.Fatalf("Expected cached signature: %v", err)
+	}
+	// loaded from the valid cache without the mirror
+	if _, err := NewMetadataMgr(cacheDir, "file:///nonexistent/llpkgstore.json"); err != nil {
+		t.Errorf("Failed to load verified cache: %v", err)
+	}
+
+	// a tampered cache is never used, even if the remote is unavailable
+	tamperedData := []byte(`{"cjson":{"versions":{"1.7.18":["v9.9.9"]}}}`)
+	os.WriteFile(cachePath, tamperedData, 0644)
+	if _, err := NewMetadataMgr(cacheDir, "file:///nonexistent/llpkgstore.json"); err == nil {
+		t.Error("Expected error for tampered cache without remote, but got nil")
+	}
+	// and it's replaced by the verified remote
+	mgr, err := NewMetadataMgr(cacheDir, server.URL+"/llpkgstore.json")
+	if err != nil {
+		t.Fatalf("Expected tampered cache to be fetched again, got: %v", err)
+	}
+	if !reflect.DeepEqual(mgr.(*metadataMgr).allCachedMetadata(), testMetadata) {
+		t.Errorf("Expected metadata from the remote, got: %v", mgr.(*metadataMgr).allCachedMetadata())
+	}
+	if cached, _ := os.ReadFile(cachePath); !reflect.DeepEqual(cached, data) {
+		t.Errorf("Expected cache file to be replaced, got: %s", cached)
+	}
+
+	// an unsigned cache is fetched again once signatures are required
+	os.Remove(cachePath + SignatureSuffix)
+	t.Setenv(RequireSignatureEnv, "1")
+	if _, err := NewMetadataMgr(cacheDir, server.URL+"/llpkgstore.json"); err != nil {
+		t.Errorf("Expected unsigned cache to be fetched again, got: %v", err)
+	}
+	if _, err := os.Stat(cachePath + SignatureSuffix); err != nil {
+		t.Errorf("Expected cached signature: %v", err)
+	}
+}
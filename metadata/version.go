@@ -1,20 +1,23 @@
 package metadata
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"golang.org/x/mod/semver"
 )
 
 // Gets the latest C version associated with the latest Go version
-func (m *metadataMgr) LatestCVer(name string) (string, error) {
-	latestGoVersion, err := m.LatestGoVer(name)
+func (m *metadataMgr) LatestCVer(ctx context.Context, name string) (string, error) {
+	latestGoVersion, err := m.LatestGoVer(ctx, name)
 	if err != nil {
 		return "", err
 	}
 
-	latestCVersion, err := m.CVerFromGoVer(name, latestGoVersion)
+	latestCVersion, err := m.CVerFromGoVer(ctx, name, latestGoVersion)
 	if err != nil {
 		return "", err
 	}
@@ -22,12 +25,13 @@ func (m *metadataMgr) LatestCVer(name string) (string, error) {
 	return latestCVersion, nil
 }
 
-// Gets the latest Go version for the given module name
-func (m *metadataMgr) LatestGoVer(name string) (string, error) {
-	allGoVersions, err := m.AllGoVersFromName(name)
+// Gets the latest Go version for the given module name, yanked versions are excluded
+func (m *metadataMgr) LatestGoVer(ctx context.Context, name string) (string, error) {
+	allGoVersions, err := m.AllGoVersFromName(ctx, name)
 	if err != nil {
 		return "", err
 	}
+	allGoVersions = m.withoutYanked(name, allGoVersions)
 
 	if len(allGoVersions) == 0 {
 		return "", fmt.Errorf("no Go versions found for %s", name)
@@ -39,27 +43,29 @@ func (m *metadataMgr) LatestGoVer(name string) (string, error) {
 	return latestGoVersion, nil
 }
 
-// Gets the latest Go version based on the module name and C version
-func (m *metadataMgr) LatestGoVerFromCVer(name, cVer string) (string, error) {
+// Gets the latest Go version based on the module name and C version, yanked versions are excluded
+func (m *metadataMgr) LatestGoVerFromCVer(ctx context.Context, name, cVer string) (string, error) {
 	// Build the flat key
 	cKey := flatKey{name, cVer}
 
 	// Search for the latest Go version
-	goVersions, ok := m.flatCToGo[cKey]
+	goVersions, ok := m.current().flatCToGo[cKey]
 	if !ok {
 		// Try to update if not found
-		err := m.update()
+		err := m.update(ctx)
 		if err != nil {
 			return "", err
 		}
 
 		// Try again
-		goVersions, ok = m.flatCToGo[cKey]
+		goVersions, ok = m.current().flatCToGo[cKey]
 		if !ok {
-			return "", fmt.Errorf("no version mappings for %s %s", name, cVer)
+			return "", fmt.Errorf("%w for %s %s", ErrNoVersionMapping, name, cVer)
 		}
 	}
 
+	// withoutYanked may return goVersions itself, which is shared with other readers, sort a copy
+	goVersions = slices.Clone(m.withoutYanked(name, goVersions))
 	if len(goVersions) > 0 {
 		semver.Sort(goVersions)
 		latestGoVersion := goVersions[len(goVersions)-1]
@@ -67,27 +73,27 @@ func (m *metadataMgr) LatestGoVerFromCVer(name, cVer string) (string, error) {
 		return latestGoVersion, nil
 	}
 
-	return "", fmt.Errorf("no version mappings for %s %s", name, cVer)
+	return "", fmt.Errorf("%w for %s %s", ErrNoVersionMapping, name, cVer)
 }
 
 // Gets Go versions based on the module name and C version
-func (m *metadataMgr) GoVersFromCVer(name, cVer string) ([]string, error) {
+func (m *metadataMgr) GoVersFromCVer(ctx context.Context, name, cVer string) ([]string, error) {
 	// Build the flat key
 	cKey := flatKey{name, cVer}
 
 	// Search for the Go versions
-	versions, ok := m.flatCToGo[cKey]
+	versions, ok := m.current().flatCToGo[cKey]
 	if !ok {
 		// Try to update if not found
-		err := m.update()
+		err := m.update(ctx)
 		if err != nil {
 			return nil, err
 		}
 
 		// Try again
-		versions, ok = m.flatCToGo[cKey]
+		versions, ok = m.current().flatCToGo[cKey]
 		if !ok {
-			return nil, fmt.Errorf("no version mappings for %s %s", name, cVer)
+			return nil, fmt.Errorf("%w for %s %s", ErrNoVersionMapping, name, cVer)
 		}
 	}
 
@@ -99,21 +105,21 @@ func (m *metadataMgr) GoVersFromCVer(name, cVer string) ([]string, error) {
 }
 
 // Gets the C version based on the module name and Go version
-func (m *metadataMgr) CVerFromGoVer(name, goVer string) (string, error) {
+func (m *metadataMgr) CVerFromGoVer(ctx context.Context, name, goVer string) (string, error) {
 	// Build the flat key
 	goKey := flatKey{name, goVer}
 
 	// Search for the C version in the cached flat hash
-	cVersion, ok := m.flatGoToC[goKey]
+	cVersion, ok := m.current().flatGoToC[goKey]
 	if !ok {
 		// Update if not found
-		err := m.update()
+		err := m.update(ctx)
 		if err != nil {
 			return "", err
 		}
 
 		// Try again
-		cVersion, ok = m.flatGoToC[goKey]
+		cVersion, ok = m.current().flatGoToC[goKey]
 		if !ok {
 			return "", fmt.Errorf("no C version found for %s %s", name, goVer)
 		}
@@ -123,9 +129,9 @@ func (m *metadataMgr) CVerFromGoVer(name, goVer string) (string, error) {
 }
 
 // Gets all Go versions for the given module name
-func (m *metadataMgr) AllGoVersFromName(name string) ([]string, error) {
+func (m *metadataMgr) AllGoVersFromName(ctx context.Context, name string) ([]string, error) {
 	queryFunc := func(name string) ([]string, error) {
-		exists, err := m.ModuleExists(name)
+		exists, err := m.ModuleExists(ctx, name)
 		if !exists {
 			return nil, errors.New("module not found")
 		}
@@ -134,8 +140,9 @@ func (m *metadataMgr) AllGoVersFromName(name string) ([]string, error) {
 		}
 
 		// Extract Go versions
-		goVersions := make([]string, 0, len(m.flatGoToC))
-		for goVersionKey, _ := range m.flatGoToC {
+		flatGoToC := m.current().flatGoToC
+		goVersions := make([]string, 0, len(flatGoToC))
+		for goVersionKey := range flatGoToC {
 			if goVersionKey.name == name {
 				goVersions = append(goVersions, goVersionKey.version)
 			}
@@ -147,7 +154,7 @@ func (m *metadataMgr) AllGoVersFromName(name string) ([]string, error) {
 	goVersions, err := queryFunc(name)
 	if err != nil {
 		// update and try again
-		err := m.update()
+		err := m.update(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -162,9 +169,9 @@ func (m *metadataMgr) AllGoVersFromName(name string) ([]string, error) {
 }
 
 // Gets all C versions for the given module name
-func (m *metadataMgr) AllCVersFromName(name string) ([]string, error) {
+func (m *metadataMgr) AllCVersFromName(ctx context.Context, name string) ([]string, error) {
 	queryFunc := func(name string) ([]string, error) {
-		exists, err := m.ModuleExists(name)
+		exists, err := m.ModuleExists(ctx, name)
 		if !exists {
 			return nil, errors.New("module not found")
 		}
@@ -173,8 +180,9 @@ func (m *metadataMgr) AllCVersFromName(name string) ([]string, error) {
 		}
 
 		// Extract C versions
-		cVersions := make([]string, 0, len(m.flatCToGo))
-		for cVersionKey, _ := range m.flatCToGo {
+		flatCToGo := m.current().flatCToGo
+		cVersions := make([]string, 0, len(flatCToGo))
+		for cVersionKey := range flatCToGo {
 			if cVersionKey.name == name {
 				cVersions = append(cVersions, cVersionKey.version)
 			}
@@ -186,7 +194,7 @@ func (m *metadataMgr) AllCVersFromName(name string) ([]string, error) {
 	cVersions, err := queryFunc(name)
 	if err != nil {
 		// update and try again
-		err := m.update()
+		err := m.update(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -207,3 +215,104 @@ type flatKey struct {
 func (m *flatKey) String(name, version string) string {
 	return fmt.Sprintf("%s/%s", m.name, m.version)
 }
+
+// Gets Go versions satisfying the constraint for the given module name, sorted descending.
+// Yanked versions are excluded.
+func (m *metadataMgr) GoVersFromConstraint(ctx context.Context, name, constraint string) ([]string, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	allGoVersions, err := m.AllGoVersFromName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for _, goVersion := range m.withoutYanked(name, allGoVersions) {
+		if c.Check(goVersion) {
+			matched = append(matched, goVersion)
+		}
+	}
+	sortDescending(matched)
+
+	return matched, nil
+}
+
+// Gets Go versions whose C version satisfies the constraint for the given module name, sorted descending.
+// Yanked versions are excluded.
+func (m *metadataMgr) GoVersFromCConstraint(ctx context.Context, name, constraint string) ([]string, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	// make sure the module is up-to-date
+	if _, err := m.AllCVersFromName(ctx, name); err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for cVersionKey, goVersions := range m.current().flatCToGo {
+		if cVersionKey.name == name && c.Check(cVersionKey.version) {
+			matched = append(matched, goVersions...)
+		}
+	}
+	matched = m.withoutYanked(name, matched)
+	sortDescending(matched)
+
+	return matched, nil
+}
+
+// sortDescending sorts versions in descending semver order
+func sortDescending(versions []string) {
+	slices.SortFunc(versions, func(a, b string) int {
+		if cmp := semver.Compare(b, a); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(b, a)
+	})
+}
+
+// Gets the release details based on the module name and Go version
+func (m *metadataMgr) ReleaseInfo(ctx context.Context, name, goVer string) (Release, error) {
+	// make sure the Go version exists
+	if _, err := m.CVerFromGoVer(ctx, name, goVer); err != nil {
+		return Release{}, err
+	}
+	metadata, ok := m.allCachedMetadata()[name]
+	if !ok {
+		return Release{}, ErrMetadataNotInCache
+	}
+	release, ok := metadata.Releases[goVer]
+	if !ok || release == nil {
+		return Release{}, fmt.Errorf("%w: %s %s", ErrReleaseNotFound, name, goVer)
+	}
+	return *release.clone(), nil
+}
+
+// Reports whether the Go version is yanked and why
+func (m *metadataMgr) Yanked(ctx context.Context, name, goVer string) (bool, string, error) {
+	// make sure the Go version exists
+	if _, err := m.CVerFromGoVer(ctx, name, goVer); err != nil {
+		return false, "", err
+	}
+	metadata, ok := m.allCachedMetadata()[name]
+	if !ok {
+		return false, "", ErrMetadataNotInCache
+	}
+	reason, yanked := metadata.Yanked[goVer]
+	return yanked, reason, nil
+}
+
+// Gets the deprecation of the module, or nil if it's not deprecated
+func (m *metadataMgr) Deprecation(ctx context.Context, name string) (*Deprecation, error) {
+	metadata, err := m.MetadataByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Deprecated == nil {
+		return nil, nil
+	}
+	deprecation := *metadata.Deprecated
+	return &deprecation, nil
+}
@@ -1,12 +1,15 @@
 package metadata
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"slices"
 	"testing"
+	"time"
 )
 
 // Define more enriched test data
@@ -31,7 +34,7 @@ func setupTestEnv(t *testing.T, testData MetadataMap) (*metadataMgr, func()) {
 	remoteMetadataURL = server.URL
 
 	tmpDir := t.TempDir()
-	mgr, err := NewMetadataMgr(tmpDir)
+	mgr, err := newMetadataMgr(tmpDir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -50,7 +53,7 @@ func TestLatestCVer(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	latestCVer, err := mgr.LatestCVer("test-module")
+	latestCVer, err := mgr.LatestCVer(context.Background(), "test-module")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -59,13 +62,13 @@ func TestLatestCVer(t *testing.T) {
 	}
 
 	// Test empty module
-	_, err = mgr.LatestCVer("empty-module")
+	_, err = mgr.LatestCVer(context.Background(), "empty-module")
 	if err == nil {
 		t.Fatal("Expected error for empty module")
 	}
 
 	// Test non-existent module
-	_, err = mgr.LatestCVer("non-existent-module")
+	_, err = mgr.LatestCVer(context.Background(), "non-existent-module")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
@@ -77,7 +80,7 @@ func TestLatestGoVer(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	latestGoVer, err := mgr.LatestGoVer("test-module")
+	latestGoVer, err := mgr.LatestGoVer(context.Background(), "test-module")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,13 +89,13 @@ func TestLatestGoVer(t *testing.T) {
 	}
 
 	// Test empty module
-	_, err = mgr.LatestGoVer("empty-module")
+	_, err = mgr.LatestGoVer(context.Background(), "empty-module")
 	if err == nil {
 		t.Fatal("Expected error for empty module")
 	}
 
 	// Test non-existent module
-	_, err = mgr.LatestGoVer("non-existent-module")
+	_, err = mgr.LatestGoVer(context.Background(), "non-existent-module")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
@@ -104,7 +107,7 @@ func TestLatestGoVerFromCVer(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	latestGoVer, err := mgr.LatestGoVerFromCVer("test-module", "1.7.18")
+	latestGoVer, err := mgr.LatestGoVerFromCVer(context.Background(), "test-module", "1.7.18")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -113,13 +116,13 @@ func TestLatestGoVerFromCVer(t *testing.T) {
 	}
 
 	// Test non-existent C version
-	_, err = mgr.LatestGoVerFromCVer("test-module", "non-existent-version")
+	_, err = mgr.LatestGoVerFromCVer(context.Background(), "test-module", "non-existent-version")
 	if err == nil {
 		t.Fatal("Expected error for non-existent C version")
 	}
 
 	// Test non-existent module
-	_, err = mgr.LatestGoVerFromCVer("non-existent-module", "1.7.18")
+	_, err = mgr.LatestGoVerFromCVer(context.Background(), "non-existent-module", "1.7.18")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
@@ -131,7 +134,7 @@ func TestGoVersFromCVer(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	goVers, err := mgr.GoVersFromCVer("test-module", "1.7.18")
+	goVers, err := mgr.GoVersFromCVer(context.Background(), "test-module", "1.7.18")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,13 +144,13 @@ func TestGoVersFromCVer(t *testing.T) {
 	}
 
 	// Test non-existent C version
-	_, err = mgr.GoVersFromCVer("test-module", "non-existent-version")
+	_, err = mgr.GoVersFromCVer(context.Background(), "test-module", "non-existent-version")
 	if err == nil {
 		t.Fatal("Expected error for non-existent C version")
 	}
 
 	// Test non-existent module
-	_, err = mgr.GoVersFromCVer("non-existent-module", "1.7.18")
+	_, err = mgr.GoVersFromCVer(context.Background(), "non-existent-module", "1.7.18")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
@@ -159,7 +162,7 @@ func TestCVerFromGoVer(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	cVer, err := mgr.CVerFromGoVer("test-module", "v1.3.0")
+	cVer, err := mgr.CVerFromGoVer(context.Background(), "test-module", "v1.3.0")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -168,13 +171,13 @@ func TestCVerFromGoVer(t *testing.T) {
 	}
 
 	// Test non-existent Go version
-	_, err = mgr.CVerFromGoVer("test-module", "non-existent-version")
+	_, err = mgr.CVerFromGoVer(context.Background(), "test-module", "non-existent-version")
 	if err == nil {
 		t.Fatal("Expected error for non-existent Go version")
 	}
 
 	// Test non-existent module
-	_, err = mgr.CVerFromGoVer("non-existent-module", "v1.3.0")
+	_, err = mgr.CVerFromGoVer(context.Background(), "non-existent-module", "v1.3.0")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
@@ -186,7 +189,7 @@ func TestAllGoVersFromName(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	goVers, err := mgr.AllGoVersFromName("test-module")
+	goVers, err := mgr.AllGoVersFromName(context.Background(), "test-module")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -199,7 +202,7 @@ func TestAllGoVersFromName(t *testing.T) {
 	}
 
 	// Test empty module
-	goVers, err = mgr.AllGoVersFromName("empty-module")
+	goVers, err = mgr.AllGoVersFromName(context.Background(), "empty-module")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -208,7 +211,7 @@ func TestAllGoVersFromName(t *testing.T) {
 	}
 
 	// Test non-existent module
-	_, err = mgr.AllGoVersFromName("non-existent-module")
+	_, err = mgr.AllGoVersFromName(context.Background(), "non-existent-module")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
@@ -220,7 +223,7 @@ func TestAllCVersFromName(t *testing.T) {
 	defer cleanup()
 
 	// Test normal case
-	cVers, err := mgr.AllCVersFromName("test-module")
+	cVers, err := mgr.AllCVersFromName(context.Background(), "test-module")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -233,7 +236,7 @@ func TestAllCVersFromName(t *testing.T) {
 	}
 
 	// Test empty module
-	cVers, err = mgr.AllCVersFromName("empty-module")
+	cVers, err = mgr.AllCVersFromName(context.Background(), "empty-module")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -242,8 +245,187 @@ func TestAllCVersFromName(t *testing.T) {
 	}
 
 	// Test non-existent module
-	_, err = mgr.AllCVersFromName("non-existent-module")
+	_, err = mgr.AllCVersFromName(context.Background(), "non-existent-module")
 	if err == nil {
 		t.Fatal("Expected error for non-existent module")
 	}
 }
+
+// TestGoVersFromConstraint tests querying Go versions by constraint
+func TestGoVersFromConstraint(t *testing.T) {
+	mgr, cleanup := setupTestEnv(t, enhancedTestVersionData)
+	defer cleanup()
+
+	goVers, err := mgr.GoVersFromConstraint(context.Background(), "test-module", "^1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"v1.4.1", "v1.4.0", "v1.3.0", "v1.2.1", "v1.2.0"}
+	if !reflect.DeepEqual(goVers, expected) {
+		t.Errorf("Expected %v, got %v", expected, goVers)
+	}
+
+	goVers, err = mgr.GoVersFromConstraint(context.Background(), "test-module", ">=v1.3.0 <v1.4.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"v1.4.0", "v1.3.0"}
+	if !reflect.DeepEqual(goVers, expected) {
+		t.Errorf("Expected %v, got %v", expected, goVers)
+	}
+
+	if _, err := mgr.GoVersFromConstraint(context.Background(), "test-module", ">=abc"); err == nil {
+		t.Error("Expected error for invalid constraint")
+	}
+	if _, err := mgr.GoVersFromConstraint(context.Background(), "non-existent-module", "*"); err == nil {
+		t.Error("Expected error for non-existent module")
+	}
+}
+
+// TestGoVersFromCConstraint tests querying Go versions by constraint over C versions
+func TestGoVersFromCConstraint(t *testing.T) {
+	mgr, cleanup := setupTestEnv(t, enhancedTestVersionData)
+	defer cleanup()
+
+	goVers, err := mgr.GoVersFromCConstraint(context.Background(), "test-module", "~1.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"v1.3.0", "v1.2.1", "v1.2.0"}
+	if !reflect.DeepEqual(goVers, expected) {
+		t.Errorf("Expected %v, got %v", expected, goVers)
+	}
+
+	goVers, err = mgr.GoVersFromCConstraint(context.Background(), "test-module", ">=1.7.19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"v1.4.1", "v1.4.0", "v1.3.0"}
+	if !reflect.DeepEqual(goVers, expected) {
+		t.Errorf("Expected %v, got %v", expected, goVers)
+	}
+
+	goVers, err = mgr.GoVersFromCConstraint(context.Background(), "test-module", ">=2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goVers) != 0 {
+		t.Errorf("Expected no matches, got %v", goVers)
+	}
+}
+
+// TestReleaseInfo tests reading release details, including metadata without releases
+func TestReleaseInfo(t *testing.T) {
+	var data MetadataMap
+	err := json.Unmarshal([]byte(`{
+		"cjson": {
+			"versions": {"1.7.18": ["v1.0.0", "v1.0.1"]},
+			"releases": {
+				"v1.0.1": {
+					"publishedAt": "2025-03-01T00:00:00Z",
+					"commit": "0123456789abcdef",
+					"installer": "conan",
+					"checksums": {"linux_amd64": "aaa", "darwin_arm64": "bbb"}
+				}
+			}
+		},
+		"zlib": {
+			"versions": {"1.3.1": ["v1.0.0"]}
+		}
+	}`), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewMemoryManager(data)
+	ctx := context.Background()
+
+	release, err := mgr.ReleaseInfo(ctx, "cjson", "v1.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Commit != "0123456789abcdef" || release.Installer != "conan" ||
+		!release.PublishedAt.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected release: %v", release)
+	}
+	if platforms := release.Platforms(); !reflect.DeepEqual(platforms, []string{"darwin_arm64", "linux_amd64"}) {
+		t.Errorf("Unexpected platforms: %v", platforms)
+	}
+
+	if _, err := mgr.ReleaseInfo(ctx, "cjson", "v1.0.0"); !errors.Is(err, ErrReleaseNotFound) {
+		t.Errorf("Expected ErrReleaseNotFound, got %v", err)
+	}
+	if _, err := mgr.ReleaseInfo(ctx, "zlib", "v1.0.0"); !errors.Is(err, ErrReleaseNotFound) {
+		t.Errorf("Expected ErrReleaseNotFound, got %v", err)
+	}
+	if _, err := mgr.ReleaseInfo(ctx, "cjson", "v9.9.9"); err == nil {
+		t.Error("Expected error for unknown Go version")
+	}
+}
+
+// TestYankedAndDeprecated tests yanked versions are excluded from latest and constraint resolution
+func TestYankedAndDeprecated(t *testing.T) {
+	mgr := NewMemoryManager(MetadataMap{
+		"cjson": &Metadata{
+			Versions: map[CVersion][]GoVersion{
+				"1.7.18": {"v1.0.0", "v1.0.1"},
+				"1.7.19": {"v1.1.0"},
+			},
+			Yanked: map[GoVersion]string{
+				"v1.0.1": "broken binding",
+				"v1.1.0": "broken binding",
+			},
+		},
+		"cjson-old": &Metadata{
+			Versions:   map[CVersion][]GoVersion{"1.7.18": {"v1.0.0"}},
+			Deprecated: &Deprecation{Reason: "renamed", Replacement: "cjson"},
+		},
+	})
+	ctx := context.Background()
+
+	latestGoVer, err := mgr.LatestGoVer(ctx, "cjson")
+	if err != nil || latestGoVer != "v1.0.0" {
+		t.Errorf("Expected latest Go version v1.0.0, got %s: %v", latestGoVer, err)
+	}
+	latestCVer, err := mgr.LatestCVer(ctx, "cjson")
+	if err != nil || latestCVer != "1.7.18" {
+		t.Errorf("Expected latest C version 1.7.18, got %s: %v", latestCVer, err)
+	}
+	latestGoVer, err = mgr.LatestGoVerFromCVer(ctx, "cjson", "1.7.18")
+	if err != nil || latestGoVer != "v1.0.0" {
+		t.Errorf("Expected latest Go version v1.0.0, got %s: %v", latestGoVer, err)
+	}
+	if _, err := mgr.LatestGoVerFromCVer(ctx, "cjson", "1.7.19"); err == nil {
+		t.Error("Expected error when all versions are yanked")
+	}
+	goVers, err := mgr.GoVersFromConstraint(ctx, "cjson", "*")
+	if err != nil || !reflect.DeepEqual(goVers, []string{"v1.0.0"}) {
+		t.Errorf("Unexpected Go versions %v: %v", goVers, err)
+	}
+	goVers, err = mgr.GoVersFromCConstraint(ctx, "cjson", "*")
+	if err != nil || !reflect.DeepEqual(goVers, []string{"v1.0.0"}) {
+		t.Errorf("Unexpected Go versions %v: %v", goVers, err)
+	}
+
+	// still resolvable by exact version
+	cVer, err := mgr.CVerFromGoVer(ctx, "cjson", "v1.1.0")
+	if err != nil || cVer != "1.7.19" {
+		t.Errorf("Expected C version 1.7.19, got %s: %v", cVer, err)
+	}
+	yanked, reason, err := mgr.Yanked(ctx, "cjson", "v1.1.0")
+	if err != nil || !yanked || reason != "broken binding" {
+		t.Errorf("Expected v1.1.0 to be yanked, got %v %s: %v", yanked, reason, err)
+	}
+	yanked, _, err = mgr.Yanked(ctx, "cjson", "v1.0.0")
+	if err != nil || yanked {
+		t.Errorf("Expected v1.0.0 not to be yanked: %v", err)
+	}
+
+	deprecation, err := mgr.Deprecation(ctx, "cjson-old")
+	if err != nil || deprecation == nil || deprecation.Replacement != "cjson" {
+		t.Errorf("Unexpected deprecation %v: %v", deprecation, err)
+	}
+	deprecation, err = mgr.Deprecation(ctx, "cjson")
+	if err != nil || deprecation != nil {
+		t.Errorf("Unexpected deprecation %v: %v", deprecation, err)
+	}
+}
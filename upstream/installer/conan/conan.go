@@ -286,7 +286,10 @@ func (c *conanInstaller) Dependency(pkg upstream.Package) (dependencies []upstre
 
 	out, err := cmd.Output()
 	if err != nil {
-		err = errors.New(conanError.String())
+		// keep the error if conan isn't run, e.g. not installed
+		if conanError.Len() > 0 {
+			err = errors.New(conanError.String())
+		}
 		return
 	}
 